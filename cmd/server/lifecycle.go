@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// lifecycle owns the HTTP server and the background workers of the process
+// and tears them down in order when SIGINT or SIGTERM is received:
+//
+//  1. run the drain hooks (readiness starts failing) and wait for the drain
+//     delay so load balancers stop routing new traffic,
+//  2. stop accepting connections and run the shutdown hooks (WebSocket close
+//     frames are sent here),
+//  3. wait for in-flight requests,
+//  4. cancel and wait for background workers,
+//  5. run the closers (database, Redis, ...) in reverse registration order.
+//
+// Steps 3 and 4 share the shutdown timeout.
+type lifecycle struct {
+	server          *http.Server
+	shutdownTimeout time.Duration
+	drainDelay      time.Duration
+	drainHooks      []func()
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+
+	closers []closer
+}
+
+type closer struct {
+	name string
+	fn   func() error
+}
+
+func newLifecycle(server *http.Server, shutdownTimeout, drainDelay time.Duration) *lifecycle {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &lifecycle{
+		server:          server,
+		shutdownTimeout: shutdownTimeout,
+		drainDelay:      drainDelay,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+// OnDrain registers fn to run when a termination signal arrives, before the
+// server stops accepting connections.
+func (l *lifecycle) OnDrain(fn func()) {
+	l.drainHooks = append(l.drainHooks, fn)
+}
+
+// Go starts a background worker. The context passed to fn is cancelled once
+// the HTTP server has drained.
+func (l *lifecycle) Go(name string, fn func(ctx context.Context)) {
+	l.workers.Add(1)
+	go func() {
+		defer l.workers.Done()
+		fn(l.ctx)
+		log.Printf("Worker %s stopped", name)
+	}()
+}
+
+// OnShutdown registers fn to run as soon as shutdown starts, while requests
+// are still being drained.
+func (l *lifecycle) OnShutdown(fn func()) {
+	l.server.RegisterOnShutdown(fn)
+}
+
+// OnClose registers fn to release a resource after the server and all workers
+// have stopped.
+func (l *lifecycle) OnClose(name string, fn func() error) {
+	l.closers = append(l.closers, closer{name: name, fn: fn})
+}
+
+// Run serves HTTP until a termination signal arrives or the listener fails,
+// then shuts everything down.
+func (l *lifecycle) Run() error {
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on %s...", l.server.Addr)
+		if err := l.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	var err error
+	select {
+	case err = <-serveErr:
+		log.Printf("Server stopped: %v", err)
+	case <-signalCtx.Done():
+		log.Println("Shutdown signal received")
+		for _, fn := range l.drainHooks {
+			fn()
+		}
+		if l.drainDelay > 0 {
+			log.Printf("Draining for %s before closing listeners...", l.drainDelay)
+			time.Sleep(l.drainDelay)
+		}
+	}
+	stop()
+
+	if shutdownErr := l.shutdown(); shutdownErr != nil {
+		err = errors.Join(err, shutdownErr)
+	}
+	return err
+}
+
+func (l *lifecycle) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), l.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+
+	log.Println("Draining HTTP server...")
+	if err := l.server.Shutdown(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	log.Println("Stopping background workers...")
+	l.cancel()
+	done := make(chan struct{})
+	go func() {
+		l.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, errors.New("timed out waiting for background workers"))
+	}
+
+	for i := len(l.closers) - 1; i >= 0; i-- {
+		c := l.closers[i]
+		if err := c.fn(); err != nil {
+			errs = append(errs, err)
+			log.Printf("Failed to close %s: %v", c.name, err)
+		}
+	}
+
+	log.Println("Shutdown complete")
+	return errors.Join(errs...)
+}
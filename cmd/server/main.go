@@ -1,85 +1,293 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
 	"log"
+	"net/http"
+	"os"
+	"time"
 
+	"github.com/Dostonlv/hackathon-nt/config"
 	"github.com/Dostonlv/hackathon-nt/internal/api"
+	"github.com/Dostonlv/hackathon-nt/internal/api/middleware"
 	"github.com/Dostonlv/hackathon-nt/internal/repository/postgres"
 	"github.com/Dostonlv/hackathon-nt/internal/service"
 	"github.com/Dostonlv/hackathon-nt/internal/utils"
+	"github.com/Dostonlv/hackathon-nt/migrations"
 	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
 	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
 	"github.com/go-redis/redis/v8"
 	_ "github.com/lib/pq"
 )
 
-func initializeCasbin() (*casbin.Enforcer, error) {
-	// Create the adapter
-	a := fileadapter.NewAdapter("config/policy.csv")
+// healthCheckTimeout bounds every dependency probe of /healthz and /readyz.
+const healthCheckTimeout = 2 * time.Second
 
-	// Initialize the enforcer
-	enforcer, err := casbin.NewEnforcer("config/model.conf", a)
+// initializeCasbin creates an enforcer backed by the casbin_rules table. On
+// first start the table is seeded from the policy file.
+func initializeCasbin(ctx context.Context, cfg config.CasbinConfig, db *sql.DB) (*casbin.SyncedEnforcer, error) {
+	adapter := postgres.NewCasbinAdapter(db)
+
+	seed, err := model.NewModelFromFile(cfg.ModelPath)
 	if err != nil {
 		return nil, err
 	}
+	err = fileadapter.NewAdapter(cfg.PolicyPath).LoadPolicy(seed)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// Nothing to import; the policies are managed through the API
+	case err != nil:
+		return nil, fmt.Errorf("error reading policy file: %w", err)
+	default:
+		seeded, err := adapter.Seed(ctx, seed)
+		if err != nil {
+			return nil, fmt.Errorf("error seeding policies: %w", err)
+		}
+		if seeded {
+			log.Printf("Imported casbin policies from %s", cfg.PolicyPath)
+		}
+	}
 
-	// Load the policy from DB
-	err = enforcer.LoadPolicy()
-	if err != nil {
-		return nil, err
+	// NewSyncedEnforcer loads the policy from the adapter
+	return casbin.NewSyncedEnforcer(cfg.ModelPath, adapter)
+}
+
+// newBlobStore returns the BlobStore selected by cfg.Driver.
+func newBlobStore(cfg config.StorageConfig) (utils.BlobStore, error) {
+	switch cfg.Driver {
+	case "local":
+		return utils.NewLocalBlobStore(cfg.Dir)
+	case "s3":
+		return utils.NewS3BlobStore(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3PathStyle)
 	}
+	return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+}
 
-	return enforcer, nil
+// newMailer returns the Mailer selected by cfg.Driver.
+func newMailer(cfg config.MailConfig) (utils.Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return utils.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	case "file":
+		return utils.NewFileMailer(cfg.Dir, cfg.From)
+	case "memory":
+		return utils.NewMemoryMailer(), nil
+	}
+	return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "users" {
+		if err := runUsers(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "policy" {
+		if err := runPolicy(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal("Failed to load configuration: ", err)
+	}
+
 	// Database connection
-	connStr := "postgres://postgres:postgres@db:5432/tender_db?sslmode=disable"
-	db, err := sql.Open("postgres", connStr)
+	db, err := postgres.NewConnection(cfg.DB)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	// Schema migrations
+	migrator, err := postgres.NewMigrator(db, migrations.FS)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.DB.AutoMigrate {
+		if err := migrator.Up(context.Background()); err != nil {
+			log.Fatal("Failed to apply migrations: ", err)
+		}
+	}
+	if err := migrator.CheckVersion(context.Background()); err != nil {
+		log.Fatal("Refusing to start: ", err)
+	}
+	// Rebuilding the search columns locks tables, so it is left to
+	// "server migrate up"
+	if language, err := postgres.SearchLanguage(context.Background(), db); err != nil {
+		log.Fatal("Failed to read the search language: ", err)
+	} else if language != cfg.Search.Language {
+		log.Printf("The search index uses %q instead of search.language %q until server migrate up is run", language, cfg.Search.Language)
+	}
+
 	// Initialize JWT util
-	jwtSecret := "secreeet"
-	jwtUtil := utils.NewJWTUtil(jwtSecret)
+	var (
+		jwtUtil    *utils.JWTUtil
+		keyService *service.KeyRotationService
+	)
+	if cfg.JWT.Algorithm == utils.AlgorithmHS256 {
+		jwtUtil = utils.NewJWTUtil(cfg.JWT.Secret, cfg.JWT.TokenTTL.Duration)
+	} else {
+		jwtUtil, err = utils.NewAsymmetricJWTUtil(cfg.JWT.Algorithm, cfg.JWT.TokenTTL.Duration)
+		if err != nil {
+			log.Fatal(err)
+		}
+		keyService = service.NewKeyRotationService(postgres.NewSigningKeyRepo(db), jwtUtil, service.KeyRotationConfig{
+			Interval:         cfg.JWT.KeyRotation.Duration,
+			PublishLead:      cfg.JWT.KeyPublishLead.Duration,
+			RefreshInterval:  cfg.JWT.KeyRefreshInterval.Duration,
+			EncryptionSecret: cfg.JWT.Secret,
+		})
+		if err := keyService.Sync(context.Background()); err != nil {
+			log.Fatal("Failed to load JWT signing keys: ", err)
+		}
+	}
+
+	// Redis connection
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
 
 	// Initialize Casbin
-	enforcer, err := initializeCasbin()
+	enforcer, err := initializeCasbin(context.Background(), cfg.Casbin, db)
 	if err != nil {
 		log.Fatal("Failed to initialize Casbin: ", err)
 	}
+	policyWatcher := utils.NewPolicyWatcher(redisClient, cfg.Casbin.WatcherChannel)
+	if err := enforcer.SetWatcher(policyWatcher); err != nil {
+		log.Fatal("Failed to set Casbin watcher: ", err)
+	}
+	// SetWatcher installs a reload that bypasses the enforcer's lock
+	policyWatcher.SetUpdateCallback(func(string) {
+		if err := enforcer.LoadPolicy(); err != nil {
+			log.Printf("Failed to reload Casbin policies: %v", err)
+		}
+	})
+	policyService := service.NewPolicyService(enforcer, postgres.NewPolicyAuditRepo(db))
+	userRepo := postgres.NewUserRepo(db)
+	securityLog := service.NewSecurityLog(postgres.NewSecurityEventRepo(db))
+	loginGuard := service.NewLoginGuard(postgres.NewLoginAttemptRepo(redisClient), securityLog, service.LoginProtectionConfig{
+		MaxAccountFailures: cfg.Login.MaxAccountFailures,
+		MaxIPFailures:      cfg.Login.MaxIPFailures,
+		FailureWindow:      cfg.Login.FailureWindow.Duration,
+		LockoutDuration:    cfg.Login.LockoutDuration.Duration,
+		DelayAfter:         cfg.Login.DelayAfter,
+		BaseDelay:          cfg.Login.BaseDelay.Duration,
+		MaxDelay:           cfg.Login.MaxDelay.Duration,
+	})
+	authService := service.NewAuthService(userRepo, postgres.NewTokenRepo(db, redisClient), postgres.NewTwoFactorRepo(db, redisClient), loginGuard, jwtUtil, cfg.JWT.RefreshTokenTTL.Duration, service.TwoFactorConfig{
+		Issuer:           cfg.TwoFactor.Issuer,
+		EncryptionSecret: cfg.JWT.Secret,
+		ChallengeTTL:     cfg.TwoFactor.ChallengeTTL.Duration,
+	})
 
-	// Add some basic policies
-	enforcer.AddPolicy("admin", "/api/*", "*")
-	enforcer.AddPolicy("user", "/api/users", "GET")
-	enforcer.AddPolicy("user", "/api/users/:id", "GET")
-
-	// Save the policies back to storage
-	err = enforcer.SavePolicy()
+	mailer, err := newMailer(cfg.Mail)
 	if err != nil {
-		log.Fatal("Failed to save Casbin policies: ", err)
+		log.Fatal("Failed to initialize mailer: ", err)
 	}
+	accountService := service.NewAccountService(userRepo, postgres.NewAccountTokenRepo(db), authService, mailer, service.AccountConfig{
+		VerificationTokenTTL:  cfg.Account.VerificationTokenTTL.Duration,
+		PasswordResetTokenTTL: cfg.Account.PasswordResetTokenTTL.Duration,
+		LinkBaseURL:           cfg.Account.LinkBaseURL,
+	})
 
-	// Redis connection
-	redisAddr := "localhost:6379"
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: redisAddr,
+	apiKeyService := service.NewAPIKeyService(postgres.NewAPIKeyRepo(db), userRepo, cfg.APIKeys.MaxPerUser)
+	adminService := service.NewAdminService(userRepo, authService, accountService, securityLog)
+
+	orgRepo := postgres.NewOrganizationRepo(db)
+	authorizer := service.NewAuthorizer(orgRepo)
+	organizationService := service.NewOrganizationService(orgRepo, userRepo, authorizer, mailer, service.OrganizationConfig{
+		InvitationTTL: cfg.Organizations.InvitationTTL.Duration,
+		LinkBaseURL:   cfg.Account.LinkBaseURL,
+	})
+
+	blobStore, err := newBlobStore(cfg.Storage)
+	if err != nil {
+		log.Fatal("Failed to initialize blob store: ", err)
+	}
+	documentService := service.NewDocumentService(postgres.NewDocumentRepo(db), postgres.NewTenderRepo(db, redisClient), blobStore, authorizer, service.DocumentConfig{
+		MaxSize:      cfg.Documents.MaxSize,
+		AllowedTypes: cfg.Documents.AllowedTypes,
+		MaxPerTender: cfg.Documents.MaxPerTender,
 	})
-	userRepo := postgres.NewUserRepo(db)
-	authService := service.NewAuthService(userRepo, jwtUtil)
 
 	// Pass Redis client to NewTenderRepo
-	tenderService := service.NewTenderService(postgres.NewTenderRepo(db, redisClient))
-	bidService := service.NewBidService(postgres.NewBidRepo(db, redisClient), postgres.NewTenderRepo(db, redisClient))
-	historyService := service.NewHistoryService(postgres.NewHistoryRepo(db))
+	tenderService := service.NewTenderService(postgres.NewTenderRepo(db, redisClient), postgres.NewBidRepo(db, redisClient), postgres.NewCategoryRepo(db), authorizer, organizationService, documentService)
+	bidService := service.NewBidService(postgres.NewBidRepo(db, redisClient), postgres.NewTenderRepo(db, redisClient), authorizer, organizationService)
+	clarificationService := service.NewClarificationService(postgres.NewClarificationRepo(db), postgres.NewTenderRepo(db, redisClient), postgres.NewBidRepo(db, redisClient), authorizer, organizationService, service.ClarificationConfig{
+		CloseBefore: cfg.Clarifications.CloseBefore.Duration,
+	})
+	historyService := service.NewHistoryService(postgres.NewHistoryRepo(db), authorizer)
+
+	notificationService := utils.NewNotificationService(cfg.Notification.WriteTimeout.Duration)
+	deadlineScheduler := service.NewDeadlineScheduler(postgres.NewTenderRepo(db, redisClient), postgres.NewBidRepo(db, redisClient), organizationService, notificationService, service.DeadlineConfig{
+		Interval:  cfg.Deadlines.Interval.Duration,
+		Reminders: cfg.Deadlines.Reminders.Durations(),
+		BatchSize: cfg.Deadlines.BatchSize,
+	})
+	bidLimiter := middleware.NewBidRateLimiter(cfg.RateLimit.BidLimit, cfg.RateLimit.BidWindow.Duration)
+
+	healthService := service.NewHealthService(healthCheckTimeout,
+		service.HealthCheck{Name: "postgres", Check: db.PingContext},
+		service.HealthCheck{Name: "redis", Check: func(ctx context.Context) error {
+			return redisClient.Ping(ctx).Err()
+		}},
+		service.HealthCheck{Name: "casbin", Check: func(ctx context.Context) error {
+			policies, err := enforcer.GetPolicy()
+			if err != nil {
+				return err
+			}
+			if len(policies) == 0 {
+				return errors.New("no policies loaded")
+			}
+			return nil
+		}},
+		service.HealthCheck{Name: "migrations", Check: migrator.CheckVersion},
+	)
+
 	// Setup router with Casbin enforcer
-	router := api.SetupRouter(authService, tenderService, bidService, historyService, enforcer, jwtSecret)
+	router := api.SetupRouter(cfg, authService, securityLog, adminService, accountService, apiKeyService, tenderService, documentService, clarificationService, bidService, historyService, healthService, policyService, organizationService, authorizer, enforcer, jwtUtil, notificationService, bidLimiter)
+
+	srv := &http.Server{
+		Addr:         cfg.HTTP.Addr,
+		Handler:      router,
+		ReadTimeout:  cfg.HTTP.ReadTimeout.Duration,
+		WriteTimeout: cfg.HTTP.WriteTimeout.Duration,
+	}
+
+	lc := newLifecycle(srv, cfg.HTTP.ShutdownTimeout.Duration, cfg.HTTP.DrainDelay.Duration)
+	lc.OnDrain(healthService.SetDraining)
+	lc.OnClose("postgres", db.Close)
+	lc.OnClose("redis", redisClient.Close)
+	lc.OnShutdown(notificationService.Shutdown)
+	lc.Go("bid rate limiter cleanup", bidLimiter.Run)
+	lc.Go("casbin policy watcher", func(ctx context.Context) {
+		policyWatcher.Run(ctx, cfg.Casbin.ReloadInterval.Duration)
+	})
+	if keyService != nil {
+		lc.Go("jwt key rotation", keyService.Run)
+	}
+	if cfg.Deadlines.Interval.Duration > 0 {
+		lc.Go("tender deadline scheduler", deadlineScheduler.Run)
+	}
 
 	// Start the server
-	log.Println("Server starting on :8888...")
-	if err := router.Run(":8888"); err != nil {
-		log.Fatal("Failed to start server: ", err)
+	if err := lc.Run(); err != nil {
+		log.Fatal("Server stopped with error: ", err)
 	}
 }
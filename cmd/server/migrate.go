@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Dostonlv/hackathon-nt/config"
+	"github.com/Dostonlv/hackathon-nt/internal/repository/postgres"
+	"github.com/Dostonlv/hackathon-nt/migrations"
+)
+
+const migrateUsage = `usage: server migrate <command> [flags]
+
+commands:
+  up            apply all pending migrations and search.language
+  down [N]      roll back N migrations (all when N is omitted)
+  status        show the current and pending migrations
+  force V       set the schema version to V and clear the dirty flag`
+
+// runMigrate implements the "server migrate" subcommand. Flags after the
+// command are the same configuration flags the server accepts.
+func runMigrate(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return errors.New(migrateUsage)
+	}
+	command, args := args[0], args[1:]
+	switch command {
+	case "up", "down", "status", "force":
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", command, migrateUsage)
+	}
+
+	var arg string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		arg, args = args[0], args[1:]
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+
+	db, err := postgres.NewConnection(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := postgres.NewMigrator(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		if err = migrator.Up(ctx); err == nil {
+			err = migrator.SetSearchLanguage(ctx, cfg.Search.Language)
+		}
+	case "down":
+		steps := 0
+		if arg != "" {
+			if steps, err = strconv.Atoi(arg); err != nil || steps <= 0 {
+				return fmt.Errorf("invalid number of steps %q", arg)
+			}
+		}
+		err = migrator.Down(ctx, steps)
+	case "force":
+		version, parseErr := strconv.ParseUint(arg, 10, 64)
+		if parseErr != nil {
+			return fmt.Errorf("force requires a version: %s", migrateUsage)
+		}
+		err = migrator.Force(ctx, uint(version))
+	}
+	if err != nil {
+		return err
+	}
+
+	return printMigrationStatus(ctx, migrator)
+}
+
+func printMigrationStatus(ctx context.Context, migrator *postgres.Migrator) error {
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("version: %d (latest %d)", status.Version, status.Latest)
+	if status.Dirty {
+		fmt.Print(" DIRTY")
+	}
+	fmt.Println()
+	for _, m := range status.Applied {
+		fmt.Printf("  [x] %02d_%s\n", m.Version, m.Name)
+	}
+	for _, m := range status.Pending {
+		fmt.Printf("  [ ] %02d_%s\n", m.Version, m.Name)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Dostonlv/hackathon-nt/config"
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository/postgres"
+	"github.com/Dostonlv/hackathon-nt/internal/service"
+	"github.com/casbin/casbin/v2"
+)
+
+const policyUsage = `usage: server policy <command> [flags]
+
+commands:
+  explain SUBJECT PATH METHOD   show how the policy decides a request, such as
+                                "explain client /api/users/123/tenders GET"`
+
+// runPolicy implements the "server policy" subcommand. It reads the policy
+// stored in Postgres, the same one the running servers enforce.
+func runPolicy(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return errors.New(policyUsage)
+	}
+	command, args := args[0], args[1:]
+	if command != "explain" {
+		return fmt.Errorf("unknown policy command %q\n%s", command, policyUsage)
+	}
+	if len(args) < 3 || strings.HasPrefix(args[0], "-") || strings.HasPrefix(args[1], "-") || strings.HasPrefix(args[2], "-") {
+		return errors.New(policyUsage)
+	}
+	request, args := models.Policy{Subject: args[0], Object: args[1], Action: args[2]}, args[3:]
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+
+	db, err := postgres.NewConnection(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	enforcer, err := casbin.NewSyncedEnforcer(cfg.Casbin.ModelPath, postgres.NewCasbinAdapter(db))
+	if err != nil {
+		return err
+	}
+	policies := service.NewPolicyService(enforcer, postgres.NewPolicyAuditRepo(db))
+	decision, err := policies.Explain(context.Background(), request)
+	if err != nil {
+		return err
+	}
+
+	verdict := "denied"
+	if decision.Allowed {
+		verdict = "allowed"
+	}
+	fmt.Printf("%s %s %s: %s\n", decision.Subject, decision.Action, decision.Object, verdict)
+	if decision.MatchedPolicy != nil {
+		p := decision.MatchedPolicy
+		fmt.Printf("matched policy: p, %s, %s, %s\n", p.Subject, p.Object, p.Action)
+	}
+	fmt.Printf("role chain: %s\n", strings.Join(decision.RoleChain, " -> "))
+	fmt.Printf("policies of the role chain for %s:\n", decision.Action)
+	if len(decision.Candidates) == 0 {
+		fmt.Println("  none")
+	}
+	for _, p := range decision.Candidates {
+		fmt.Printf("  p, %s, %s, %s\n", p.Subject, p.Object, p.Action)
+	}
+	return nil
+}
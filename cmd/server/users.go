@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Dostonlv/hackathon-nt/config"
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository/postgres"
+)
+
+const usersUsage = `usage: server users <command> [flags]
+
+commands:
+  set-role USERNAME ROLE   give a user the client, contractor or admin role`
+
+// runUsers implements the "server users" subcommand. It is how the first
+// admin is created, since registration only offers client and contractor.
+func runUsers(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return errors.New(usersUsage)
+	}
+	command, args := args[0], args[1:]
+	if command != "set-role" {
+		return fmt.Errorf("unknown users command %q\n%s", command, usersUsage)
+	}
+	if len(args) < 2 || strings.HasPrefix(args[0], "-") || strings.HasPrefix(args[1], "-") {
+		return errors.New(usersUsage)
+	}
+	username, role, args := args[0], models.UserRole(args[1]), args[2:]
+	if !role.Valid() {
+		return fmt.Errorf("invalid role %q\n%s", role, usersUsage)
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+
+	db, err := postgres.NewConnection(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	users := postgres.NewUserRepo(db)
+	user, err := users.GetByUsername(ctx, username)
+	if err != nil {
+		return fmt.Errorf("error loading user %q: %w", username, err)
+	}
+	if err := users.UpdateRole(ctx, user.ID, role); err != nil {
+		return err
+	}
+
+	// Access tokens issued before keep the old role until they expire.
+	fmt.Printf("%s is now %s; the new role applies from the next login\n", user.Username, role)
+	return nil
+}
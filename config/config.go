@@ -1,10 +1,562 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultJWTSecret is the built-in jwt.secret. It is public, so Validate
+// rejects it in release mode.
+const DefaultJWTSecret = "secreeet"
+
+// Config is the root configuration of the server. Values are layered as
+// defaults, then the config file, then environment variables, then flags.
 type Config struct {
-	Host     string
-	Port     int
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	HTTP           HTTPConfig           `yaml:"http" toml:"http"`
+	DB             DatabaseConfig       `yaml:"db" toml:"db"`
+	Redis          RedisConfig          `yaml:"redis" toml:"redis"`
+	JWT            JWTConfig            `yaml:"jwt" toml:"jwt"`
+	Casbin         CasbinConfig         `yaml:"casbin" toml:"casbin"`
+	RateLimit      RateLimitConfig      `yaml:"rate_limit" toml:"rate_limit"`
+	Notification   NotificationConfig   `yaml:"notification" toml:"notification"`
+	Mail           MailConfig           `yaml:"mail" toml:"mail"`
+	Account        AccountConfig        `yaml:"account" toml:"account"`
+	TwoFactor      TwoFactorConfig      `yaml:"two_factor" toml:"two_factor"`
+	Login          LoginConfig          `yaml:"login_protection" toml:"login_protection"`
+	APIKeys        APIKeysConfig        `yaml:"api_keys" toml:"api_keys"`
+	Organizations  OrganizationsConfig  `yaml:"organizations" toml:"organizations"`
+	Deadlines      DeadlinesConfig      `yaml:"deadlines" toml:"deadlines"`
+	Storage        StorageConfig        `yaml:"storage" toml:"storage"`
+	Documents      DocumentsConfig      `yaml:"documents" toml:"documents"`
+	Clarifications ClarificationsConfig `yaml:"clarifications" toml:"clarifications"`
+	Search         SearchConfig         `yaml:"search" toml:"search"`
+	Pagination     PaginationConfig     `yaml:"pagination" toml:"pagination"`
+}
+
+// HTTPConfig holds the HTTP listener settings.
+type HTTPConfig struct {
+	Addr            string   `yaml:"addr" toml:"addr"`
+	Mode            string   `yaml:"mode" toml:"mode"`
+	ReadTimeout     Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout    Duration `yaml:"write_timeout" toml:"write_timeout"`
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// DrainDelay is how long readiness fails before listeners are closed.
+	DrainDelay Duration `yaml:"drain_delay" toml:"drain_delay"`
+}
+
+// DatabaseConfig holds the Postgres connection settings. When URL is set it
+// takes precedence over the individual fields.
+type DatabaseConfig struct {
+	URL          string `yaml:"url" toml:"url"`
+	Host         string `yaml:"host" toml:"host"`
+	Port         int    `yaml:"port" toml:"port"`
+	User         string `yaml:"user" toml:"user"`
+	Password     string `yaml:"password" toml:"password"`
+	DBName       string `yaml:"name" toml:"name"`
+	SSLMode      string `yaml:"sslmode" toml:"sslmode"`
+	MaxOpenConns int    `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns int    `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	// AutoMigrate applies pending migrations on startup.
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate"`
+}
+
+// DSN returns the connection string for lib/pq.
+func (c DatabaseConfig) DSN() string {
+	if c.URL != "" {
+		return c.URL
+	}
+	return fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		c.Host,
+		c.Port,
+		c.User,
+		c.Password,
+		c.DBName,
+		c.SSLMode,
+	)
+}
+
+// RedisConfig holds the Redis connection settings.
+type RedisConfig struct {
+	Addr     string `yaml:"addr" toml:"addr"`
+	Password string `yaml:"password" toml:"password"`
+	DB       int    `yaml:"db" toml:"db"`
+}
+
+// JWTConfig holds the token signing settings.
+// With HS256 tokens are signed with Secret. With RS256 or EdDSA they are
+// signed with rotating keys stored in Postgres, and Secret only encrypts
+// those keys at rest.
+type JWTConfig struct {
+	Algorithm          string   `yaml:"algorithm" toml:"algorithm"`
+	Secret             string   `yaml:"secret" toml:"secret"`
+	TokenTTL           Duration `yaml:"token_ttl" toml:"token_ttl"`
+	RefreshTokenTTL    Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl"`
+	KeyRotation        Duration `yaml:"key_rotation" toml:"key_rotation"`
+	KeyPublishLead     Duration `yaml:"key_publish_lead" toml:"key_publish_lead"`
+	KeyRefreshInterval Duration `yaml:"key_refresh_interval" toml:"key_refresh_interval"`
+}
+
+// CasbinConfig holds the authorization settings. Policies are stored in
+// Postgres; PolicyPath is only imported when the policy table is empty.
+type CasbinConfig struct {
+	ModelPath  string `yaml:"model_path" toml:"model_path"`
+	PolicyPath string `yaml:"policy_path" toml:"policy_path"`
+	// WatcherChannel is the Redis channel policy changes are announced on.
+	WatcherChannel string `yaml:"watcher_channel" toml:"watcher_channel"`
+	// ReloadInterval is how often the policy is reloaded in case a change
+	// announcement was missed. Zero disables it.
+	ReloadInterval Duration `yaml:"reload_interval" toml:"reload_interval"`
+}
+
+// RateLimitConfig holds the bid submission rate limit.
+type RateLimitConfig struct {
+	BidLimit  int      `yaml:"bid_limit" toml:"bid_limit"`
+	BidWindow Duration `yaml:"bid_window" toml:"bid_window"`
+}
+
+// NotificationConfig holds the WebSocket notification settings.
+type NotificationConfig struct {
+	AllowedOrigins StringList `yaml:"allowed_origins" toml:"allowed_origins"`
+	WriteTimeout   Duration   `yaml:"write_timeout" toml:"write_timeout"`
+}
+
+// MailConfig selects how outgoing email is delivered. The smtp driver sends
+// through an SMTP server; file writes each message as an .eml file to Dir and
+// memory keeps messages in the process, for development and tests.
+type MailConfig struct {
+	Driver       string `yaml:"driver" toml:"driver"`
+	From         string `yaml:"from" toml:"from"`
+	Dir          string `yaml:"dir" toml:"dir"`
+	SMTPHost     string `yaml:"smtp_host" toml:"smtp_host"`
+	SMTPPort     int    `yaml:"smtp_port" toml:"smtp_port"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password"`
+}
+
+// AccountConfig holds the email verification and password reset settings.
+type AccountConfig struct {
+	VerificationTokenTTL  Duration `yaml:"verification_token_ttl" toml:"verification_token_ttl"`
+	PasswordResetTokenTTL Duration `yaml:"password_reset_token_ttl" toml:"password_reset_token_ttl"`
+	// LinkBaseURL is the frontend URL the links in account emails point at.
+	LinkBaseURL string `yaml:"link_base_url" toml:"link_base_url"`
+}
+
+// TwoFactorConfig holds the TOTP two-factor authentication settings. TOTP
+// secrets are encrypted with jwt.secret.
+type TwoFactorConfig struct {
+	// Issuer is the account label shown in authenticator apps.
+	Issuer       string   `yaml:"issuer" toml:"issuer"`
+	ChallengeTTL Duration `yaml:"challenge_ttl" toml:"challenge_ttl"`
+	// RequireForSensitiveActions makes awarding bids and deleting tenders
+	// require a session opened with a second factor.
+	RequireForSensitiveActions bool `yaml:"require_for_sensitive_actions" toml:"require_for_sensitive_actions"`
+}
+
+// LoginConfig holds the brute-force protection of logins. Failures are
+// counted per username and per client IP address in Redis.
+type LoginConfig struct {
+	MaxAccountFailures int      `yaml:"max_account_failures" toml:"max_account_failures"`
+	MaxIPFailures      int      `yaml:"max_ip_failures" toml:"max_ip_failures"`
+	FailureWindow      Duration `yaml:"failure_window" toml:"failure_window"`
+	LockoutDuration    Duration `yaml:"lockout_duration" toml:"lockout_duration"`
+	// After DelayAfter failures an account waits BaseDelay before the next
+	// attempt, doubling with every further failure up to MaxDelay.
+	DelayAfter int      `yaml:"delay_after" toml:"delay_after"`
+	BaseDelay  Duration `yaml:"base_delay" toml:"base_delay"`
+	MaxDelay   Duration `yaml:"max_delay" toml:"max_delay"`
+}
+
+// APIKeysConfig holds the API key settings.
+type APIKeysConfig struct {
+	// MaxPerUser is how many unrevoked, unexpired keys a user may have.
+	MaxPerUser int `yaml:"max_per_user" toml:"max_per_user"`
+}
+
+// OrganizationsConfig holds the organization membership settings.
+type OrganizationsConfig struct {
+	// InvitationTTL is how long an invitation to join an organization can be
+	// accepted.
+	InvitationTTL Duration `yaml:"invitation_ttl" toml:"invitation_ttl"`
+}
+
+// DeadlinesConfig holds the scheduler that closes tenders at their deadline
+// and sends reminders before it.
+type DeadlinesConfig struct {
+	// Interval is how often deadlines are checked. Zero disables the
+	// scheduler; late bids are rejected regardless.
+	Interval Duration `yaml:"interval" toml:"interval"`
+	// Reminders are how long before a deadline reminders are sent.
+	Reminders DurationList `yaml:"reminders" toml:"reminders"`
+	BatchSize int          `yaml:"batch_size" toml:"batch_size"`
+}
+
+// StorageConfig selects where uploaded files are kept. The local driver
+// writes them below Dir; s3 stores them in a bucket of Amazon S3 or an
+// S3-compatible service such as MinIO.
+type StorageConfig struct {
+	Driver      string `yaml:"driver" toml:"driver"`
+	Dir         string `yaml:"dir" toml:"dir"`
+	S3Endpoint  string `yaml:"s3_endpoint" toml:"s3_endpoint"`
+	S3Region    string `yaml:"s3_region" toml:"s3_region"`
+	S3Bucket    string `yaml:"s3_bucket" toml:"s3_bucket"`
+	S3AccessKey string `yaml:"s3_access_key" toml:"s3_access_key"`
+	S3SecretKey string `yaml:"s3_secret_key" toml:"s3_secret_key"`
+	// S3PathStyle addresses objects as endpoint/bucket/key rather than
+	// bucket.endpoint/key.
+	S3PathStyle bool `yaml:"s3_path_style" toml:"s3_path_style"`
+}
+
+// DocumentsConfig holds the limits of tender documents.
+type DocumentsConfig struct {
+	// MaxSize is the largest file accepted, in bytes.
+	MaxSize int64 `yaml:"max_size" toml:"max_size"`
+	// AllowedTypes are media types as detected from the file contents.
+	AllowedTypes StringList `yaml:"allowed_types" toml:"allowed_types"`
+	MaxPerTender int        `yaml:"max_per_tender" toml:"max_per_tender"`
+	// TransferTimeout replaces http.read_timeout and http.write_timeout for
+	// uploads and downloads, which stream whole files.
+	TransferTimeout Duration `yaml:"transfer_timeout" toml:"transfer_timeout"`
+}
+
+// ClarificationsConfig holds the questions contractors ask about tenders.
+type ClarificationsConfig struct {
+	// CloseBefore is how long before the deadline of a tender it stops
+	// taking questions, leaving the client time to answer.
+	CloseBefore Duration `yaml:"close_before" toml:"close_before"`
+}
+
+// SearchConfig holds the full-text search settings.
+type SearchConfig struct {
+	// Language is the Postgres text search configuration tenders and bids
+	// are indexed with, e.g. english, german or simple. Changing it rebuilds
+	// the search index at the next "server migrate up".
+	Language string `yaml:"language" toml:"language"`
+}
+
+// PaginationConfig holds the page sizes of list endpoints.
+type PaginationConfig struct {
+	// DefaultLimit is the page size when a request has no limit.
+	DefaultLimit int `yaml:"default_limit" toml:"default_limit"`
+	// MaxLimit caps the limit a request may ask for.
+	MaxLimit int `yaml:"max_limit" toml:"max_limit"`
+}
+
+// Default returns the configuration used when nothing else is provided.
+func Default() *Config {
+	return &Config{
+		HTTP: HTTPConfig{
+			Addr:            ":8888",
+			Mode:            "debug",
+			ReadTimeout:     Duration{15 * time.Second},
+			WriteTimeout:    Duration{15 * time.Second},
+			ShutdownTimeout: Duration{30 * time.Second},
+			DrainDelay:      Duration{5 * time.Second},
+		},
+		DB: DatabaseConfig{
+			Host:         "db",
+			Port:         5432,
+			User:         "postgres",
+			Password:     "postgres",
+			DBName:       "tender_db",
+			SSLMode:      "disable",
+			MaxOpenConns: 25,
+			MaxIdleConns: 10,
+		},
+		Redis: RedisConfig{
+			Addr: "localhost:6379",
+		},
+		JWT: JWTConfig{
+			Algorithm:          "HS256",
+			Secret:             DefaultJWTSecret,
+			TokenTTL:           Duration{15 * time.Minute},
+			RefreshTokenTTL:    Duration{30 * 24 * time.Hour},
+			KeyRotation:        Duration{30 * 24 * time.Hour},
+			KeyPublishLead:     Duration{time.Hour},
+			KeyRefreshInterval: Duration{time.Minute},
+		},
+		Casbin: CasbinConfig{
+			ModelPath:      "config/model.conf",
+			PolicyPath:     "config/policy.csv",
+			WatcherChannel: "casbin:policy:updates",
+			ReloadInterval: Duration{5 * time.Minute},
+		},
+		RateLimit: RateLimitConfig{
+			BidLimit:  5,
+			BidWindow: Duration{time.Minute},
+		},
+		Notification: NotificationConfig{
+			WriteTimeout: Duration{10 * time.Second},
+		},
+		Mail: MailConfig{
+			Driver:   "file",
+			From:     "no-reply@tender.local",
+			Dir:      "tmp/mail",
+			SMTPPort: 587,
+		},
+		Account: AccountConfig{
+			VerificationTokenTTL:  Duration{48 * time.Hour},
+			PasswordResetTokenTTL: Duration{time.Hour},
+			LinkBaseURL:           "http://localhost:3000",
+		},
+		TwoFactor: TwoFactorConfig{
+			Issuer:       "Tender Management",
+			ChallengeTTL: Duration{5 * time.Minute},
+		},
+		Login: LoginConfig{
+			MaxAccountFailures: 10,
+			MaxIPFailures:      100,
+			FailureWindow:      Duration{15 * time.Minute},
+			LockoutDuration:    Duration{15 * time.Minute},
+			DelayAfter:         3,
+			BaseDelay:          Duration{time.Second},
+			MaxDelay:           Duration{30 * time.Second},
+		},
+		APIKeys: APIKeysConfig{
+			MaxPerUser: 20,
+		},
+		Organizations: OrganizationsConfig{
+			InvitationTTL: Duration{7 * 24 * time.Hour},
+		},
+		Deadlines: DeadlinesConfig{
+			Interval:  Duration{time.Minute},
+			Reminders: DurationList{{24 * time.Hour}, {time.Hour}},
+			BatchSize: 100,
+		},
+		Storage: StorageConfig{
+			Driver:      "local",
+			Dir:         "tmp/blobs",
+			S3Region:    "us-east-1",
+			S3PathStyle: true,
+		},
+		Documents: DocumentsConfig{
+			MaxSize:         25 << 20,
+			AllowedTypes:    StringList{"application/pdf", "image/png", "image/jpeg", "application/zip", "text/plain"},
+			MaxPerTender:    20,
+			TransferTimeout: Duration{10 * time.Minute},
+		},
+		Clarifications: ClarificationsConfig{
+			CloseBefore: Duration{48 * time.Hour},
+		},
+		Search: SearchConfig{
+			Language: "english",
+		},
+		Pagination: PaginationConfig{
+			DefaultLimit: 50,
+			MaxLimit:     200,
+		},
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.HTTP.Addr != "", "http.addr is required")
+	check(c.HTTP.Mode == "debug" || c.HTTP.Mode == "release" || c.HTTP.Mode == "test",
+		"http.mode must be one of debug, release, test (got %q)", c.HTTP.Mode)
+	check(c.HTTP.ReadTimeout.Duration >= 0, "http.read_timeout must not be negative")
+	check(c.HTTP.WriteTimeout.Duration >= 0, "http.write_timeout must not be negative")
+	check(c.HTTP.ShutdownTimeout.Duration > 0, "http.shutdown_timeout must be positive")
+	check(c.HTTP.DrainDelay.Duration >= 0, "http.drain_delay must not be negative")
+
+	if c.DB.URL == "" {
+		check(c.DB.Host != "", "db.host is required")
+		check(c.DB.Port > 0 && c.DB.Port < 65536, "db.port must be between 1 and 65535")
+		check(c.DB.User != "", "db.user is required")
+		check(c.DB.DBName != "", "db.name is required")
+	} else {
+		check(strings.HasPrefix(c.DB.URL, "postgres://") || strings.HasPrefix(c.DB.URL, "postgresql://"),
+			"db.url must be a postgres:// URL")
+	}
+	check(c.DB.MaxOpenConns > 0, "db.max_open_conns must be positive")
+	check(c.DB.MaxIdleConns >= 0, "db.max_idle_conns must not be negative")
+
+	check(c.Redis.Addr != "", "redis.addr is required")
+	check(c.Redis.DB >= 0, "redis.db must not be negative")
+
+	check(c.JWT.Algorithm == "HS256" || c.JWT.Algorithm == "RS256" || c.JWT.Algorithm == "EdDSA",
+		"jwt.algorithm must be one of HS256, RS256, EdDSA (got %q)", c.JWT.Algorithm)
+	check(c.JWT.Secret != "", "jwt.secret is required")
+	if c.HTTP.Mode == "release" {
+		check(c.JWT.Secret != DefaultJWTSecret && c.JWT.Secret != "change-me",
+			"jwt.secret must be changed from the built-in or example value in release mode")
+	}
+	if c.JWT.Algorithm != "HS256" {
+		check(c.JWT.KeyRefreshInterval.Duration > 0, "jwt.key_refresh_interval must be positive")
+		check(c.JWT.KeyPublishLead.Duration > c.JWT.KeyRefreshInterval.Duration,
+			"jwt.key_publish_lead must be longer than jwt.key_refresh_interval")
+		check(c.JWT.KeyRotation.Duration > c.JWT.KeyPublishLead.Duration,
+			"jwt.key_rotation must be longer than jwt.key_publish_lead")
+	}
+	check(c.JWT.TokenTTL.Duration > 0, "jwt.token_ttl must be positive")
+	check(c.JWT.RefreshTokenTTL.Duration > c.JWT.TokenTTL.Duration, "jwt.refresh_token_ttl must be longer than jwt.token_ttl")
+
+	check(c.Casbin.ModelPath != "", "casbin.model_path is required")
+	check(c.Casbin.PolicyPath != "", "casbin.policy_path is required")
+	check(c.Casbin.WatcherChannel != "", "casbin.watcher_channel is required")
+	check(c.Casbin.ReloadInterval.Duration >= 0, "casbin.reload_interval must not be negative")
+
+	check(c.RateLimit.BidLimit > 0, "rate_limit.bid_limit must be positive")
+	check(c.RateLimit.BidWindow.Duration > 0, "rate_limit.bid_window must be positive")
+
+	check(c.Notification.WriteTimeout.Duration > 0, "notification.write_timeout must be positive")
+
+	check(c.Mail.Driver == "smtp" || c.Mail.Driver == "file" || c.Mail.Driver == "memory",
+		"mail.driver must be one of smtp, file, memory (got %q)", c.Mail.Driver)
+	check(c.Mail.From != "", "mail.from is required")
+	switch c.Mail.Driver {
+	case "smtp":
+		check(c.Mail.SMTPHost != "", "mail.smtp_host is required for the smtp driver")
+		check(c.Mail.SMTPPort > 0 && c.Mail.SMTPPort < 65536, "mail.smtp_port must be between 1 and 65535")
+	case "file":
+		check(c.Mail.Dir != "", "mail.dir is required for the file driver")
+	}
+
+	check(c.Account.VerificationTokenTTL.Duration > 0, "account.verification_token_ttl must be positive")
+	check(c.Account.PasswordResetTokenTTL.Duration > 0, "account.password_reset_token_ttl must be positive")
+	check(c.Account.LinkBaseURL != "", "account.link_base_url is required")
+
+	check(c.TwoFactor.Issuer != "", "two_factor.issuer is required")
+	check(c.TwoFactor.ChallengeTTL.Duration > 0, "two_factor.challenge_ttl must be positive")
+
+	check(c.Login.MaxAccountFailures > 0, "login_protection.max_account_failures must be positive")
+	check(c.Login.MaxIPFailures > 0, "login_protection.max_ip_failures must be positive")
+	check(c.Login.FailureWindow.Duration > 0, "login_protection.failure_window must be positive")
+	check(c.Login.LockoutDuration.Duration > 0, "login_protection.lockout_duration must be positive")
+	check(c.Login.DelayAfter >= 0, "login_protection.delay_after must not be negative")
+	check(c.Login.BaseDelay.Duration >= 0, "login_protection.base_delay must not be negative")
+	check(c.Login.MaxDelay.Duration >= c.Login.BaseDelay.Duration, "login_protection.max_delay must not be shorter than login_protection.base_delay")
+
+	check(c.APIKeys.MaxPerUser > 0, "api_keys.max_per_user must be positive")
+
+	check(c.Organizations.InvitationTTL.Duration > 0, "organizations.invitation_ttl must be positive")
+
+	check(c.Deadlines.Interval.Duration >= 0, "deadlines.interval must not be negative")
+	for _, d := range c.Deadlines.Reminders {
+		check(d.Duration > 0, "deadlines.reminders must be positive (got %s)", d)
+	}
+	check(c.Deadlines.BatchSize > 0, "deadlines.batch_size must be positive")
+
+	check(c.Storage.Driver == "local" || c.Storage.Driver == "s3",
+		"storage.driver must be one of local, s3 (got %q)", c.Storage.Driver)
+	switch c.Storage.Driver {
+	case "local":
+		check(c.Storage.Dir != "", "storage.dir is required for the local driver")
+	case "s3":
+		check(strings.HasPrefix(c.Storage.S3Endpoint, "http://") || strings.HasPrefix(c.Storage.S3Endpoint, "https://"),
+			"storage.s3_endpoint must be an http:// or https:// URL")
+		check(c.Storage.S3Region != "", "storage.s3_region is required for the s3 driver")
+		check(c.Storage.S3Bucket != "", "storage.s3_bucket is required for the s3 driver")
+		check(c.Storage.S3AccessKey != "" && c.Storage.S3SecretKey != "",
+			"storage.s3_access_key and storage.s3_secret_key are required for the s3 driver")
+	}
+
+	check(c.Documents.MaxSize > 0, "documents.max_size must be positive")
+	check(len(c.Documents.AllowedTypes) > 0, "documents.allowed_types must not be empty")
+	check(c.Documents.MaxPerTender > 0, "documents.max_per_tender must be positive")
+	check(c.Documents.TransferTimeout.Duration > 0, "documents.transfer_timeout must be positive")
+	check(c.Clarifications.CloseBefore.Duration >= 0, "clarifications.close_before must not be negative")
+	check(c.Search.Language != "", "search.language is required")
+	check(c.Pagination.MaxLimit > 0, "pagination.max_limit must be positive")
+	check(c.Pagination.DefaultLimit > 0 && c.Pagination.DefaultLimit <= c.Pagination.MaxLimit,
+		"pagination.default_limit must be positive and at most pagination.max_limit")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Duration is a time.Duration that can be read from strings such as "15s"
+// in config files, environment variables and flags.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	return d.Set(string(text))
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Set implements flag.Value.
+func (d *Duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// DurationList is a list of durations that is written as a comma separated
+// value in environment variables and flags.
+type DurationList []Duration
+
+func (l *DurationList) String() string {
+	if l == nil {
+		return ""
+	}
+	s := make([]string, len(*l))
+	for i, d := range *l {
+		s[i] = d.String()
+	}
+	return strings.Join(s, ",")
+}
+
+// Set implements flag.Value.
+func (l *DurationList) Set(s string) error {
+	*l = nil
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		var d Duration
+		if err := d.Set(item); err != nil {
+			return err
+		}
+		*l = append(*l, d)
+	}
+	return nil
+}
+
+// Durations returns the list as time.Durations.
+func (l DurationList) Durations() []time.Duration {
+	ds := make([]time.Duration, len(l))
+	for i, d := range l {
+		ds[i] = d.Duration
+	}
+	return ds
+}
+
+// StringList is a list of strings that is written as a comma separated value
+// in environment variables and flags.
+type StringList []string
+
+func (l *StringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+// Set implements flag.Value.
+func (l *StringList) Set(s string) error {
+	*l = nil
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
 }
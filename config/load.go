@@ -0,0 +1,215 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is prepended to every environment variable read by Load.
+const EnvPrefix = "TENDER_"
+
+// Load builds the configuration from defaults, an optional config file, the
+// environment and the given command line arguments, in that order, and
+// validates the result.
+//
+// The config file is taken from the -config flag or the TENDER_CONFIG
+// environment variable. Every flag has a matching environment variable, e.g.
+// -db.host is TENDER_DB_HOST. DATABASE_URL is honoured as well.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	path := configPath(args)
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	fs := cfg.FlagSet()
+	if err := applyEnv(fs); err != nil {
+		return nil, err
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// FlagSet returns a flag set bound to c. The current values of c are used as
+// flag defaults.
+func (c *Config) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+
+	fs.String("config", "", "path to a YAML or TOML config file")
+
+	fs.StringVar(&c.HTTP.Addr, "http.addr", c.HTTP.Addr, "HTTP listen address")
+	fs.StringVar(&c.HTTP.Mode, "http.mode", c.HTTP.Mode, "gin mode: debug, release or test")
+	fs.Var(&c.HTTP.ReadTimeout, "http.read-timeout", "HTTP read timeout")
+	fs.Var(&c.HTTP.WriteTimeout, "http.write-timeout", "HTTP write timeout")
+	fs.Var(&c.HTTP.ShutdownTimeout, "http.shutdown-timeout", "time allowed for draining requests and workers on shutdown")
+	fs.Var(&c.HTTP.DrainDelay, "http.drain-delay", "time readiness fails before listeners close on shutdown")
+
+	fs.StringVar(&c.DB.URL, "db.url", c.DB.URL, "Postgres URL, overrides the individual db flags")
+	fs.StringVar(&c.DB.Host, "db.host", c.DB.Host, "Postgres host")
+	fs.IntVar(&c.DB.Port, "db.port", c.DB.Port, "Postgres port")
+	fs.StringVar(&c.DB.User, "db.user", c.DB.User, "Postgres user")
+	fs.StringVar(&c.DB.Password, "db.password", c.DB.Password, "Postgres password")
+	fs.StringVar(&c.DB.DBName, "db.name", c.DB.DBName, "Postgres database name")
+	fs.StringVar(&c.DB.SSLMode, "db.sslmode", c.DB.SSLMode, "Postgres sslmode")
+	fs.IntVar(&c.DB.MaxOpenConns, "db.max-open-conns", c.DB.MaxOpenConns, "maximum open Postgres connections")
+	fs.IntVar(&c.DB.MaxIdleConns, "db.max-idle-conns", c.DB.MaxIdleConns, "maximum idle Postgres connections")
+	fs.BoolVar(&c.DB.AutoMigrate, "db.auto-migrate", c.DB.AutoMigrate, "apply pending migrations on startup")
+
+	fs.StringVar(&c.Redis.Addr, "redis.addr", c.Redis.Addr, "Redis address")
+	fs.StringVar(&c.Redis.Password, "redis.password", c.Redis.Password, "Redis password")
+	fs.IntVar(&c.Redis.DB, "redis.db", c.Redis.DB, "Redis database number")
+
+	fs.StringVar(&c.JWT.Algorithm, "jwt.algorithm", c.JWT.Algorithm, "JWT signing algorithm: HS256, RS256 or EdDSA")
+	fs.StringVar(&c.JWT.Secret, "jwt.secret", c.JWT.Secret, "HS256 signing secret, or the key encryption secret for RS256 and EdDSA")
+	fs.Var(&c.JWT.TokenTTL, "jwt.token-ttl", "access token lifetime")
+	fs.Var(&c.JWT.RefreshTokenTTL, "jwt.refresh-token-ttl", "refresh token lifetime")
+	fs.Var(&c.JWT.KeyRotation, "jwt.key-rotation", "how long an RS256/EdDSA key signs before it is replaced")
+	fs.Var(&c.JWT.KeyPublishLead, "jwt.key-publish-lead", "how long a new key is in the JWKS before it signs")
+	fs.Var(&c.JWT.KeyRefreshInterval, "jwt.key-refresh-interval", "how often the key set is reloaded")
+
+	fs.StringVar(&c.Casbin.ModelPath, "casbin.model-path", c.Casbin.ModelPath, "casbin model file")
+	fs.StringVar(&c.Casbin.PolicyPath, "casbin.policy-path", c.Casbin.PolicyPath, "casbin policy file imported into an empty policy table")
+	fs.StringVar(&c.Casbin.WatcherChannel, "casbin.watcher-channel", c.Casbin.WatcherChannel, "Redis channel announcing policy changes")
+	fs.Var(&c.Casbin.ReloadInterval, "casbin.reload-interval", "how often the policy is reloaded from Postgres (0 disables)")
+
+	fs.IntVar(&c.RateLimit.BidLimit, "rate-limit.bid-limit", c.RateLimit.BidLimit, "bids a contractor may submit per window")
+	fs.Var(&c.RateLimit.BidWindow, "rate-limit.bid-window", "bid rate limit window")
+
+	fs.Var(&c.Notification.AllowedOrigins, "notification.allowed-origins", "comma separated WebSocket origins, empty allows all")
+	fs.Var(&c.Notification.WriteTimeout, "notification.write-timeout", "WebSocket write timeout")
+
+	fs.StringVar(&c.Mail.Driver, "mail.driver", c.Mail.Driver, "mail delivery: smtp, file or memory")
+	fs.StringVar(&c.Mail.From, "mail.from", c.Mail.From, "sender address of outgoing email")
+	fs.StringVar(&c.Mail.Dir, "mail.dir", c.Mail.Dir, "directory the file driver writes messages to")
+	fs.StringVar(&c.Mail.SMTPHost, "mail.smtp-host", c.Mail.SMTPHost, "SMTP server host")
+	fs.IntVar(&c.Mail.SMTPPort, "mail.smtp-port", c.Mail.SMTPPort, "SMTP server port")
+	fs.StringVar(&c.Mail.SMTPUsername, "mail.smtp-username", c.Mail.SMTPUsername, "SMTP username")
+	fs.StringVar(&c.Mail.SMTPPassword, "mail.smtp-password", c.Mail.SMTPPassword, "SMTP password")
+
+	fs.Var(&c.Account.VerificationTokenTTL, "account.verification-token-ttl", "email verification link lifetime")
+	fs.Var(&c.Account.PasswordResetTokenTTL, "account.password-reset-token-ttl", "password reset link lifetime")
+	fs.StringVar(&c.Account.LinkBaseURL, "account.link-base-url", c.Account.LinkBaseURL, "frontend URL used in account email links")
+
+	fs.StringVar(&c.TwoFactor.Issuer, "two-factor.issuer", c.TwoFactor.Issuer, "issuer shown in authenticator apps")
+	fs.Var(&c.TwoFactor.ChallengeTTL, "two-factor.challenge-ttl", "time allowed to enter the code of a two-step login")
+	fs.BoolVar(&c.TwoFactor.RequireForSensitiveActions, "two-factor.require-for-sensitive-actions", c.TwoFactor.RequireForSensitiveActions, "require a 2FA session to award bids and delete tenders")
+
+	fs.IntVar(&c.Login.MaxAccountFailures, "login-protection.max-account-failures", c.Login.MaxAccountFailures, "failed logins that lock an account")
+	fs.IntVar(&c.Login.MaxIPFailures, "login-protection.max-ip-failures", c.Login.MaxIPFailures, "failed logins that lock a client IP address")
+	fs.Var(&c.Login.FailureWindow, "login-protection.failure-window", "how long failed logins are remembered after the last one")
+	fs.Var(&c.Login.LockoutDuration, "login-protection.lockout-duration", "how long a locked account or IP address stays locked")
+	fs.IntVar(&c.Login.DelayAfter, "login-protection.delay-after", c.Login.DelayAfter, "failed logins before an account has to wait between attempts")
+	fs.Var(&c.Login.BaseDelay, "login-protection.base-delay", "first wait between attempts, doubled with every further failure")
+	fs.Var(&c.Login.MaxDelay, "login-protection.max-delay", "longest wait between attempts")
+
+	fs.IntVar(&c.APIKeys.MaxPerUser, "api-keys.max-per-user", c.APIKeys.MaxPerUser, "active API keys a user may have")
+
+	fs.Var(&c.Organizations.InvitationTTL, "organizations.invitation-ttl", "how long an organization invitation can be accepted")
+
+	fs.Var(&c.Deadlines.Interval, "deadlines.interval", "how often tenders are closed at their deadline and reminders sent (0 disables)")
+	fs.Var(&c.Deadlines.Reminders, "deadlines.reminders", "comma separated times before a deadline to send reminders at")
+	fs.IntVar(&c.Deadlines.BatchSize, "deadlines.batch-size", c.Deadlines.BatchSize, "tenders closed or reminded per query")
+
+	fs.StringVar(&c.Storage.Driver, "storage.driver", c.Storage.Driver, "where uploaded files are kept: local or s3")
+	fs.StringVar(&c.Storage.Dir, "storage.dir", c.Storage.Dir, "directory the local driver writes files to")
+	fs.StringVar(&c.Storage.S3Endpoint, "storage.s3-endpoint", c.Storage.S3Endpoint, "S3 endpoint URL, e.g. https://s3.us-east-1.amazonaws.com")
+	fs.StringVar(&c.Storage.S3Region, "storage.s3-region", c.Storage.S3Region, "S3 region")
+	fs.StringVar(&c.Storage.S3Bucket, "storage.s3-bucket", c.Storage.S3Bucket, "S3 bucket")
+	fs.StringVar(&c.Storage.S3AccessKey, "storage.s3-access-key", c.Storage.S3AccessKey, "S3 access key ID")
+	fs.StringVar(&c.Storage.S3SecretKey, "storage.s3-secret-key", c.Storage.S3SecretKey, "S3 secret access key")
+	fs.BoolVar(&c.Storage.S3PathStyle, "storage.s3-path-style", c.Storage.S3PathStyle, "address S3 objects as endpoint/bucket/key")
+
+	fs.Int64Var(&c.Documents.MaxSize, "documents.max-size", c.Documents.MaxSize, "largest tender document accepted, in bytes")
+	fs.Var(&c.Documents.AllowedTypes, "documents.allowed-types", "comma separated media types of tender documents, detected from the contents")
+	fs.IntVar(&c.Documents.MaxPerTender, "documents.max-per-tender", c.Documents.MaxPerTender, "documents a tender may have")
+	fs.Var(&c.Documents.TransferTimeout, "documents.transfer-timeout", "read and write timeout of document uploads and downloads")
+
+	fs.Var(&c.Clarifications.CloseBefore, "clarifications.close-before", "how long before the deadline of a tender questions about it close")
+
+	fs.StringVar(&c.Search.Language, "search.language", c.Search.Language, "Postgres text search configuration of the search index, e.g. english or simple")
+
+	fs.IntVar(&c.Pagination.DefaultLimit, "pagination.default-limit", c.Pagination.DefaultLimit, "page size of list endpoints when a request has no limit")
+	fs.IntVar(&c.Pagination.MaxLimit, "pagination.max-limit", c.Pagination.MaxLimit, "largest page size a request may ask for")
+
+	return fs
+}
+
+// EnvName returns the environment variable that maps to a flag name.
+func EnvName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(flagName))
+}
+
+func applyEnv(fs *flag.FlagSet) error {
+	if url := os.Getenv("DATABASE_URL"); url != "" {
+		if err := fs.Set("db.url", url); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || f.Name == "config" {
+			return
+		}
+		name := EnvName(f.Name)
+		if value, ok := os.LookupEnv(name); ok {
+			if setErr := f.Value.Set(value); setErr != nil {
+				err = fmt.Errorf("invalid %s: %w", name, setErr)
+			}
+		}
+	})
+	return err
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".toml":
+		err = toml.Unmarshal(data, c)
+	default:
+		return fmt.Errorf("unsupported config file format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// configPath finds the config file before the full flag set is parsed, so
+// that flags can still override values from the file.
+func configPath(args []string) string {
+	for i, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		if value, ok := strings.CutPrefix(name, "config="); ok {
+			return value
+		}
+		if name == "config" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return os.Getenv(EnvPrefix + "CONFIG")
+}
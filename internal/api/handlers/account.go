@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Dostonlv/hackathon-nt/internal/api/middleware"
+	"github.com/Dostonlv/hackathon-nt/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// AccountHandler handles email verification and password reset requests
+type AccountHandler struct {
+	accountService *service.AccountService
+}
+
+// NewAccountHandler creates a new AccountHandler
+func NewAccountHandler(accountService *service.AccountService) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+	}
+}
+
+// VerifyEmail godoc
+// @Summary Verify email address
+// @Description Confirm the email address with the token from the verification email. A token can be used once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body service.VerifyEmailInput true "Verification token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/verify-email [post]
+func (h *AccountHandler) VerifyEmail(c *gin.Context) {
+	var input service.VerifyEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Token == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Token is required"})
+		return
+	}
+
+	if err := h.accountService.VerifyEmail(c.Request.Context(), input); err != nil {
+		if errors.Is(err, service.ErrInvalidAccountToken) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid or expired token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// ResendVerification godoc
+// @Summary Resend verification email
+// @Description Send a new verification link to the current user. Earlier links stop working.
+// @Tags auth
+// @Produce json
+// @Success 202 {object} map[string]string
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/auth/verify-email/resend [post]
+func (h *AccountHandler) ResendVerification(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	if err := h.accountService.SendVerificationEmail(c.Request.Context(), principal.UserID); err != nil {
+		if errors.Is(err, service.ErrEmailAlreadyVerified) {
+			c.JSON(http.StatusConflict, ErrorResponse{Message: "Email already verified"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+}
+
+// RequestPasswordReset godoc
+// @Summary Request a password reset
+// @Description Email a password reset link if an account with the address exists. The response is the same either way.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body service.PasswordResetRequestInput true "Account email"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/password-reset/request [post]
+func (h *AccountHandler) RequestPasswordReset(c *gin.Context) {
+	var input service.PasswordResetRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Email == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Email is required"})
+		return
+	}
+
+	if err := h.accountService.RequestPasswordReset(c.Request.Context(), input); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to request password reset"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the account exists, a reset link has been sent"})
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Set a new password with the token from the reset email. Every session of the user is signed out.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body service.PasswordResetInput true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/password-reset [post]
+func (h *AccountHandler) ResetPassword(c *gin.Context) {
+	var input service.PasswordResetInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Token == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Token and password are required"})
+		return
+	}
+
+	if err := h.accountService.ResetPassword(c.Request.Context(), input); err != nil {
+		switch {
+		case errors.Is(err, service.ErrWeakPassword):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		case errors.Is(err, service.ErrInvalidAccountToken):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid or expired token"})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to reset password"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated"})
+}
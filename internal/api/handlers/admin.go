@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/Dostonlv/hackathon-nt/internal/api/middleware"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/Dostonlv/hackathon-nt/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AdminHandler serves the administration API.
+type AdminHandler struct {
+	authService    *service.AuthService
+	adminService   *service.AdminService
+	historyService *service.HistoryService
+	securityLog    *service.SecurityLog
+	pagination     Pagination
+}
+
+// NewAdminHandler creates a new AdminHandler
+func NewAdminHandler(authService *service.AuthService, adminService *service.AdminService, historyService *service.HistoryService, securityLog *service.SecurityLog, pagination Pagination) *AdminHandler {
+	return &AdminHandler{
+		authService:    authService,
+		adminService:   adminService,
+		historyService: historyService,
+		securityLog:    securityLog,
+		pagination:     pagination,
+	}
+}
+
+// ListUsers godoc
+// @Summary List users
+// @Description List and search users, newest first.
+// @Tags admin
+// @Produce json
+// @Param search query string false "Part of the username or email"
+// @Param role query string false "client, contractor or admin"
+// @Param status query string false "active or suspended"
+// @Param limit query int false "Maximum number of users (default 50, max 200)"
+// @Param offset query int false "Number of users to skip"
+// @Success 200 {array} models.User
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/users [get]
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	filters := repository.UserFilters{
+		Search: c.Query("search"),
+		Role:   c.Query("role"),
+	}
+	switch c.Query("status") {
+	case "":
+	case "active":
+		suspended := false
+		filters.Suspended = &suspended
+	case "suspended":
+		suspended := true
+		filters.Suspended = &suspended
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Status must be active or suspended"})
+		return
+	}
+	for name, dst := range map[string]*int{"limit": &filters.Limit, "offset": &filters.Offset} {
+		if v := c.Query(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid " + name})
+				return
+			}
+			*dst = n
+		}
+	}
+
+	users, err := h.adminService.ListUsers(c.Request.Context(), filters)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRole) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid role"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to list users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// GetUser godoc
+// @Summary Get a user
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.User
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/users/{id} [get]
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.adminService.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		abortAdmin(c, err, "Failed to get user")
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// SuspendUser godoc
+// @Summary Suspend a user
+// @Description Block the account and end all of its sessions. Access tokens and API keys of the user are rejected from the next request on. Suspending a suspended user ends its sessions again and answers 409.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param input body service.SuspendUserInput false "Reason"
+// @Success 200 {object} models.User
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/users/{id}/suspend [post]
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input service.SuspendUserInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil || len(input.Reason) > 500 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request payload"})
+			return
+		}
+	}
+
+	user, err := h.adminService.SuspendUser(c.Request.Context(), principal.UserID, userID, input)
+	if err != nil {
+		abortAdmin(c, err, "Failed to suspend user")
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ReactivateUser godoc
+// @Summary Reactivate a user
+// @Description Lift a suspension. The user has to log in again.
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.User
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/users/{id}/reactivate [post]
+func (h *AdminHandler) ReactivateUser(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.adminService.ReactivateUser(c.Request.Context(), principal.UserID, userID)
+	if err != nil {
+		abortAdmin(c, err, "Failed to reactivate user")
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ChangeUserRole godoc
+// @Summary Change a user's role
+// @Description Give the user another role and end their sessions, since access tokens carry the role.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param input body service.ChangeRoleInput true "New role"
+// @Success 200 {object} models.User
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/users/{id}/role [put]
+func (h *AdminHandler) ChangeUserRole(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input service.ChangeRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request payload"})
+		return
+	}
+
+	user, err := h.adminService.ChangeRole(c.Request.Context(), principal.UserID, userID, input)
+	if err != nil {
+		abortAdmin(c, err, "Failed to change role")
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ForcePasswordReset godoc
+// @Summary Force a password reset
+// @Description Invalidate the user's password and sessions and email them a reset link.
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/users/{id}/password-reset [post]
+func (h *AdminHandler) ForcePasswordReset(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.adminService.ForcePasswordReset(c.Request.Context(), principal.UserID, userID); err != nil {
+		abortAdmin(c, err, "Failed to reset password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset, the user has been emailed a link"})
+}
+
+// GetUserTenders godoc
+// @Summary List a user's tenders
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param total query bool false "Also count all tenders"
+// @Success 200 {object} models.Page[models.Tender]
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/users/{id}/tenders [get]
+func (h *AdminHandler) GetUserTenders(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	page, ok := h.pagination.pageRequest(c)
+	if !ok {
+		return
+	}
+
+	tenders, err := h.historyService.GetTenderHistory(c.Request.Context(), userID, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to retrieve tenders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tenders)
+}
+
+// GetUserBids godoc
+// @Summary List a user's bids
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param total query bool false "Also count all bids"
+// @Success 200 {object} models.Page[models.Bid]
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/users/{id}/bids [get]
+func (h *AdminHandler) GetUserBids(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	page, ok := h.pagination.pageRequest(c)
+	if !ok {
+		return
+	}
+
+	bids, err := h.historyService.GetBidHistory(c.Request.Context(), userID, page)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to retrieve bids"})
+		return
+	}
+
+	c.JSON(http.StatusOK, bids)
+}
+
+// UnlockUser godoc
+// @Summary Unlock a user's logins
+// @Description Lift a lockout caused by failed logins and forget the user's failed attempts.
+// @Tags admin
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/users/{id}/unlock [post]
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.UnlockAccount(c.Request.Context(), principal.UserID, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to unlock user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked"})
+}
+
+// UnlockIP godoc
+// @Summary Unlock an IP address
+// @Description Lift a lockout of an IP address caused by failed logins and forget its failed attempts.
+// @Tags admin
+// @Produce json
+// @Param ip path string true "IP address"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/ips/{ip}/unlock [post]
+func (h *AdminHandler) UnlockIP(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	ip := net.ParseIP(c.Param("ip"))
+	if ip == nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid IP address"})
+		return
+	}
+
+	if err := h.authService.UnlockIP(c.Request.Context(), principal.UserID, ip.String()); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to unlock IP address"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "IP address unlocked"})
+}
+
+// ListSecurityEvents godoc
+// @Summary List security events
+// @Description List logins, failed logins, lockouts and unlocks, newest first.
+// @Tags admin
+// @Produce json
+// @Param user_id query string false "User ID"
+// @Param ip query string false "IP address"
+// @Param type query string false "Event type"
+// @Param limit query int false "Maximum number of events (max 500)"
+// @Success 200 {array} models.SecurityEvent
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/security-events [get]
+func (h *AdminHandler) ListSecurityEvents(c *gin.Context) {
+	filters := repository.SecurityEventFilters{
+		IP:   c.Query("ip"),
+		Type: c.Query("type"),
+	}
+	if v := c.Query("user_id"); v != "" {
+		userID, err := uuid.Parse(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid user ID"})
+			return
+		}
+		filters.UserID = &userID
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid limit"})
+			return
+		}
+		filters.Limit = limit
+	}
+
+	events, err := h.securityLog.List(c.Request.Context(), filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to list security events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+func userIDParam(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid user ID"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func abortAdmin(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "User not found"})
+	case errors.Is(err, service.ErrInvalidRole):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Role must be client, contractor or admin"})
+	case errors.Is(err, service.ErrCannotModifySelf):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "You cannot suspend or change the role of your own account"})
+	case errors.Is(err, service.ErrAlreadySuspended):
+		c.JSON(http.StatusConflict, ErrorResponse{Message: "User is already suspended"})
+	case errors.Is(err, service.ErrNotSuspended):
+		c.JSON(http.StatusConflict, ErrorResponse{Message: "User is not suspended"})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: message})
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Dostonlv/hackathon-nt/internal/api/middleware"
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// APIKeyHandler handles the management of a user's API keys
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create an API key for integrations. It acts with the current user's role, limited to its scopes (tenders:read, tenders:write, bids:read, bids:write). The key is only shown in this response.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body service.CreateAPIKeyInput true "Name, scopes and optional expiry"
+// @Success 201 {object} service.CreatedAPIKey
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/auth/api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	var input service.CreateAPIKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request payload"})
+		return
+	}
+
+	key, err := h.apiKeyService.Create(c.Request.Context(), principal.UserID, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidAPIKeyName),
+			errors.Is(err, service.ErrInvalidAPIKeyScope),
+			errors.Is(err, service.ErrAPIKeyExpiry):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		case errors.Is(err, service.ErrTooManyAPIKeys):
+			c.JSON(http.StatusConflict, ErrorResponse{Message: "Too many active API keys, revoke one first"})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to create API key"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description List the current user's API keys, including revoked and expired ones.
+// @Tags auth
+// @Produce json
+// @Success 200 {array} models.APIKey
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/auth/api-keys [get]
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apiKeyService.List(c.Request.Context(), principal.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to list API keys"})
+		return
+	}
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revoke one of the current user's API keys. It stops working immediately.
+// @Tags auth
+// @Produce json
+// @Param id path string true "API key ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/auth/api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid API key ID"})
+		return
+	}
+
+	if err := h.apiKeyService.Revoke(c.Request.Context(), principal.UserID, keyID); err != nil {
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "API key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to revoke API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
@@ -1,22 +1,29 @@
 package handlers
 
 import (
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/Dostonlv/hackathon-nt/internal/api/middleware"
 	"github.com/Dostonlv/hackathon-nt/internal/service"
 	"github.com/gin-gonic/gin"
 )
 
 // AuthHandler handles authentication-related requests
 type AuthHandler struct {
-	authService *service.AuthService
+	authService    *service.AuthService
+	accountService *service.AccountService
 }
 
 // NewAuthHandler creates a new AuthHandler
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, accountService *service.AccountService) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
+		authService:    authService,
+		accountService: accountService,
 	}
 }
 
@@ -76,20 +83,28 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// The account works without a verified email, so a failed send is not fatal
+	if err := h.accountService.SendVerificationEmail(c.Request.Context(), resp.User.ID); err != nil {
+		log.Printf("Failed to send verification email to user %s: %v", resp.User.ID, err)
+	}
+
 	// Success response
 	c.JSON(http.StatusCreated, resp)
 }
 
 // Login godoc
 // @Summary Login a user
-// @Description Login a user with the provided credentials
+// @Description Login a user with the provided credentials. When the user has two-factor authentication enabled, the response is a service.TwoFactorChallenge to complete at /login/2fa instead of tokens. Repeated failures are slowed down and lock the account or address temporarily; such attempts get 429 with a Retry-After header.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param input body service.LoginInput true "Login Input"
 // @Success 200 {object} service.AuthResponse
+// @Success 202 {object} service.TwoFactorChallenge
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -105,22 +120,132 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Username and password are required"})
 		return
 	}
+	input.IP = c.ClientIP()
+	input.UserAgent = c.Request.UserAgent()
 
 	// Call AuthService to login
-	resp, err := h.authService.Login(c.Request.Context(), input)
+	resp, challenge, err := h.authService.Login(c.Request.Context(), input)
 	if err != nil {
-		// Handle specific errors
-		switch err.Error() {
-		case "invalid credentials":
-			c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Invalid username or password"})
-		case "user not found":
-			c.JSON(http.StatusNotFound, ErrorResponse{Message: "User not found"})
+		abortLogin(c, err)
+		return
+	}
+
+	if challenge != nil {
+		c.JSON(http.StatusAccepted, challenge)
+		return
+	}
+
+	// Success response
+	c.JSON(http.StatusOK, resp)
+}
+
+// Refresh godoc
+// @Summary Refresh an access token
+// @Description Exchange a refresh token for a new access token and a new refresh token. The presented refresh token stops working; presenting it again revokes the whole session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body service.RefreshInput true "Refresh Input"
+// @Success 200 {object} service.AuthResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var input service.RefreshInput
+
+	if err := c.ShouldBindJSON(&input); err != nil || input.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request payload"})
+		return
+	}
+
+	resp, err := h.authService.Refresh(c.Request.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRefreshToken):
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Invalid refresh token"})
+		case errors.Is(err, service.ErrRefreshTokenReused):
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Refresh token reuse detected, please log in again"})
+		case errors.Is(err, service.ErrAccountSuspended):
+			c.JSON(http.StatusForbidden, ErrorResponse{Message: "Account suspended"})
 		default:
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to login user"})
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to refresh token"})
 		}
 		return
 	}
 
-	// Success response
 	c.JSON(http.StatusOK, resp)
 }
+
+// Logout godoc
+// @Summary Log out
+// @Description Revoke the session of the given refresh token and, when an Authorization header is sent, the access token.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body service.RefreshInput true "Refresh Input"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var input service.RefreshInput
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request payload"})
+		return
+	}
+
+	if err := h.authService.Logout(c.Request.Context(), input.RefreshToken, c.GetHeader("Authorization")); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// LogoutAll godoc
+// @Summary Log out of all sessions
+// @Description Revoke every refresh token and access token of the current user.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.LogoutAll(c.Request.Context(), principal.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions"})
+}
+
+// abortLogin answers a failed login. Unknown usernames and wrong passwords
+// get the same response.
+func abortLogin(c *gin.Context, err error) {
+	var blocked *service.LoginBlockedError
+	switch {
+	case errors.As(err, &blocked):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, ErrorResponse{Message: "Too many failed login attempts, try again later"})
+	case errors.Is(err, service.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Invalid username or password"})
+	case errors.Is(err, service.ErrAccountSuspended):
+		c.JSON(http.StatusForbidden, ErrorResponse{Message: "Account suspended"})
+	case errors.Is(err, service.ErrInvalidLoginChallenge):
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Login challenge expired, sign in again"})
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Invalid code"})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to login user"})
+	}
+}
@@ -2,9 +2,10 @@ package handlers
 
 import (
 	"errors"
+	"log"
 	"net/http"
-	"strconv"
 
+	"github.com/Dostonlv/hackathon-nt/internal/api/middleware"
 	"github.com/Dostonlv/hackathon-nt/internal/repository"
 	"github.com/Dostonlv/hackathon-nt/internal/service"
 	"github.com/Dostonlv/hackathon-nt/internal/utils"
@@ -16,12 +17,14 @@ import (
 type BidHandler struct {
 	bidService          *service.BidService
 	notificationService *utils.NotificationService
+	pagination          Pagination
 }
 
-func NewBidHandler(bidService *service.BidService, notificationService *utils.NotificationService) *BidHandler {
+func NewBidHandler(bidService *service.BidService, notificationService *utils.NotificationService, pagination Pagination) *BidHandler {
 	return &BidHandler{
 		bidService:          bidService,
 		notificationService: notificationService,
+		pagination:          pagination,
 	}
 }
 
@@ -29,29 +32,35 @@ type CreateBidRequest struct {
 	Price        float64 `json:"price"`
 	DeliveryTime int     `json:"delivery_time"`
 	Comments     string  `json:"comments"`
+	// OrganizationID is the contractor organization bidding. It may be left
+	// out by members of a single contractor organization.
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
 }
 
 type Bid struct {
-	ID           uuid.UUID `json:"id"`
-	TenderID     uuid.UUID `json:"tender_id"`
-	ContractorID uuid.UUID `json:"contractor_id"`
-	Price        float64   `json:"price"`
-	DeliveryTime int       `json:"delivery_time"`
-	Comments     string    `json:"comments"`
-	Status       string    `json:"status"`
+	ID             uuid.UUID `json:"id"`
+	TenderID       uuid.UUID `json:"tender_id"`
+	ContractorID   uuid.UUID `json:"contractor_id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Price          float64   `json:"price"`
+	DeliveryTime   int       `json:"delivery_time"`
+	Comments       string    `json:"comments"`
+	Status         string    `json:"status"`
 }
 
 // CreateBid handles the creation of a new bid for a specific tender.
 //
 // @Summary Create a new bid
-// @Description This endpoint allows a contractor to create a new bid for a specified tender. The contractor must provide the bid details in the request body.
+// @Description This endpoint allows a contractor to create a new bid for a specified tender. The contractor must provide the bid details in the request body. The bid belongs to the contractor organization given as organization_id, which may be left out by members of a single contractor organization.
 // @Tags bids
 // @Accept json
 // @Produce json
 // @Param tender_id path string true "Tender ID"
 // @Param bid body CreateBidRequest true "Bid details"
 // @Success 201 {object} Bid "Successfully created bid"
-// @Failure 400 {object} ErrorResponse "Invalid tender ID or bad request body"
+// @Failure 400 {object} ErrorResponse "Bad request body, or the tender is not open or past its deadline"
+// @Failure 403 {object} ErrorResponse "Not allowed to bid on the tender"
+// @Failure 404 {object} ErrorResponse "Tender or organization not found"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Security BearerAuth
 // @Router /api/contractor/tenders/{tender_id}/bid [post]
@@ -73,37 +82,52 @@ func (h *BidHandler) CreateBid(c *gin.Context) {
 		return
 	}
 
-	userID, _ := c.Get("userId")
-	contractorID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid user ID"})
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
 		return
 	}
+	contractorID := principal.UserID
 
 	bid, err := h.bidService.CreateBid(c.Request.Context(), service.CreateBidInput{
-		TenderID:     tenderID,
-		ContractorID: contractorID,
-		Price:        req.Price,
-		DeliveryTime: req.DeliveryTime,
-		Comments:     req.Comments,
+		TenderID:       tenderID,
+		ContractorID:   contractorID,
+		OrganizationID: req.OrganizationID,
+		Price:          req.Price,
+		DeliveryTime:   req.DeliveryTime,
+		Comments:       req.Comments,
 	})
 
 	if err != nil {
+		if errors.Is(err, service.ErrTenderNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found"})
+			return
+		}
 		if errors.Is(err, service.ErrInvalidTender) {
 			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Tender is not open for bids"})
 			return
 		}
+		if errors.Is(err, service.ErrBiddingClosed) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "The deadline of the tender has passed"})
+			return
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, ErrorResponse{Message: "Access denied"})
+			return
+		}
+		if abortOrganizationChoice(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusCreated, bid)
-	clientID, err := h.bidService.GetClientIDByTenderID(c.Request.Context(), tenderID)
+	team, err := h.bidService.TenderTeam(c.Request.Context(), tenderID)
 	if err != nil {
 		// Log the error but don't fail the bid creation
-		pp.Printf("Failed to get client ID for notification: %v", err)
+		pp.Printf("Failed to get tender team for notification: %v", err)
 	} else {
-		// Send notification to client
+		// Send notification to the client organization
 		notification := utils.BidNotification{
 			Type:     "new_bid",
 			TenderID: tenderID,
@@ -111,109 +135,45 @@ func (h *BidHandler) CreateBid(c *gin.Context) {
 			Price:    bid.Price,
 			Message:  "New bid received for your tender",
 		}
-		if err := h.notificationService.NotifyNewBid(c.Request.Context(), clientID, notification); err != nil {
-			// Log the error but don't fail the bid creation
-			pp.Printf("Failed to send notification: %v", err)
-		}
-
-	}
-
-}
-
-// ListBids retrieves a list of bids for a specific tender.
-//
-// @Summary List bids for a tender
-// @Description This endpoint retrieves a list of bids for a specified tender. The list can be filtered and sorted using query parameters.
-// @Tags bids
-// @Accept json
-// @Produce json
-// @Param tender_id path string true "Tender ID"
-// @Param status query string false "Filter by bid status"
-// @Param search query string false "Search bids by comments"
-// @Param min_price query number false "Minimum bid price"
-// @Param max_price query number false "Maximum bid price"
-// @Param min_delivery_time query int false "Minimum delivery time"
-// @Param max_delivery_time query int false "Maximum delivery time"
-// @Param sort_by query string false "Sort by field"
-// @Param sort_order query string false "Sort order (asc or desc)"
-// @Success 200 {array} Bid "List of bids"
-// @Failure 400 {object} ErrorResponse "Invalid tender ID"
-// @Failure 500 {object} ErrorResponse "Internal server error"
-// @Security BearerAuth
-// @Router /api/contractor/tenders/{tender_id}/bids [get]
-func (h *BidHandler) ListBids(c *gin.Context) {
-	tenderID, err := uuid.Parse(c.Param("tender_id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid tender ID"})
-		return
-	}
-
-	filters := repository.BidFilters{
-		Status:       c.Query("status"),
-		Search:       c.Query("search"),
-		Price:        parseFloatQuery(c, "price"),
-		DeliveryTime: parseIntQuery(c, "delivery_time"),
-		MinPrice:     parseFloatQuery(c, "min_price"),
-		MaxPrice:     parseFloatQuery(c, "max_price"),
-		MinDelivery:  parseIntQuery(c, "min_delivery_time"),
-		MaxDelivery:  parseIntQuery(c, "max_delivery_time"),
-		SortBy:       c.Query("sort_by"),
-		SortOrder:    c.Query("sort_order"),
-	}
-
-	bids, err := h.bidService.ListBids(c.Request.Context(), tenderID, filters)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, bids)
-}
-
-func parseFloatQuery(c *gin.Context, key string) *float64 {
-	if value := c.Query(key); value != "" {
-		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
-			return &floatValue
+		for _, userID := range team {
+			if err := h.notificationService.NotifyNewBid(c.Request.Context(), userID, notification); err != nil {
+				// Log the error but don't fail the bid creation
+				pp.Printf("Failed to send notification: %v", err)
+			}
 		}
 	}
-	return nil
-}
 
-func parseIntQuery(c *gin.Context, key string) *int {
-	if value := c.Query(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
-			return &intValue
-		}
-	}
-	return nil
 }
 
 // GetBidsByContractorID retrieves a list of bids made by a specific contractor.
 //
 // @Summary Get bids by contractor ID
-// @Description This endpoint retrieves a list of bids made by a specific contractor.
+// @Description This endpoint retrieves the bids of every contractor organization of the caller.
 // @Tags bids
 // @Accept json
 // @Produce json
 // @Param contractor_id path string true "Contractor ID"
-// @Success 200 {array} Bid "List of bids"
-// @Failure 400 {object} ErrorResponse "Invalid contractor ID"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param total query bool false "Also count all bids"
+// @Success 200 {object} models.Page[models.Bid] "Page of bids, newest first"
+// @Failure 400 {object} ErrorResponse "Invalid contractor ID or page"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Security BearerAuth
 // @Router /api/contractor/bids [get]
 func (h *BidHandler) GetBidsByContractorID(c *gin.Context) {
 
-	contractorID, _ := c.Get("userId")
-
-	contractorUUID, err := uuid.Parse(contractorID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid contractor ID"})
+	if _, ok := middleware.RequirePrincipal(c); !ok {
+		return
+	}
+	page, ok := h.pagination.pageRequest(c)
+	if !ok {
 		return
 	}
 
-	bids, err := h.bidService.GetBidsByContractorID(c.Request.Context(), contractorUUID)
+	bids, err := h.bidService.ListContractorBids(c.Request.Context(), page)
 	if err != nil {
-		pp.Println("kirdi")
+		log.Printf("Failed to list contractor bids: %v", err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
 		return
 	}
@@ -224,13 +184,18 @@ func (h *BidHandler) GetBidsByContractorID(c *gin.Context) {
 // GetBidsByClientID retrieves a list of bids made by a specific client.
 //
 // @Summary Get bids by client ID
-// @Description This endpoint retrieves a list of bids made by a specific client for a specified tender.
+// @Description This endpoint retrieves the bids on a tender of the caller's client organization. With search, only the bids whose comments match the web search style query are returned, best match first, each with a highlighted snippet.
 // @Tags bids
 // @Accept json
 // @Produce json
 // @Param tender_id path string true "Tender ID"
-// @Success 200 {array} Bid "List of bids"
-// @Failure 400 {object} ErrorResponse "Invalid tender ID or client ID"
+// @Param search query string false "Full-text search of the bid comments"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param total query bool false "Also count all bids"
+// @Success 200 {object} models.Page[models.Bid] "Page of bids"
+// @Failure 400 {object} ErrorResponse "Invalid page"
+// @Failure 404 {object} ErrorResponse "Tender not found or access denied"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Security BearerAuth
 // @Router /api/client/tenders/{tender_id}/bids [get]
@@ -241,16 +206,25 @@ func (h *BidHandler) GetBidsByClientID(c *gin.Context) {
 		return
 	}
 
-	clientID, _ := c.Get("userId")
-	clientUUID, err := uuid.Parse(clientID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid client ID"})
+	if _, ok := middleware.RequirePrincipal(c); !ok {
+		return
+	}
+	page, ok := h.pagination.pageRequest(c)
+	if !ok {
 		return
 	}
 
-	bids, err := h.bidService.GetBidsByClientID(c.Request.Context(), clientUUID, tenderID)
+	bids, err := h.bidService.GetTenderBids(c.Request.Context(), tenderID, c.Query("search"), page)
 	if err != nil {
-		pp.Println("kirdi")
+		if errors.Is(err, service.ErrTenderNotFound) || errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+			return
+		}
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid cursor"})
+			return
+		}
+		log.Printf("Failed to list the bids of tender %s: %v", tenderID, err)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
 		return
 	}
@@ -268,7 +242,8 @@ func (h *BidHandler) GetBidsByClientID(c *gin.Context) {
 // @Param tender_id path string true "Tender ID"
 // @Param bid_id path string true "Bid ID"
 // @Success 200 {object} Bid "Successfully awarded bid"
-// @Failure 400 {object} ErrorResponse "Invalid tender ID or bid ID"
+// @Failure 409 {object} ErrorResponse "Only closed or evaluated tenders can be awarded"
+// @Failure 404 {object} ErrorResponse "Tender or bid not found or access denied"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Security BearerAuth
 // @Router /api/client/tenders/{tender_id}/award/{bid_id} [post]
@@ -285,22 +260,19 @@ func (h *BidHandler) AwardBid(c *gin.Context) {
 		return
 	}
 
-	clientID, _ := c.Get("userId")
-	clientUUID, err := uuid.Parse(clientID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid client ID"})
+	if _, ok := middleware.RequirePrincipal(c); !ok {
 		return
 	}
 
-	err = h.bidService.AwardBid(c.Request.Context(), clientUUID, tenderID, bidID)
+	err = h.bidService.AwardBid(c.Request.Context(), tenderID, bidID)
 	if err != nil {
-		if err.Error() == "unauthorized: client does not own the tender" || err.Error() == "bid not found" {
+		if errors.Is(err, service.ErrTenderNotFound) || errors.Is(err, service.ErrBidNotFound) || errors.Is(err, service.ErrForbidden) {
 			c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
 			return
 		}
 
-		if errors.Is(err, service.ErrInvalidTender) {
-			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Tender is not open for bids"})
+		if errors.Is(err, service.ErrInvalidTransition) {
+			c.JSON(http.StatusConflict, ErrorResponse{Message: "Only closed or evaluated tenders can be awarded"})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
@@ -309,20 +281,26 @@ func (h *BidHandler) AwardBid(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Bid awarded successfully"})
 
-	// Send notification to contractor
+	// Send notification to the contractor organization
 	bid, err := h.bidService.GetBidByID(c.Request.Context(), bidID)
 	if err != nil {
 		pp.Printf("Failed to get bid for notification: %v", err)
 		return
-	} else {
-		notification := utils.BidNotification{
-			Type:     "bid_awarded",
-			TenderID: tenderID,
-			BidID:    bid.ID,
-			Price:    bid.Price,
-			Message:  "Your bid has been awarded",
-		}
-		if err := h.notificationService.NotifyAward(c.Request.Context(), bid.ContractorID, notification); err != nil {
+	}
+	team, err := h.bidService.BidTeam(c.Request.Context(), bid)
+	if err != nil {
+		pp.Printf("Failed to get bid team for notification: %v", err)
+		return
+	}
+	notification := utils.BidNotification{
+		Type:     "bid_awarded",
+		TenderID: tenderID,
+		BidID:    bid.ID,
+		Price:    bid.Price,
+		Message:  "Your bid has been awarded",
+	}
+	for _, userID := range team {
+		if err := h.notificationService.NotifyAward(c.Request.Context(), userID, notification); err != nil {
 			pp.Printf("Failed to send notification: %v", err)
 		}
 	}
@@ -332,13 +310,14 @@ func (h *BidHandler) AwardBid(c *gin.Context) {
 // DeleteBidByContractorID deletes a specific bid made by a contractor.
 //
 // @Summary Delete a bid by contractor ID
-// @Description This endpoint allows a contractor to delete a specific bid they made.
+// @Description This endpoint allows owners, managers and bidders of the contractor organization to withdraw one of its bids while the tender is open.
 // @Tags bids
 // @Accept json
 // @Produce json
 // @Param bid_id path string true "Bid ID"
 // @Success 200 {object} string "Successfully deleted bid"
-// @Failure 400 {object} ErrorResponse "Invalid bid ID or contractor ID"
+// @Failure 404 {object} ErrorResponse "Bid not found or access denied"
+// @Failure 409 {object} ErrorResponse "The tender is no longer open"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Security BearerAuth
 // @Router /api/contractor/bids/{bid_id} [delete]
@@ -349,21 +328,18 @@ func (h *BidHandler) DeleteBidByContractorID(c *gin.Context) {
 		return
 	}
 
-	contractorID, _ := c.Get("userId")
-	contractorUUID, err := uuid.Parse(contractorID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid contractor ID"})
+	if _, ok := middleware.RequirePrincipal(c); !ok {
 		return
 	}
 
-	err = h.bidService.DeleteBidByContractorID(c.Request.Context(), contractorUUID, bidID)
+	err = h.bidService.DeleteBid(c.Request.Context(), bidID)
 	if err != nil {
-		if err.Error() == "unauthorized: contractor does not own the bid" || err.Error() == "bid not found" {
+		if errors.Is(err, service.ErrBidNotFound) || errors.Is(err, service.ErrForbidden) {
 			c.JSON(http.StatusNotFound, ErrorResponse{Message: "Bid not found or access denied"})
 			return
 		}
-		if errors.Is(err, service.ErrInvalidContractor) {
-			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Bid not found or access denied"})
+		if errors.Is(err, service.ErrBidLocked) {
+			c.JSON(http.StatusConflict, ErrorResponse{Message: "Bids can only be withdrawn while the tender is open"})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: err.Error()})
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Dostonlv/hackathon-nt/internal/api/middleware"
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/service"
+	"github.com/Dostonlv/hackathon-nt/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/k0kubun/pp"
+)
+
+// ClarificationHandler handles the questions contractors ask about tenders
+type ClarificationHandler struct {
+	clarificationService *service.ClarificationService
+	notificationService  *utils.NotificationService
+}
+
+func NewClarificationHandler(clarificationService *service.ClarificationService, notificationService *utils.NotificationService) *ClarificationHandler {
+	return &ClarificationHandler{
+		clarificationService: clarificationService,
+		notificationService:  notificationService,
+	}
+}
+
+type AskQuestionRequest struct {
+	Question string `json:"question" binding:"required"`
+	// OrganizationID is the contractor organization asking. It may be left
+	// out by members of a single contractor organization.
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+}
+
+type AnswerQuestionRequest struct {
+	Answer string `json:"answer" binding:"required"`
+	// Visibility is public to show the answer to every contractor without
+	// the asker, or private to show it to the asking organization only.
+	Visibility models.AnswerVisibility `json:"visibility" binding:"required"`
+}
+
+// AskQuestion godoc
+// @Summary Ask a question about a tender
+// @Description Ask the client a clarification question about an open tender. Questions close clarifications.close_before ahead of the deadline; closes_at of the question list tells when. The question belongs to the contractor organization given as organization_id, which may be left out by members of a single contractor organization.
+// @Tags clarifications
+// @Accept json
+// @Produce json
+// @Param tender_id path string true "Tender ID"
+// @Param question body AskQuestionRequest true "Question"
+// @Success 201 {object} models.TenderQuestion
+// @Failure 400 {object} ErrorResponse "Bad request body, or the tender is not open or no longer takes questions"
+// @Failure 404 {object} ErrorResponse "Tender or organization not found"
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/contractor/tenders/{tender_id}/questions [post]
+func (h *ClarificationHandler) AskQuestion(c *gin.Context) {
+	tenderID, err := uuid.Parse(c.Param("tender_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+		return
+	}
+
+	var req AskQuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	if _, ok := middleware.RequirePrincipal(c); !ok {
+		return
+	}
+
+	update, err := h.clarificationService.Ask(c.Request.Context(), service.AskQuestionInput{
+		TenderID:       tenderID,
+		OrganizationID: req.OrganizationID,
+		Question:       req.Question,
+	})
+	if err != nil {
+		if abortOrganizationChoice(c, err) {
+			return
+		}
+		abortClarification(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, update.Question)
+	h.notify(c, update, utils.TenderNotification{
+		Type:     "tender_question_asked",
+		TenderID: update.Tender.ID,
+		Message:  fmt.Sprintf("New question about tender %q", update.Tender.Title),
+	})
+}
+
+// ListContractorQuestions godoc
+// @Summary List the questions about a tender
+// @Description List the publicly answered questions about an open tender, without who asked them, and the questions of the caller's organizations with their answers, oldest first.
+// @Tags clarifications
+// @Produce json
+// @Param tender_id path string true "Tender ID"
+// @Success 200 {object} models.TenderClarifications
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/contractor/tenders/{tender_id}/questions [get]
+func (h *ClarificationHandler) ListContractorQuestions(c *gin.Context) {
+	h.listQuestions(c, h.clarificationService.ListForContractor)
+}
+
+// ListClientQuestions godoc
+// @Summary List the questions about a tender
+// @Description List every question asked about a tender of the caller's organization, answered or not, oldest first.
+// @Tags clarifications
+// @Produce json
+// @Param tender_id path string true "Tender ID"
+// @Success 200 {object} models.TenderClarifications
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/client/tenders/{tender_id}/questions [get]
+func (h *ClarificationHandler) ListClientQuestions(c *gin.Context) {
+	h.listQuestions(c, h.clarificationService.ListForClient)
+}
+
+// AnswerQuestion godoc
+// @Summary Answer a question about a tender
+// @Description Answer a question about an open tender. A public answer is shown to every contractor without who asked, and pushed to the asking organization, the bidders and the watchers of the tender. A private answer is only shown and pushed to the asking organization. A question is answered once.
+// @Tags clarifications
+// @Accept json
+// @Produce json
+// @Param tender_id path string true "Tender ID"
+// @Param question_id path string true "Question ID"
+// @Param answer body AnswerQuestionRequest true "Answer"
+// @Success 200 {object} models.TenderQuestion
+// @Failure 400 {object} ErrorResponse "Bad request body, or the tender is not open"
+// @Failure 404 {object} ErrorResponse "Tender or question not found"
+// @Failure 409 {object} ErrorResponse "Question answered already"
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/client/tenders/{tender_id}/questions/{question_id}/answer [post]
+func (h *ClarificationHandler) AnswerQuestion(c *gin.Context) {
+	tenderID, err := uuid.Parse(c.Param("tender_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+		return
+	}
+	questionID, err := uuid.Parse(c.Param("question_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Question not found"})
+		return
+	}
+
+	var req AnswerQuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		return
+	}
+
+	if _, ok := middleware.RequirePrincipal(c); !ok {
+		return
+	}
+
+	update, err := h.clarificationService.Answer(c.Request.Context(), service.AnswerQuestionInput{
+		TenderID:   tenderID,
+		QuestionID: questionID,
+		Answer:     req.Answer,
+		Visibility: req.Visibility,
+	})
+	if err != nil {
+		abortClarification(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, update.Question)
+	message := fmt.Sprintf("A question about tender %q was answered", update.Tender.Title)
+	if *update.Question.Visibility == models.AnswerPrivate {
+		message = fmt.Sprintf("Your question about tender %q was answered", update.Tender.Title)
+	}
+	h.notify(c, update, utils.TenderNotification{
+		Type:     "tender_question_answered",
+		TenderID: update.Tender.ID,
+		Message:  message,
+	})
+}
+
+func (h *ClarificationHandler) listQuestions(c *gin.Context, list func(context.Context, uuid.UUID) (*models.TenderClarifications, error)) {
+	tenderID, err := uuid.Parse(c.Param("tender_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+		return
+	}
+
+	if _, ok := middleware.RequirePrincipal(c); !ok {
+		return
+	}
+
+	clarifications, err := list(c.Request.Context(), tenderID)
+	if err != nil {
+		abortClarification(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, clarifications)
+}
+
+// notify sends notification to the recipients of a question or answer.
+func (h *ClarificationHandler) notify(c *gin.Context, update *service.QuestionUpdate, notification utils.TenderNotification) {
+	for _, userID := range update.Recipients {
+		if err := h.notificationService.NotifyTenderUpdate(c.Request.Context(), userID, notification); err != nil {
+			// Log the error but don't fail the request
+			pp.Printf("Failed to send notification: %v", err)
+		}
+	}
+}
+
+func abortClarification(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTenderNotFound), errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+	case errors.Is(err, service.ErrQuestionNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Question not found"})
+	case errors.Is(err, service.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidTender):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Tender is not open"})
+	case errors.Is(err, service.ErrClarificationsClosed):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "The tender no longer takes questions"})
+	case errors.Is(err, service.ErrQuestionAnswered):
+		c.JSON(http.StatusConflict, ErrorResponse{Message: "The question has been answered already"})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to process the question"})
+	}
+}
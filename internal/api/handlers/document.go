@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/api/middleware"
+	"github.com/Dostonlv/hackathon-nt/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// multipartOverhead is the room left in an upload request for the multipart
+// headers and boundaries around the file.
+const multipartOverhead = 1 << 20
+
+// DocumentHandler handles the files uploaded to tenders
+type DocumentHandler struct {
+	documentService *service.DocumentService
+	maxSize         int64
+	transferTimeout time.Duration
+}
+
+// NewDocumentHandler creates a new DocumentHandler. Upload requests larger
+// than maxSize plus some room for the multipart encoding are cut off.
+// Uploads and downloads may take transferTimeout instead of the read and
+// write timeouts of the server.
+func NewDocumentHandler(documentService *service.DocumentService, maxSize int64, transferTimeout time.Duration) *DocumentHandler {
+	return &DocumentHandler{
+		documentService: documentService,
+		maxSize:         maxSize,
+		transferTimeout: transferTimeout,
+	}
+}
+
+// UploadDocument godoc
+// @Summary Upload a tender document
+// @Description Upload a file to a tender as multipart/form-data in the field "file". The file is streamed to storage; its size, type and SHA-256 digest are recorded. The type is detected from the contents and must be one of documents.allowed_types.
+// @Tags documents
+// @Accept multipart/form-data
+// @Produce json
+// @Param tender_id path string true "Tender ID"
+// @Param file formData file true "Document"
+// @Success 201 {object} models.TenderDocument
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "Tender locked or at its document limit"
+// @Failure 413 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/client/tenders/{tender_id}/documents [post]
+func (h *DocumentHandler) UploadDocument(c *gin.Context) {
+	tenderID, err := uuid.Parse(c.Param("tender_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+		return
+	}
+
+	if _, ok := middleware.RequirePrincipal(c); !ok {
+		return
+	}
+
+	h.extendDeadlines(c)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize+multipartOverhead)
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Expected a multipart/form-data body"})
+		return
+	}
+
+	// Parts are read in order without buffering; anything before the file
+	// is skipped
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "The file field is required"})
+			return
+		}
+		if err != nil {
+			abortUpload(c, fmt.Errorf("%w: %w", service.ErrDocumentRead, err))
+			return
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		doc, err := h.documentService.Upload(c.Request.Context(), service.UploadDocumentInput{
+			TenderID: tenderID,
+			Filename: part.FileName(),
+			Content:  part,
+		})
+		part.Close()
+		if err != nil {
+			abortUpload(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, doc)
+		return
+	}
+}
+
+// ListDocuments godoc
+// @Summary List tender documents
+// @Description List the files of a tender. Contractors see the documents of open tenders.
+// @Tags documents
+// @Produce json
+// @Param tender_id path string true "Tender ID"
+// @Success 200 {array} models.TenderDocument
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/client/tenders/{tender_id}/documents [get]
+// @Router /api/contractor/tenders/{tender_id}/documents [get]
+func (h *DocumentHandler) ListDocuments(c *gin.Context) {
+	tenderID, err := uuid.Parse(c.Param("tender_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+		return
+	}
+
+	if _, ok := middleware.RequirePrincipal(c); !ok {
+		return
+	}
+
+	docs, err := h.documentService.List(c.Request.Context(), tenderID)
+	if err != nil {
+		if errors.Is(err, service.ErrTenderNotFound) || errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to list documents"})
+		return
+	}
+
+	c.JSON(http.StatusOK, docs)
+}
+
+// DownloadDocument godoc
+// @Summary Download a tender document
+// @Description Stream the contents of a tender document as an attachment. The ETag is the SHA-256 digest of the contents.
+// @Tags documents
+// @Produce octet-stream
+// @Param tender_id path string true "Tender ID"
+// @Param document_id path string true "Document ID"
+// @Success 200 {file} file
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/client/tenders/{tender_id}/documents/{document_id} [get]
+// @Router /api/contractor/tenders/{tender_id}/documents/{document_id} [get]
+func (h *DocumentHandler) DownloadDocument(c *gin.Context) {
+	tenderID, err := uuid.Parse(c.Param("tender_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+		return
+	}
+	documentID, err := uuid.Parse(c.Param("document_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Document not found"})
+		return
+	}
+
+	if _, ok := middleware.RequirePrincipal(c); !ok {
+		return
+	}
+
+	doc, content, err := h.documentService.Open(c.Request.Context(), tenderID, documentID)
+	if err != nil {
+		abortDocument(c, err)
+		return
+	}
+	defer content.Close()
+
+	h.extendDeadlines(c)
+	c.DataFromReader(http.StatusOK, doc.Size, doc.ContentType, content, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": doc.Filename}),
+		"ETag":                   `"` + doc.SHA256 + `"`,
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// extendDeadlines gives the request transferTimeout from now to stream a
+// file, instead of what is left of the server timeouts.
+func (h *DocumentHandler) extendDeadlines(c *gin.Context) {
+	deadline := time.Now().Add(h.transferTimeout)
+	rc := http.NewResponseController(c.Writer)
+	// Test recorders do not support deadlines, which leaves the server
+	// timeouts in place
+	if err := rc.SetReadDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Failed to extend the read deadline of a document transfer: %v", err)
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("Failed to extend the write deadline of a document transfer: %v", err)
+	}
+}
+
+// DeleteDocument godoc
+// @Summary Delete a tender document
+// @Description Remove a file from a tender that is not awarded or cancelled.
+// @Tags documents
+// @Produce json
+// @Param id path string true "Tender ID"
+// @Param document_id path string true "Document ID"
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/client/tenders/{id}/documents/{document_id} [delete]
+func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
+	tenderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+		return
+	}
+	documentID, err := uuid.Parse(c.Param("document_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Document not found"})
+		return
+	}
+
+	if _, ok := middleware.RequirePrincipal(c); !ok {
+		return
+	}
+
+	if err := h.documentService.Delete(c.Request.Context(), tenderID, documentID); err != nil {
+		abortDocument(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func abortDocument(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTenderNotFound), errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+	case errors.Is(err, service.ErrDocumentNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Document not found"})
+	case errors.Is(err, service.ErrTenderLocked):
+		c.JSON(http.StatusConflict, ErrorResponse{Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to process document"})
+	}
+}
+
+func abortUpload(c *gin.Context, err error) {
+	var maxBytes *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytes), errors.Is(err, service.ErrDocumentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Message: service.ErrDocumentTooLarge.Error()})
+	case errors.Is(err, service.ErrDocumentType):
+		c.JSON(http.StatusUnsupportedMediaType, ErrorResponse{Message: err.Error()})
+	case errors.Is(err, service.ErrTooManyDocuments):
+		c.JSON(http.StatusConflict, ErrorResponse{Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+	case errors.Is(err, service.ErrDocumentRead):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Malformed upload"})
+	default:
+		abortDocument(c, err)
+	}
+}
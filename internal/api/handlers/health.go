@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Dostonlv/hackathon-nt/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler serves the liveness and readiness probes.
+type HealthHandler struct {
+	healthService *service.HealthService
+}
+
+// NewHealthHandler creates a new HealthHandler
+func NewHealthHandler(healthService *service.HealthService) *HealthHandler {
+	return &HealthHandler{
+		healthService: healthService,
+	}
+}
+
+// Liveness godoc
+// @Summary Liveness probe
+// @Description Reports that the process is running. The dependency breakdown is informational and never fails the probe.
+// @Tags health
+// @Produce json
+// @Success 200 {object} service.HealthReport
+// @Router /healthz [get]
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, h.healthService.Check(c.Request.Context()))
+}
+
+// Readiness godoc
+// @Summary Readiness probe
+// @Description Reports whether Postgres, Redis, the casbin policy and the schema version are healthy. Fails while the server is shutting down.
+// @Tags health
+// @Produce json
+// @Success 200 {object} service.HealthReport
+// @Failure 503 {object} service.HealthReport
+// @Router /readyz [get]
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	report := h.healthService.Check(c.Request.Context())
+	if !report.Ready() {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
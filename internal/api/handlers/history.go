@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/Dostonlv/hackathon-nt/internal/service"
@@ -11,25 +12,31 @@ import (
 // HistoryHandler represents the handler for history-related API endpoints.
 type HistoryHandler struct {
 	historyService *service.HistoryService
+	pagination     Pagination
 }
 
 // NewHistoryHandler creates a new instance of HistoryHandler.
-func NewHistoryHandler(historyService *service.HistoryService) *HistoryHandler {
+func NewHistoryHandler(historyService *service.HistoryService, pagination Pagination) *HistoryHandler {
 	return &HistoryHandler{
 		historyService: historyService,
+		pagination:     pagination,
 	}
 }
 
 // GetTenderHistory handles the GET /users/:id/tenders endpoint.
 // @Summary Get tender history
-// @Description Get the tender history for a user
+// @Description Get the tender history for a user. Only the user and admins may read it.
 // @Tags History
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID"
-// @Success 200 {array} models.Tender "OK"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param total query bool false "Also count all tenders"
+// @Success 200 {object} models.Page[models.Tender] "Page of tenders, newest first"
 // @Security BearerAuth
 // @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 403 {object} ErrorResponse "Forbidden"
 // @Failure 500 {object} ErrorResponse "Internal Server Error"
 // @Router /api/users/{id}/tenders [get]
 func (h *HistoryHandler) GetTenderHistory(c *gin.Context) {
@@ -38,9 +45,17 @@ func (h *HistoryHandler) GetTenderHistory(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
+	page, ok := h.pagination.pageRequest(c)
+	if !ok {
+		return
+	}
 
-	tenders, err := h.historyService.GetTenderHistory(userID)
+	tenders, err := h.historyService.GetTenderHistory(c.Request.Context(), userID, page)
 	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tender history"})
 		return
 	}
@@ -50,14 +65,18 @@ func (h *HistoryHandler) GetTenderHistory(c *gin.Context) {
 
 // GetBidHistory handles the GET /users/:id/bids endpoint.
 // @Summary Get bid history
-// @Description Get the bid history for a user
+// @Description Get the bid history for a user. Only the user and admins may read it.
 // @Tags History
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID"
-// @Success 200 {array} models.Bid "OK"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param total query bool false "Also count all bids"
+// @Success 200 {object} models.Page[models.Bid] "Page of bids, newest first"
 // @Security BearerAuth
 // @Failure 400 {object} ErrorResponse "Bad Request"
+// @Failure 403 {object} ErrorResponse "Forbidden"
 // @Failure 500 {object} ErrorResponse "Internal Server Error"
 // @Router /api/users/{id}/bids [get]
 func (h *HistoryHandler) GetBidHistory(c *gin.Context) {
@@ -66,9 +85,17 @@ func (h *HistoryHandler) GetBidHistory(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
+	page, ok := h.pagination.pageRequest(c)
+	if !ok {
+		return
+	}
 
-	bids, err := h.historyService.GetBidHistory(userID)
+	bids, err := h.historyService.GetBidHistory(c.Request.Context(), userID, page)
 	if err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bid history"})
 		return
 	}
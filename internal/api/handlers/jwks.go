@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// KeysHandler publishes the public keys that verify access tokens.
+type KeysHandler struct {
+	jwtUtil *utils.JWTUtil
+	maxAge  time.Duration
+}
+
+// NewKeysHandler creates a new KeysHandler. maxAge is how long clients may
+// cache the key set.
+func NewKeysHandler(jwtUtil *utils.JWTUtil, maxAge time.Duration) *KeysHandler {
+	return &KeysHandler{
+		jwtUtil: jwtUtil,
+		maxAge:  maxAge,
+	}
+}
+
+// JWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying RS256 and EdDSA access tokens, identified by kid. Empty when tokens are signed with HS256.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} utils.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *KeysHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.maxAge.Seconds())))
+	c.JSON(http.StatusOK, h.jwtUtil.JWKS())
+}
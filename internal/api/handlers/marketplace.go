@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Dostonlv/hackathon-nt/internal/api/middleware"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/Dostonlv/hackathon-nt/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ListMarketplace godoc
+// @Summary Browse the tender marketplace
+// @Description List the open tenders of every client. Takes the filters of /api/client/tenders/filter. has_bid tells whether an organization of the caller has bid on a tender.
+// @Tags marketplace
+// @Produce json
+// @Param status query string false "open, the default and only status listed"
+// @Param search query string false "Full-text search of titles and descriptions"
+// @Param category query string false "Filter tenders by category code, including subcategories"
+// @Param tag query string false "Filter tenders by tag"
+// @Param min_budget query number false "Lowest budget"
+// @Param max_budget query number false "Highest budget"
+// @Param deadline_after query string false "Earliest deadline, RFC 3339"
+// @Param deadline_before query string false "Latest deadline, RFC 3339"
+// @Param sort query string false "deadline for the soonest deadline first, -deadline for the latest; newest or best match first by default"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param total query bool false "Also count all matching tenders"
+// @Success 200 {object} service.MarketplaceSearch "Page of tenders and facet counts"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/contractor/tenders [get]
+func (h *TenderHandler) ListMarketplace(c *gin.Context) {
+	if _, ok := middleware.RequirePrincipal(c); !ok {
+		return
+	}
+	filters, ok := tenderFilters(c)
+	if !ok {
+		return
+	}
+	page, ok := h.pagination.pageRequest(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.tenderService.ListMarketplace(c.Request.Context(), filters, page)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidInput):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		case errors.Is(err, repository.ErrInvalidCursor):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid cursor"})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to list tenders"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetMarketplaceTender godoc
+// @Summary Get a marketplace tender
+// @Description Get an open tender with its documents and whether an organization of the caller has bid on it.
+// @Tags marketplace
+// @Produce json
+// @Param tender_id path string true "Tender ID"
+// @Success 200 {object} models.MarketplaceTender
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/contractor/tenders/{tender_id} [get]
+func (h *TenderHandler) GetMarketplaceTender(c *gin.Context) {
+	tenderID, err := uuid.Parse(c.Param("tender_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+		return
+	}
+
+	if _, ok := middleware.RequirePrincipal(c); !ok {
+		return
+	}
+
+	tender, err := h.tenderService.GetMarketplaceTender(c.Request.Context(), tenderID)
+	if err != nil {
+		if errors.Is(err, service.ErrTenderNotFound) || errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to retrieve tender"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tender)
+}
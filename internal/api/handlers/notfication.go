@@ -4,23 +4,36 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/Dostonlv/hackathon-nt/internal/api/middleware"
+	"github.com/Dostonlv/hackathon-nt/internal/service"
 	"github.com/Dostonlv/hackathon-nt/internal/utils"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
 type WebSocketHandler struct {
 	notificationService *utils.NotificationService
+	authorizer          *service.Authorizer
 	upgrader            websocket.Upgrader
 }
 
-func NewWebSocketHandler(notificationService *utils.NotificationService) *WebSocketHandler {
+func NewWebSocketHandler(notificationService *utils.NotificationService, authorizer *service.Authorizer, allowedOrigins []string) *WebSocketHandler {
 	return &WebSocketHandler{
 		notificationService: notificationService,
+		authorizer:          authorizer,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
-				return true // Add appropriate origin checking for production
+				// An empty list keeps the previous behaviour of accepting any origin
+				if len(allowedOrigins) == 0 {
+					return true
+				}
+				origin := r.Header.Get("Origin")
+				for _, allowed := range allowedOrigins {
+					if origin == allowed {
+						return true
+					}
+				}
+				return false
 			},
 		},
 	}
@@ -28,15 +41,15 @@ func NewWebSocketHandler(notificationService *utils.NotificationService) *WebSoc
 
 // HandleWebSocket upgrades the HTTP connection to WebSocket
 func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
-	userID, exists := c.Get("userId")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Authentication required"})
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
 		return
 	}
+	clientID := principal.UserID
 
-	clientID, err := uuid.Parse(userID.(string))
+	err := h.authorizer.Authorize(c.Request.Context(), service.ActionView, service.UserResource(service.ResourceNotifications, clientID))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid user ID"})
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
 
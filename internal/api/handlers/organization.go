@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// OrganizationHandler handles organizations, their members and invitations
+type OrganizationHandler struct {
+	orgService *service.OrganizationService
+}
+
+// NewOrganizationHandler creates a new OrganizationHandler
+func NewOrganizationHandler(orgService *service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		orgService: orgService,
+	}
+}
+
+type UpdateMemberRoleRequest struct {
+	Role models.OrgRole `json:"role" binding:"required"`
+}
+
+// CreateOrganization godoc
+// @Summary Create an organization
+// @Description Create a client or contractor organization with the current user as its owner. The kind defaults to the user's role.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param input body service.CreateOrganizationInput true "Name and optional kind"
+// @Success 201 {object} models.Organization
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/organizations [post]
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var input service.CreateOrganizationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request payload"})
+		return
+	}
+
+	org, err := h.orgService.Create(c.Request.Context(), input)
+	if err != nil {
+		abortOrganization(c, err, "Failed to create organization")
+		return
+	}
+
+	c.JSON(http.StatusCreated, org)
+}
+
+// ListOrganizations godoc
+// @Summary List organizations
+// @Description List the organizations the current user is a member of, with the user's role in each.
+// @Tags organizations
+// @Produce json
+// @Success 200 {array} models.Organization
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/organizations [get]
+func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
+	orgs, err := h.orgService.List(c.Request.Context())
+	if err != nil {
+		abortOrganization(c, err, "Failed to list organizations")
+		return
+	}
+
+	c.JSON(http.StatusOK, orgs)
+}
+
+// GetOrganization godoc
+// @Summary Get an organization
+// @Description Get an organization the current user is a member of.
+// @Tags organizations
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {object} models.Organization
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/organizations/{id} [get]
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	orgID, ok := organizationIDParam(c)
+	if !ok {
+		return
+	}
+
+	org, err := h.orgService.Get(c.Request.Context(), orgID)
+	if err != nil {
+		abortOrganization(c, err, "Failed to get organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, org)
+}
+
+// UpdateOrganization godoc
+// @Summary Rename an organization
+// @Description Rename an organization. Only owners may.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param input body service.UpdateOrganizationInput true "New name"
+// @Success 200 {object} models.Organization
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/organizations/{id} [put]
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	orgID, ok := organizationIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input service.UpdateOrganizationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request payload"})
+		return
+	}
+
+	org, err := h.orgService.Update(c.Request.Context(), orgID, input)
+	if err != nil {
+		abortOrganization(c, err, "Failed to update organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, org)
+}
+
+// ListMembers godoc
+// @Summary List organization members
+// @Description List the members of an organization the current user belongs to.
+// @Tags organizations
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {array} models.Membership
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/organizations/{id}/members [get]
+func (h *OrganizationHandler) ListMembers(c *gin.Context) {
+	orgID, ok := organizationIDParam(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.orgService.ListMembers(c.Request.Context(), orgID)
+	if err != nil {
+		abortOrganization(c, err, "Failed to list members")
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// UpdateMemberRole godoc
+// @Summary Change a member's role
+// @Description Change the role of a member to owner, manager, viewer or, in contractor organizations, bidder. Only owners may, and the last owner cannot step down.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param user_id path string true "User ID"
+// @Param input body UpdateMemberRoleRequest true "New role"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/organizations/{id}/members/{user_id} [put]
+func (h *OrganizationHandler) UpdateMemberRole(c *gin.Context) {
+	orgID, ok := organizationIDParam(c)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid user ID"})
+		return
+	}
+
+	var req UpdateMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request payload"})
+		return
+	}
+
+	if err := h.orgService.UpdateMemberRole(c.Request.Context(), orgID, userID, req.Role); err != nil {
+		abortOrganization(c, err, "Failed to update member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member role updated"})
+}
+
+// RemoveMember godoc
+// @Summary Remove a member
+// @Description Remove a member from an organization. Owners may remove anyone; every member may remove themselves to leave. The last owner cannot leave.
+// @Tags organizations
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param user_id path string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/organizations/{id}/members/{user_id} [delete]
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	orgID, ok := organizationIDParam(c)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid user ID"})
+		return
+	}
+
+	if err := h.orgService.RemoveMember(c.Request.Context(), orgID, userID); err != nil {
+		abortOrganization(c, err, "Failed to remove member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+// InviteMember godoc
+// @Summary Invite a member
+// @Description Email an invitation to join the organization with a role. Only owners may invite.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param input body service.InviteMemberInput true "Email and role"
+// @Success 201 {object} models.OrganizationInvitation
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/organizations/{id}/invitations [post]
+func (h *OrganizationHandler) InviteMember(c *gin.Context) {
+	orgID, ok := organizationIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input service.InviteMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request payload"})
+		return
+	}
+
+	invitation, err := h.orgService.Invite(c.Request.Context(), orgID, input)
+	if err != nil {
+		abortOrganization(c, err, "Failed to send invitation")
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// ListInvitations godoc
+// @Summary List invitations
+// @Description List the open invitations of an organization. Only owners may.
+// @Tags organizations
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {array} models.OrganizationInvitation
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/organizations/{id}/invitations [get]
+func (h *OrganizationHandler) ListInvitations(c *gin.Context) {
+	orgID, ok := organizationIDParam(c)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.orgService.ListInvitations(c.Request.Context(), orgID)
+	if err != nil {
+		abortOrganization(c, err, "Failed to list invitations")
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// RevokeInvitation godoc
+// @Summary Revoke an invitation
+// @Description Revoke an invitation so its link stops working. Only owners may.
+// @Tags organizations
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param invitation_id path string true "Invitation ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/organizations/{id}/invitations/{invitation_id} [delete]
+func (h *OrganizationHandler) RevokeInvitation(c *gin.Context) {
+	orgID, ok := organizationIDParam(c)
+	if !ok {
+		return
+	}
+	invitationID, err := uuid.Parse(c.Param("invitation_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid invitation ID"})
+		return
+	}
+
+	if err := h.orgService.RevokeInvitation(c.Request.Context(), orgID, invitationID); err != nil {
+		abortOrganization(c, err, "Failed to revoke invitation")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+// AcceptInvitation godoc
+// @Summary Accept an invitation
+// @Description Join an organization with the token from an invitation email. The invitation must have been sent to the current user's email address.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Param input body service.AcceptInvitationInput true "Invitation token"
+// @Success 200 {object} models.Organization
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/organizations/invitations/accept [post]
+func (h *OrganizationHandler) AcceptInvitation(c *gin.Context) {
+	var input service.AcceptInvitationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request payload"})
+		return
+	}
+
+	org, err := h.orgService.AcceptInvitation(c.Request.Context(), input)
+	if err != nil {
+		abortOrganization(c, err, "Failed to accept invitation")
+		return
+	}
+
+	c.JSON(http.StatusOK, org)
+}
+
+func organizationIDParam(c *gin.Context) (uuid.UUID, bool) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid organization ID"})
+		return uuid.Nil, false
+	}
+	return orgID, true
+}
+
+// abortOrganization writes the response for an organization service error.
+// Organizations the caller does not belong to are reported as not found.
+func abortOrganization(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Message: "Unauthorized"})
+	case errors.Is(err, service.ErrOrganizationNotFound), errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Organization not found or access denied"})
+	case errors.Is(err, service.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Member not found"})
+	case errors.Is(err, service.ErrInvitationNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Invitation not found"})
+	case errors.Is(err, service.ErrInvalidOrganizationName),
+		errors.Is(err, service.ErrInvalidOrganizationKind),
+		errors.Is(err, service.ErrInvalidOrgRole),
+		errors.Is(err, service.ErrInvalidInvitation),
+		errors.Is(err, service.ErrOrganizationKindMismatch):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+	case errors.Is(err, service.ErrInvitationEmailMismatch):
+		c.JSON(http.StatusForbidden, ErrorResponse{Message: err.Error()})
+	case errors.Is(err, service.ErrAlreadyMember), errors.Is(err, service.ErrLastOwner):
+		c.JSON(http.StatusConflict, ErrorResponse{Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: message})
+	}
+}
+
+// abortOrganizationChoice writes the response when the organization a new
+// tender or bid should belong to cannot be determined, and reports whether
+// it did.
+func abortOrganizationChoice(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrOrganizationRequired),
+		errors.Is(err, service.ErrOrganizationKindMismatch):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+	case errors.Is(err, service.ErrOrganizationNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Organization not found"})
+	default:
+		return false
+	}
+	return true
+}
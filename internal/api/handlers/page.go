@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// Pagination holds the page sizes of list endpoints.
+type Pagination struct {
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// pageRequest reads the limit, cursor and total query parameters of a list
+// request. A limit above the maximum is lowered to it. It responds with 400
+// and returns false when a parameter is invalid.
+func (p Pagination) pageRequest(c *gin.Context) (repository.PageRequest, bool) {
+	page := repository.PageRequest{Limit: p.DefaultLimit}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid limit"})
+			return page, false
+		}
+		page.Limit = min(limit, p.MaxLimit)
+	}
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := repository.ParseCursor(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid cursor"})
+			return page, false
+		}
+		page.After = cursor
+	}
+	page.WithTotal = c.Query("total") == "true"
+	return page, true
+}
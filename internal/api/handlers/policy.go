@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Dostonlv/hackathon-nt/internal/api/middleware"
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// PolicyHandler serves the authorization policy administration API.
+type PolicyHandler struct {
+	policyService *service.PolicyService
+}
+
+// NewPolicyHandler creates a new PolicyHandler
+func NewPolicyHandler(policyService *service.PolicyService) *PolicyHandler {
+	return &PolicyHandler{
+		policyService: policyService,
+	}
+}
+
+// ListPolicies godoc
+// @Summary List authorization policies
+// @Description List every casbin policy and role grouping.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} service.PolicySet
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/policies [get]
+func (h *PolicyHandler) ListPolicies(c *gin.Context) {
+	set, err := h.policyService.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to list policies"})
+		return
+	}
+	c.JSON(http.StatusOK, set)
+}
+
+// AddPolicy godoc
+// @Summary Add an authorization policy
+// @Description Allow a role, or an API key scope written as scope:<name>, to call an action on a path pattern. The change applies on every instance.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param input body models.Policy true "Policy"
+// @Success 201 {object} models.Policy
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/policies [post]
+func (h *PolicyHandler) AddPolicy(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	var policy models.Policy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request payload"})
+		return
+	}
+
+	if err := h.policyService.AddPolicy(c.Request.Context(), principal.UserID, policy); err != nil {
+		abortPolicy(c, err, "Failed to add policy")
+		return
+	}
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+// RemovePolicy godoc
+// @Summary Remove an authorization policy
+// @Tags admin
+// @Produce json
+// @Param subject query string true "Role or scope:<name>"
+// @Param object query string true "Path pattern"
+// @Param action query string true "HTTP method, WS or *"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/policies [delete]
+func (h *PolicyHandler) RemovePolicy(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	policy := models.Policy{
+		Subject: c.Query("subject"),
+		Object:  c.Query("object"),
+		Action:  c.Query("action"),
+	}
+	if err := h.policyService.RemovePolicy(c.Request.Context(), principal.UserID, policy); err != nil {
+		abortPolicy(c, err, "Failed to remove policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Policy removed"})
+}
+
+// AddRoleGrouping godoc
+// @Summary Add a role grouping
+// @Description Make member inherit every permission of role.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param input body models.RoleGrouping true "Role grouping"
+// @Success 201 {object} models.RoleGrouping
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/role-groupings [post]
+func (h *PolicyHandler) AddRoleGrouping(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	var grouping models.RoleGrouping
+	if err := c.ShouldBindJSON(&grouping); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request payload"})
+		return
+	}
+
+	if err := h.policyService.AddGrouping(c.Request.Context(), principal.UserID, grouping); err != nil {
+		abortPolicy(c, err, "Failed to add role grouping")
+		return
+	}
+
+	c.JSON(http.StatusCreated, grouping)
+}
+
+// RemoveRoleGrouping godoc
+// @Summary Remove a role grouping
+// @Tags admin
+// @Produce json
+// @Param member query string true "Member role"
+// @Param role query string true "Inherited role"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/role-groupings [delete]
+func (h *PolicyHandler) RemoveRoleGrouping(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	grouping := models.RoleGrouping{
+		Member: c.Query("member"),
+		Role:   c.Query("role"),
+	}
+	if err := h.policyService.RemoveGrouping(c.Request.Context(), principal.UserID, grouping); err != nil {
+		abortPolicy(c, err, "Failed to remove role grouping")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role grouping removed"})
+}
+
+// ListPolicyAudit godoc
+// @Summary Policy change history
+// @Description List policy and role grouping changes, newest first.
+// @Tags admin
+// @Produce json
+// @Param limit query int false "Maximum number of entries (max 500)"
+// @Success 200 {array} models.PolicyAudit
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/policies/audit [get]
+func (h *PolicyHandler) ListPolicyAudit(c *gin.Context) {
+	limit := 0
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.policyService.Audit(c.Request.Context(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to list policy changes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// ExplainPolicy godoc
+// @Summary Explain an authorization decision
+// @Description Evaluate a request for a role, or an API key scope written as scope:<name>, and return the decision, the policy that allowed it, the roles the subject inherits and the policies of those roles for the method.
+// @Tags admin
+// @Produce json
+// @Param subject query string true "Role or scope:<name>"
+// @Param path query string true "Request path, such as /api/users/123/tenders"
+// @Param method query string true "HTTP method or WS"
+// @Success 200 {object} service.PolicyDecision
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/admin/policies/explain [get]
+func (h *PolicyHandler) ExplainPolicy(c *gin.Context) {
+	request := models.Policy{
+		Subject: c.Query("subject"),
+		Object:  c.Query("path"),
+		Action:  c.Query("method"),
+	}
+	decision, err := h.policyService.Explain(c.Request.Context(), request)
+	if err != nil {
+		abortPolicy(c, err, "Failed to explain decision")
+		return
+	}
+
+	c.JSON(http.StatusOK, decision)
+}
+
+func abortPolicy(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidPolicy):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+	case errors.Is(err, service.ErrPolicyExists):
+		c.JSON(http.StatusConflict, ErrorResponse{Message: "Already exists"})
+	case errors.Is(err, service.ErrPolicyNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Not found"})
+	case errors.Is(err, service.ErrProtectedPolicy):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "The admin policy cannot be removed"})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: message})
+	}
+}
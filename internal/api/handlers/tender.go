@@ -1,26 +1,37 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/Dostonlv/hackathon-nt/internal/api/middleware"
+	"github.com/Dostonlv/hackathon-nt/internal/models"
 	"github.com/Dostonlv/hackathon-nt/internal/repository"
 	"github.com/Dostonlv/hackathon-nt/internal/service"
 	"github.com/Dostonlv/hackathon-nt/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/k0kubun/pp"
 )
 
 type TenderHandler struct {
-	tenderService *service.TenderService
+	tenderService       *service.TenderService
+	notificationService *utils.NotificationService
+	pagination          Pagination
 }
 
-func NewTenderHandler(tenderService *service.TenderService) *TenderHandler {
+func NewTenderHandler(tenderService *service.TenderService, notificationService *utils.NotificationService, pagination Pagination) *TenderHandler {
 	if tenderService == nil {
 		panic("tenderService cannot be nil")
 	}
 	return &TenderHandler{
-		tenderService: tenderService,
+		tenderService:       tenderService,
+		notificationService: notificationService,
+		pagination:          pagination,
 	}
 }
 
@@ -30,17 +41,28 @@ type CreateTenderRequest struct {
 	Deadline    string  `json:"deadline" datetime:"2006-01-02T15:04:05Z07:00"`
 	Budget      float64 `json:"budget" `
 	Attachment  *string `json:"attachment"`
+	// OrganizationID is the client organization the tender belongs to. It
+	// may be left out by members of a single client organization.
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	// CategoryCode classifies the tender, see GET /api/categories.
+	CategoryCode *string `json:"category_code,omitempty"`
+	// Tags are free-form labels, stored lowercase.
+	Tags []string `json:"tags,omitempty"`
+	// Draft creates the tender unpublished, hidden from contractors.
+	Draft bool `json:"draft,omitempty"`
 }
 
 // CreateTender godoc
 // @Summary Create a new tender
-// @Description Create a new tender with the provided details
+// @Description Create a new tender with the provided details. The tender belongs to the client organization given as organization_id, which may be left out by members of a single client organization.
 // @Tags tenders
 // @Accept json
 // @Produce json
 // @Param tender body CreateTenderRequest true "Tender details"
 // @Success 201 {object} models.Tender
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /api/client/tenders [post]
@@ -52,12 +74,6 @@ func (h *TenderHandler) CreateTender(c *gin.Context) {
 		return
 	}
 
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Missing token"})
-		return
-	}
-
 	if req.Title == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid input"})
 		return
@@ -78,14 +94,8 @@ func (h *TenderHandler) CreateTender(c *gin.Context) {
 		return
 	}
 
-	claims, err := utils.ParseToken(authHeader, []byte("secreeet"))
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-		return
-	}
-
-	if claims.UserID == uuid.Nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
 		return
 	}
 
@@ -101,15 +111,30 @@ func (h *TenderHandler) CreateTender(c *gin.Context) {
 	}
 
 	tender, err := h.tenderService.CreateTender(c.Request.Context(), service.CreateTenderInput{
-		ClientID:    claims.UserID,
-		Title:       req.Title,
-		Description: req.Description,
-		Deadline:    deadline,
-		Budget:      req.Budget,
-		Attachment:  req.Attachment,
+		ClientID:       principal.UserID,
+		OrganizationID: req.OrganizationID,
+		Title:          req.Title,
+		Description:    req.Description,
+		Deadline:       deadline,
+		Budget:         req.Budget,
+		Attachment:     req.Attachment,
+		CategoryCode:   req.CategoryCode,
+		Tags:           req.Tags,
+		Draft:          req.Draft,
 	})
 
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		if abortOrganizationChoice(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -119,25 +144,28 @@ func (h *TenderHandler) CreateTender(c *gin.Context) {
 
 // ListTenders handles the request to list tenders based on provided filters.
 // @Summary List Tenders
-// @Description Retrieves a list of tenders filtered by status and search query.
+// @Description Retrieves a page of the tenders of every client organization of the caller, newest first.
 // @Tags tenders
 // @Accept json
 // @Produce json
-// @Param status query string false "Filter tenders by status"
-// @Param search query string false "Search tenders by keyword"
-// @Success 200 {array} []models.Tender "List of tenders"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param total query bool false "Also count all tenders"
+// @Success 200 {object} models.Page[models.Tender] "Page of tenders"
+// @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} []models.Tender "Internal Server Error"
 // @Security BearerAuth
 // @Router /api/client/tenders [get]
 func (h *TenderHandler) ListTenders(c *gin.Context) {
-	clientID, _ := c.Get("userId")
-
-	clientUUID, err := uuid.Parse(clientID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid client ID format"})
+	if _, ok := middleware.RequirePrincipal(c); !ok {
+		return
+	}
+	page, ok := h.pagination.pageRequest(c)
+	if !ok {
 		return
 	}
-	tenders, err := h.tenderService.ListTenders(c.Request.Context(), clientUUID)
+
+	tenders, err := h.tenderService.ListTenders(c.Request.Context(), page)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -148,11 +176,13 @@ func (h *TenderHandler) ListTenders(c *gin.Context) {
 
 type UpdateTenderStatusRequest struct {
 	Status string `json:"status"`
+	// Reason is required to cancel.
+	Reason string `json:"reason,omitempty"`
 }
 
 // UpdateTenderStatus godoc
 // @Summary Update the status of a tender
-// @Description Update the status of a tender by its ID
+// @Description Move a tender to a status through the lifecycle transition that leads there: draft to open publishes, open to closed closes, closed to open reopens, closed to evaluating starts the evaluation and any status short of awarded to cancelled cancels, which needs a reason. Tenders are awarded by awarding a bid. Every bidder is notified.
 // @Tags tenders
 // @Accept json
 // @Produce json
@@ -161,6 +191,7 @@ type UpdateTenderStatusRequest struct {
 // @Success 200 {object} models.Tender
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /api/client/tenders/{id} [put]
@@ -171,7 +202,7 @@ func (h *TenderHandler) UpdateTenderStatus(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if req.Status == "" || req.Status != "open" && req.Status != "closed" {
+	if !models.TenderStatus(req.Status).IsValid() {
 		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid tender status"})
 		return
 	}
@@ -182,40 +213,219 @@ func (h *TenderHandler) UpdateTenderStatus(c *gin.Context) {
 		return
 	}
 
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Missing token"})
+	if _, ok := middleware.RequirePrincipal(c); !ok {
 		return
 	}
 
-	claims, err := utils.ParseToken(authHeader, []byte("secreeet"))
+	tenderUUID, err := uuid.Parse(tenderID)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.JSON(http.StatusNotFound, gin.H{"message": "Tender not found"})
 		return
 	}
 
-	if claims.UserID == uuid.Nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	update, err := h.tenderService.UpdateTenderStatus(c.Request.Context(), tenderUUID, req.Status, req.Reason)
+	if err != nil {
+		abortTransition(c, err)
 		return
 	}
 
-	tenderUUID, err := uuid.Parse(tenderID)
+	c.JSON(http.StatusOK, gin.H{"message": "Tender status updated"})
+	h.notifyStatusChange(c, update)
+}
+
+// TransitionTender godoc
+// @Summary Move a tender through its lifecycle
+// @Description Apply a lifecycle transition: publish (draft to open), close (open to closed), reopen (closed to open, before the deadline), evaluate (closed to evaluating, once there are bids) or cancel (anything short of awarded, with a reason). Tenders are awarded by awarding a bid. Every bidder is notified.
+// @Tags tenders
+// @Accept json
+// @Produce json
+// @Param tender_id path string true "Tender ID"
+// @Param input body service.TransitionTenderInput true "Transition and reason"
+// @Success 200 {object} models.Tender
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/client/tenders/{tender_id}/transitions [post]
+func (h *TenderHandler) TransitionTender(c *gin.Context) {
+	tenderID, err := uuid.Parse(c.Param("tender_id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "Tender not found"})
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+		return
+	}
+
+	var input service.TransitionTenderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request payload"})
 		return
 	}
 
-	_, err = h.tenderService.UpdateTender(c.Request.Context(), service.UpdateTenderInput{ID: tenderUUID, Status: &req.Status})
+	update, err := h.tenderService.TransitionTender(c.Request.Context(), tenderID, input)
 	if err != nil {
-		if err == service.ErrTenderNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Tender not found"})
+		abortTransition(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, update.Tender)
+	h.notifyStatusChange(c, update)
+}
+
+// GetTenderLifecycle godoc
+// @Summary Get the lifecycle of a tender
+// @Description Get the status of a tender, the transitions allowed from it and its status history.
+// @Tags tenders
+// @Produce json
+// @Param tender_id path string true "Tender ID"
+// @Success 200 {object} service.TenderLifecycle
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/client/tenders/{tender_id}/transitions [get]
+func (h *TenderHandler) GetTenderLifecycle(c *gin.Context) {
+	tenderID, err := uuid.Parse(c.Param("tender_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+		return
+	}
+
+	lifecycle, err := h.tenderService.GetTenderLifecycle(c.Request.Context(), tenderID)
+	if err != nil {
+		if errors.Is(err, service.ErrTenderNotFound) || errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to get tender lifecycle"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Tender status updated"})
+	c.JSON(http.StatusOK, lifecycle)
+}
+
+// notifyStatusChange tells every bidder about the new status of a tender.
+func (h *TenderHandler) notifyStatusChange(c *gin.Context, update *service.TenderUpdate) {
+	notification := utils.TenderNotification{
+		Type:     "tender_" + string(update.Tender.Status),
+		TenderID: update.Tender.ID,
+		Status:   string(update.Tender.Status),
+		Message:  "A tender you bid on is now " + string(update.Tender.Status),
+	}
+	for _, userID := range update.Bidders {
+		if err := h.notificationService.NotifyTenderUpdate(c.Request.Context(), userID, notification); err != nil {
+			// Log the error but don't fail the transition
+			pp.Printf("Failed to send notification: %v", err)
+		}
+	}
+}
+
+func abortTransition(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrTenderNotFound), errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+	case errors.Is(err, service.ErrInvalidInput), errors.Is(err, service.ErrCancellationReason):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+	case errors.Is(err, service.ErrInvalidTransition),
+		errors.Is(err, service.ErrDeadlinePassed),
+		errors.Is(err, service.ErrNoBids),
+		errors.Is(err, service.ErrAwardRequiresBid):
+		c.JSON(http.StatusConflict, ErrorResponse{Message: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to update tender status"})
+	}
+}
+
+type UpdateTenderRequest struct {
+	Title       *string  `json:"title,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Deadline    *string  `json:"deadline,omitempty" datetime:"2006-01-02T15:04:05Z07:00"`
+	Budget      *float64 `json:"budget,omitempty"`
+	// Attachment replaces the attachment; an empty string removes it.
+	Attachment *string `json:"attachment,omitempty"`
+	// CategoryCode replaces the category; an empty string removes it.
+	CategoryCode *string `json:"category_code,omitempty"`
+	// Tags replace the tags; an empty list removes them.
+	Tags *[]string `json:"tags,omitempty"`
+	// NotifyBidders must be set to change the budget of a tender with bids.
+	// Every bidder is then notified of the new budget.
+	NotifyBidders bool `json:"notify_bidders,omitempty"`
+}
+
+// UpdateTender godoc
+// @Summary Edit a tender
+// @Description Change the title, description, deadline, budget, attachment, category or tags of a tender. Fields left out are kept. Awarded and cancelled tenders cannot be edited, a new deadline must be in the future, and the budget of a tender with bids only changes with notify_bidders set, which notifies every bidder.
+// @Tags tenders
+// @Accept json
+// @Produce json
+// @Param id path string true "Tender ID"
+// @Param tender body UpdateTenderRequest true "Fields to change"
+// @Success 200 {object} models.Tender
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/client/tenders/{id} [patch]
+func (h *TenderHandler) UpdateTender(c *gin.Context) {
+	tenderID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+		return
+	}
+
+	var req UpdateTenderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request payload"})
+		return
+	}
+
+	input := service.UpdateTenderInput{
+		ID:            tenderID,
+		Title:         req.Title,
+		Description:   req.Description,
+		Budget:        req.Budget,
+		Attachment:    req.Attachment,
+		CategoryCode:  req.CategoryCode,
+		Tags:          req.Tags,
+		NotifyBidders: req.NotifyBidders,
+	}
+	if req.Deadline != nil {
+		deadline, err := time.Parse(time.RFC3339, *req.Deadline)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Deadline must be an RFC 3339 timestamp"})
+			return
+		}
+		input.Deadline = &deadline
+	}
+
+	update, err := h.tenderService.UpdateTender(c.Request.Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTenderNotFound), errors.Is(err, service.ErrForbidden):
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
+		case errors.Is(err, service.ErrInvalidInput):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+		case errors.Is(err, service.ErrTenderLocked), errors.Is(err, service.ErrBudgetLocked):
+			c.JSON(http.StatusConflict, ErrorResponse{Message: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to update tender"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, update.Tender)
+
+	notification := utils.TenderNotification{
+		Type:     "tender_budget_changed",
+		TenderID: update.Tender.ID,
+		Budget:   update.Tender.Budget,
+		Message:  "The budget of a tender you bid on has changed",
+	}
+	for _, userID := range update.Bidders {
+		if err := h.notificationService.NotifyTenderUpdate(c.Request.Context(), userID, notification); err != nil {
+			// Log the error but don't fail the update
+			pp.Printf("Failed to send notification: %v", err)
+		}
+	}
 }
 
 // GetTenderByID godoc
@@ -238,20 +448,7 @@ func (h *TenderHandler) GetTenderByID(c *gin.Context) {
 		return
 	}
 
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Missing token"})
-		return
-	}
-
-	claims, err := utils.ParseToken(authHeader, []byte("secreeet"))
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-		return
-	}
-
-	if claims.UserID == uuid.Nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	if _, ok := middleware.RequirePrincipal(c); !ok {
 		return
 	}
 
@@ -263,7 +460,7 @@ func (h *TenderHandler) GetTenderByID(c *gin.Context) {
 
 	tender, err := h.tenderService.GetTenderByID(c.Request.Context(), tenderUUID)
 	if err != nil {
-		if err == service.ErrTenderNotFound {
+		if errors.Is(err, service.ErrTenderNotFound) || errors.Is(err, service.ErrForbidden) {
 			c.JSON(http.StatusNotFound, gin.H{"message": "Tender not found"})
 			return
 		}
@@ -276,7 +473,7 @@ func (h *TenderHandler) GetTenderByID(c *gin.Context) {
 
 // DeleteTender godoc
 // @Summary Delete a tender
-// @Description Delete a tender by its ID
+// @Description Delete a tender by its ID. Tenders with bids cannot be deleted, they are cancelled instead.
 // @Tags tenders
 // @Accept json
 // @Produce json
@@ -284,6 +481,7 @@ func (h *TenderHandler) GetTenderByID(c *gin.Context) {
 // @Success 200 {object} string "Tender deleted"
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "The tender has bids"
 // @Failure 500 {object} ErrorResponse
 // @Security BearerAuth
 // @Router /api/client/tenders/{id} [delete]
@@ -294,72 +492,222 @@ func (h *TenderHandler) DeleteTender(c *gin.Context) {
 		return
 	}
 
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"message": "Missing token"})
+	if _, ok := middleware.RequirePrincipal(c); !ok {
 		return
 	}
 
-	claims, err := utils.ParseToken(authHeader, []byte("secreeet"))
+	tenderUUID, err := uuid.Parse(tenderID)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.JSON(http.StatusNotFound, gin.H{"message": "Tender not found or access denied"})
 		return
 	}
 
-	if claims.UserID == uuid.Nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	err = h.tenderService.DeleteTender(c.Request.Context(), tenderUUID)
+	if err != nil {
+		if errors.Is(err, service.ErrTenderNotFound) || errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Tender not found or access denied"})
+			return
+		}
+		if errors.Is(err, service.ErrTenderHasBids) {
+			c.JSON(http.StatusConflict, gin.H{"message": "Tenders with bids cannot be deleted, cancel them instead"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	tenderUUID, err := uuid.Parse(tenderID)
+	c.JSON(http.StatusOK, gin.H{"message": "Tender deleted successfully"})
+}
+
+// WatchTender godoc
+// @Summary Watch a tender
+// @Description Get the deadline reminders and status notifications of an open tender without bidding on it. Watching a tender twice is not an error.
+// @Tags tenders
+// @Produce json
+// @Param tender_id path string true "Tender ID"
+// @Success 204
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/contractor/tenders/{tender_id}/watch [post]
+func (h *TenderHandler) WatchTender(c *gin.Context) {
+	tenderID, err := uuid.Parse(c.Param("tender_id"))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"message": "Tender not found or access denied"})
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
 		return
 	}
 
-	err = h.tenderService.DeleteTender(c.Request.Context(), tenderUUID, claims.UserID)
-	if err != nil {
-		if err == service.ErrTenderNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"message": "Tender not found or access denied"})
+	if _, ok := middleware.RequirePrincipal(c); !ok {
+		return
+	}
+
+	if err := h.tenderService.WatchTender(c.Request.Context(), tenderID); err != nil {
+		if errors.Is(err, service.ErrTenderNotFound) || errors.Is(err, service.ErrForbidden) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "Tender not found or access denied"})
 			return
 		}
-		if err == service.ErrUnauthorized {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Unauthorized"})
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to watch tender"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// UnwatchTender godoc
+// @Summary Stop watching a tender
+// @Description Stop getting the notifications of a watched tender.
+// @Tags tenders
+// @Produce json
+// @Param tender_id path string true "Tender ID"
+// @Success 204
+// @Failure 404 {object} ErrorResponse "Not watching the tender"
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/contractor/tenders/{tender_id}/watch [delete]
+func (h *TenderHandler) UnwatchTender(c *gin.Context) {
+	tenderID, err := uuid.Parse(c.Param("tender_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Message: "Not watching the tender"})
+		return
+	}
+
+	if _, ok := middleware.RequirePrincipal(c); !ok {
+		return
+	}
+
+	if err := h.tenderService.UnwatchTender(c.Request.Context(), tenderID); err != nil {
+		if errors.Is(err, service.ErrNotWatching) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Message: "Not watching the tender"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to unwatch tender"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Tender deleted successfully"})
+	c.Status(http.StatusNoContent)
 }
 
 // ListTendersFiltering handles the request to list tenders with filters.
 // @Summary List Tenders with Filters
-// @Description Retrieves a list of tenders filtered by various criteria.
+// @Description Retrieves a list of tenders filtered by various criteria, with the number of matching tenders per category and per status. A category filter matches its subcategories too. Category counts include the tenders of every subcategory and ignore the category filter; status counts ignore the status filter. Clients only see the tenders of their organizations.
 // @Tags tenders
 // @Accept json
 // @Produce json
 // @Param status query string false "Filter tenders by status"
 // @Param search query string false "Search tenders by keyword"
-// @Success 200 {array} models.Tender "List of tenders"
+// @Param category query string false "Filter tenders by category code, including subcategories"
+// @Param tag query string false "Filter tenders by tag"
+// @Param min_budget query number false "Lowest budget"
+// @Param max_budget query number false "Highest budget"
+// @Param deadline_after query string false "Earliest deadline, RFC 3339"
+// @Param deadline_before query string false "Latest deadline, RFC 3339"
+// @Param sort query string false "deadline for the soonest deadline first, -deadline for the latest; newest or best match first by default"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param total query bool false "Also count all matching tenders"
+// @Success 200 {object} service.TenderSearch "Page of tenders and facet counts"
+// @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse "Internal Server Error"
 // @Router /api/client/tenders/filter [get]
 func (h *TenderHandler) ListTendersFiltering(c *gin.Context) {
-	var filters repository.TenderFilters
-
-	if status := c.Query("status"); status != "" {
-		filters.Status = status
+	filters, ok := tenderFilters(c)
+	if !ok {
+		return
 	}
-	if search := c.Query("search"); search != "" {
-		filters.Search = search
+	page, ok := h.pagination.pageRequest(c)
+	if !ok {
+		return
 	}
 
-	tenders, err := h.tenderService.ListTendersFiltering(c.Request.Context(), filters)
+	result, err := h.tenderService.ListTendersFiltering(c.Request.Context(), filters, page)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: err.Error()})
+			return
+		}
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid cursor"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, tenders)
+	c.JSON(http.StatusOK, result)
+}
+
+// ListCategories godoc
+// @Summary List tender categories
+// @Description Get the CPV-style category taxonomy. Each category comes right before its subcategories; top level categories have no parent_code.
+// @Tags tenders
+// @Produce json
+// @Success 200 {array} models.Category
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/categories [get]
+func (h *TenderHandler) ListCategories(c *gin.Context) {
+	categories, err := h.tenderService.ListCategories(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to list categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+// tenderFilters reads the filters of a tender list from the query string. It
+// responds with 400 and returns false when one is malformed.
+func tenderFilters(c *gin.Context) (repository.TenderFilters, bool) {
+	filters := repository.TenderFilters{
+		Status:   c.Query("status"),
+		Search:   c.Query("search"),
+		Category: c.Query("category"),
+		Tag:      c.Query("tag"),
+		Sort:     repository.TenderSort(c.Query("sort")),
+	}
+
+	var err error
+	if filters.MinBudget, err = queryFloat(c, "min_budget"); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "min_budget must be a number"})
+		return filters, false
+	}
+	if filters.MaxBudget, err = queryFloat(c, "max_budget"); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "max_budget must be a number"})
+		return filters, false
+	}
+	if filters.DeadlineAfter, err = queryTime(c, "deadline_after"); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "deadline_after must be an RFC 3339 timestamp"})
+		return filters, false
+	}
+	if filters.DeadlineBefore, err = queryTime(c, "deadline_before"); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "deadline_before must be an RFC 3339 timestamp"})
+		return filters, false
+	}
+	return filters, true
+}
+
+// queryFloat parses an optional number from the query string.
+func queryFloat(c *gin.Context, name string) (*float64, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, fmt.Errorf("invalid %s", name)
+	}
+	return &v, nil
+}
+
+// queryTime parses an optional RFC 3339 timestamp from the query string.
+func queryTime(c *gin.Context, name string) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
 }
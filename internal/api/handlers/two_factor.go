@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/Dostonlv/hackathon-nt/internal/api/middleware"
+	"github.com/Dostonlv/hackathon-nt/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// LoginTwoFactor godoc
+// @Summary Complete a two-factor login
+// @Description Exchange the challenge token returned by /login and a TOTP or recovery code for tokens.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body service.TwoFactorLoginInput true "Challenge and code"
+// @Success 200 {object} service.AuthResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /login/2fa [post]
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var input service.TwoFactorLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.ChallengeToken == "" || input.Code == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Challenge token and code are required"})
+		return
+	}
+	input.IP = c.ClientIP()
+	input.UserAgent = c.Request.UserAgent()
+
+	resp, err := h.authService.CompleteTwoFactorLogin(c.Request.Context(), input)
+	if err != nil {
+		abortLogin(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// TwoFactorStatus godoc
+// @Summary Two-factor status
+// @Description Whether two-factor authentication is enabled for the current user and how many recovery codes are left.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} service.TwoFactorStatus
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/auth/2fa [get]
+func (h *AuthHandler) TwoFactorStatus(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.authService.TwoFactorStatus(c.Request.Context(), principal.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to load two-factor status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// EnrollTwoFactor godoc
+// @Summary Start two-factor enrollment
+// @Description Create a TOTP secret. Render otpauth_uri as a QR code for an authenticator app, then confirm with a code.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} service.TwoFactorEnrollment
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/auth/2fa/enroll [post]
+func (h *AuthHandler) EnrollTwoFactor(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.authService.BeginTwoFactorEnrollment(c.Request.Context(), principal.UserID)
+	if err != nil {
+		if errors.Is(err, service.ErrTwoFactorAlreadyEnabled) {
+			c.JSON(http.StatusConflict, ErrorResponse{Message: "Two-factor authentication is already enabled"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to start enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTwoFactor godoc
+// @Summary Confirm two-factor enrollment
+// @Description Enable two-factor authentication with a code from the authenticator app. The recovery codes are only shown in this response.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body service.TwoFactorCodeInput true "TOTP code"
+// @Success 200 {object} service.RecoveryCodes
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/auth/2fa/confirm [post]
+func (h *AuthHandler) ConfirmTwoFactor(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	var input service.TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Code == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Code is required"})
+		return
+	}
+
+	codes, err := h.authService.ConfirmTwoFactor(c.Request.Context(), principal.UserID, input)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidTwoFactorCode):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid code"})
+		case errors.Is(err, service.ErrTwoFactorNotEnrolled):
+			c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Start enrollment first"})
+		case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+			c.JSON(http.StatusConflict, ErrorResponse{Message: "Two-factor authentication is already enabled"})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to enable two-factor authentication"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+// DisableTwoFactor godoc
+// @Summary Disable two-factor authentication
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body service.TwoFactorCodeInput true "TOTP or recovery code"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse "Too many wrong codes"
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/auth/2fa/disable [post]
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	var input service.TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Code == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Code is required"})
+		return
+	}
+	input.IP = c.ClientIP()
+	input.UserAgent = c.Request.UserAgent()
+
+	if err := h.authService.DisableTwoFactor(c.Request.Context(), principal.UserID, input); err != nil {
+		h.twoFactorCodeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace every recovery code. The new codes are only shown in this response.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body service.TwoFactorCodeInput true "TOTP or recovery code"
+// @Success 200 {object} service.RecoveryCodes
+// @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse "Too many wrong codes"
+// @Failure 500 {object} ErrorResponse
+// @Security BearerAuth
+// @Router /api/auth/2fa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	principal, ok := middleware.RequirePrincipal(c)
+	if !ok {
+		return
+	}
+
+	var input service.TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.Code == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Code is required"})
+		return
+	}
+	input.IP = c.ClientIP()
+	input.UserAgent = c.Request.UserAgent()
+
+	codes, err := h.authService.RegenerateRecoveryCodes(c.Request.Context(), principal.UserID, input)
+	if err != nil {
+		h.twoFactorCodeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+func (h *AuthHandler) twoFactorCodeError(c *gin.Context, err error) {
+	var blocked *service.LoginBlockedError
+	switch {
+	case errors.As(err, &blocked):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, ErrorResponse{Message: "Too many wrong codes, try again later"})
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid code"})
+	case errors.Is(err, service.ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Two-factor authentication is not enabled"})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Message: "Failed to update two-factor authentication"})
+	}
+}
@@ -1 +1,192 @@
-package middleware
\ No newline at end of file
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/service"
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+)
+
+const principalKey = "principal"
+
+// Authenticate verifies the access token or API key of the request once and
+// stores the resulting Principal in the gin context and in the request
+// context. Every protected route, including the WebSocket upgrade, goes
+// through it. API keys are sent in the X-API-Key header or as a bearer token.
+func Authenticate(authService *service.AuthService, apiKeyService *service.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		apiKey := c.GetHeader("X-API-Key")
+		if bearer := strings.TrimPrefix(authHeader, "Bearer "); apiKey == "" && strings.HasPrefix(bearer, service.APIKeyPrefix) {
+			apiKey = bearer
+		}
+		if authHeader == "" && apiKey == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Missing token"})
+			return
+		}
+
+		var principal *models.Principal
+		var err error
+		if apiKey != "" {
+			principal, err = apiKeyService.Authenticate(c.Request.Context(), apiKey)
+		} else {
+			principal, err = authService.Authenticate(c.Request.Context(), authHeader)
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, service.ErrTokenRevoked):
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+			case errors.Is(err, service.ErrInvalidToken):
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			case errors.Is(err, service.ErrInvalidAPIKey):
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid API key"})
+			case errors.Is(err, service.ErrAccountSuspended):
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "account suspended"})
+			default:
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "authorization error"})
+			}
+			return
+		}
+
+		SetPrincipal(c, principal)
+		c.Next()
+	}
+}
+
+// Authorize checks the request path and method against the casbin policy for
+// the roles of the authenticated principal. API keys must in addition have a
+// scope that allows the request; scopes are the policy subjects
+// "scope:<name>". Denials are logged with the request ID. It must run after
+// Authenticate.
+func Authorize(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := CurrentPrincipal(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Authentication required"})
+			return
+		}
+
+		path := c.Request.URL.Path
+		method := c.Request.Method
+
+		subjects := make([]string, len(principal.Roles))
+		for i, role := range principal.Roles {
+			subjects[i] = string(role)
+		}
+		scopes := make([]string, len(principal.Scopes))
+		for i, scope := range principal.Scopes {
+			scopes[i] = "scope:" + scope
+		}
+
+		reason := "no policy allows any role of the principal"
+		allowed, matched, err := enforceAny(enforcer, subjects, path, method)
+		if err == nil && allowed && principal.Method == models.AuthMethodAPIKey {
+			reason = "no policy allows any scope of the API key"
+			allowed, _, err = enforceAny(enforcer, scopes, path, method)
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "authorization error"})
+			return
+		}
+		if !allowed {
+			attrs := []any{
+				"request_id", GetRequestID(c),
+				"user_id", principal.UserID,
+				"auth_method", principal.Method,
+				"roles", subjects,
+				"method", method,
+				"path", path,
+				"reason", reason,
+			}
+			if principal.Method == models.AuthMethodAPIKey {
+				attrs = append(attrs, "scopes", scopes, "role_policy", matched)
+			}
+			slog.Warn("authorization denied", attrs...)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// enforceAny reports whether the policy allows the request for any of
+// subjects, and the policy that did.
+func enforceAny(enforcer *casbin.SyncedEnforcer, subjects []string, path, method string) (bool, []string, error) {
+	for _, sub := range subjects {
+		allowed, matched, err := enforcer.EnforceEx(sub, path, method)
+		if err != nil || allowed {
+			return allowed, matched, err
+		}
+	}
+	return false, nil, nil
+}
+
+// RequireVerifiedEmail rejects principals whose email address is not
+// verified. It must run after Authenticate.
+func RequireVerifiedEmail(accountService *service.AccountService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := RequirePrincipal(c)
+		if !ok {
+			return
+		}
+
+		if err := accountService.RequireVerifiedEmail(c.Request.Context(), principal.UserID); err != nil {
+			if errors.Is(err, service.ErrEmailNotVerified) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "email address not verified"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "authorization error"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireTwoFactor rejects principals whose session was not opened with a
+// second factor. It guards sensitive actions and must run after Authenticate.
+func RequireTwoFactor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := RequirePrincipal(c)
+		if !ok {
+			return
+		}
+		if !principal.MFA {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "two-factor authentication required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// SetPrincipal stores the authenticated principal for the rest of the request.
+func SetPrincipal(c *gin.Context, principal *models.Principal) {
+	c.Set(principalKey, principal)
+	c.Request = c.Request.WithContext(models.ContextWithPrincipal(c.Request.Context(), principal))
+}
+
+// CurrentPrincipal returns the principal stored by Authenticate.
+func CurrentPrincipal(c *gin.Context) (*models.Principal, bool) {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return nil, false
+	}
+	principal, ok := value.(*models.Principal)
+	return principal, ok && principal != nil
+}
+
+// RequirePrincipal returns the principal stored by Authenticate, or aborts
+// the request with 401 and returns false when there is none.
+func RequirePrincipal(c *gin.Context) (*models.Principal, bool) {
+	principal, ok := CurrentPrincipal(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Authentication required"})
+	}
+	return principal, ok
+}
@@ -1,13 +1,13 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/Dostonlv/hackathon-nt/internal/models"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
 )
 
 
@@ -18,33 +18,24 @@ type BidRateLimiter struct {
 	window   time.Duration          
 }
 
-type Claims struct {
-	RolePayload string `json:"role"`
-	UserID      string `json:"user_id"`
-	jwt.StandardClaims
-}
-
 type bidRequests struct {
 	count     int       
 	startTime time.Time 
 }
 
 
-func NewBidRateLimiter() *BidRateLimiter {
-	limiter := &BidRateLimiter{
+// NewBidRateLimiter creates a limiter. Run must be started to evict expired
+// entries.
+func NewBidRateLimiter(limit int, window time.Duration) *BidRateLimiter {
+	return &BidRateLimiter{
 		requests: make(map[string]*bidRequests),
-		limit:    5,           
-		window:   time.Minute,
+		limit:    limit,
+		window:   window,
 	}
-
-
-	go limiter.cleanupLoop()
-
-	return limiter
 }
 
 
-func (rl *BidRateLimiter) BidRateLimitMiddleware(jwtSecret string) gin.HandlerFunc {
+func (rl *BidRateLimiter) BidRateLimitMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		if c.Request.Method != http.MethodPost {
@@ -52,54 +43,17 @@ func (rl *BidRateLimiter) BidRateLimitMiddleware(jwtSecret string) gin.HandlerFu
 			return
 		}
 
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Missing token"})
-			c.Abort()
-			return
-		}
-		var jwtString string
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			jwtString = strings.Split(authHeader, "Bearer ")[1]
-		} else {
-			jwtString = authHeader
-		}
-
-
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(jwtString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(jwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			c.Abort()
-			return
-		}
-
-		// Extract role from claims
-		userRole := claims.RolePayload
-		if userRole == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "role not found in token"})
-			c.Abort()
+		principal, ok := RequirePrincipal(c)
+		if !ok {
 			return
 		}
 
-
-		if userRole != "contractor" {
-
+		if !principal.HasRole(models.RoleContractor) {
 			c.Next()
 			return
 		}
 
-		userId := claims.UserID
-		if userId == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "user_id not found in token"})
-			c.Abort()
-			return
-		}
-
-		if !rl.allowBidSubmission(userId) {
+		if !rl.allowBidSubmission(principal.UserID.String()) {
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error":       "Rate limit exceeded. Please try again later.",
 				"retry_after": time.Now().Add(rl.window).Unix(),
@@ -144,11 +98,18 @@ func (rl *BidRateLimiter) allowBidSubmission(userID string) bool {
 }
 
 
-func (rl *BidRateLimiter) cleanupLoop() {
+// Run evicts expired entries until ctx is cancelled.
+func (rl *BidRateLimiter) Run(ctx context.Context) {
 	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
 		rl.mu.Lock()
 		now := time.Now()
 		for userID, req := range rl.requests {
@@ -1,9 +1,7 @@
 package api
 
 import (
-	"net/http"
-	"strings"
-
+	"github.com/Dostonlv/hackathon-nt/config"
 	_ "github.com/Dostonlv/hackathon-nt/docs"
 	"github.com/Dostonlv/hackathon-nt/internal/api/handlers"
 	"github.com/Dostonlv/hackathon-nt/internal/api/middleware"
@@ -11,129 +9,10 @@ import (
 	"github.com/Dostonlv/hackathon-nt/internal/utils"
 	"github.com/casbin/casbin/v2"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt"
-	"github.com/google/uuid"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-// Claims represents the JWT claims structure
-type Claims struct {
-	RolePayload string    `json:"role"`
-	UserID      uuid.UUID `json:"user_id"`
-	jwt.StandardClaims
-}
-
-// AuthorizationMiddleware checks permissions using Casbin with JWT role
-func AuthorizationMiddleware(enforcer *casbin.Enforcer, jwtSecret string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-
-		// Allow WebSocket upgrade requests to pass through
-		if strings.ToLower(c.GetHeader("Upgrade")) == "websocket" {
-			// Get JWT token from Authorization header
-			authHeader := c.GetHeader("Authorization")
-			if authHeader == "" {
-				c.JSON(http.StatusUnauthorized, gin.H{"message": "Missing token"})
-				c.Abort()
-				return
-			}
-			var jwtString string
-			if strings.HasPrefix(authHeader, "Bearer ") {
-				jwtString = strings.Split(authHeader, "Bearer ")[1]
-			} else {
-				jwtString = authHeader
-			}
-
-			// Parse and validate the token
-			claims := &Claims{}
-			token, err := jwt.ParseWithClaims(jwtString, claims, func(token *jwt.Token) (interface{}, error) {
-				return []byte(jwtSecret), nil
-			})
-
-			if err != nil || !token.Valid {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-				c.Abort()
-				return
-			}
-
-			userId := claims.UserID.String()
-			if userId == "" {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "user_id not found in token"})
-				c.Abort()
-				return
-			}
-
-			// Store user ID in context for later use if needed
-			c.Set("userId", userId)
-			c.Next()
-			return
-		}
-
-		// Get JWT token from Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"message": "Missing token"})
-			c.Abort()
-			return
-		}
-		var jwtString string
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			jwtString = strings.Split(authHeader, "Bearer ")[1]
-		} else {
-			jwtString = authHeader
-		}
-
-		// Parse and validate the token
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(jwtString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(jwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
-			c.Abort()
-			return
-		}
-
-		// Extract role from claims
-		userRole := claims.RolePayload
-		if userRole == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "role not found in token"})
-			c.Abort()
-			return
-		}
-
-		userId := claims.UserID.String()
-		if userId == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "user_id not found in token"})
-			c.Abort()
-			return
-		}
-
-		// Get request path and method
-		path := c.Request.URL.Path
-		method := c.Request.Method
-		// Check permission using Casbin
-		allowed, err := enforcer.Enforce(userRole, path, method)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "authorization error"})
-			c.Abort()
-			return
-		}
-
-		if !allowed {
-			c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
-			c.Abort()
-			return
-		}
-
-		// Store role in context for later use if needed
-		c.Set("userRole", userRole)
-		c.Set("userId", userId)
-		c.Next()
-	}
-}
-
 // NewRouter -.
 // Swagger spec:
 // @title       hackathon
@@ -142,39 +21,133 @@ func AuthorizationMiddleware(enforcer *casbin.Enforcer, jwtSecret string) gin.Ha
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
-func SetupRouter(authService *service.AuthService, tenderService *service.TenderService, bidService *service.BidService, historyService *service.HistoryService, enforcer *casbin.Enforcer, jwtSecret string) *gin.Engine {
+func SetupRouter(cfg *config.Config, authService *service.AuthService, securityLog *service.SecurityLog, adminService *service.AdminService, accountService *service.AccountService, apiKeyService *service.APIKeyService, tenderService *service.TenderService, documentService *service.DocumentService, clarificationService *service.ClarificationService, bidService *service.BidService, historyService *service.HistoryService, healthService *service.HealthService, policyService *service.PolicyService, orgService *service.OrganizationService, authorizer *service.Authorizer, enforcer *casbin.SyncedEnforcer, jwtUtil *utils.JWTUtil, notificationService *utils.NotificationService, bidLimiter *middleware.BidRateLimiter) *gin.Engine {
+	gin.SetMode(cfg.HTTP.Mode)
 	router := gin.Default()
+	router.Use(middleware.RequestID())
+
+	pagination := handlers.Pagination{
+		DefaultLimit: cfg.Pagination.DefaultLimit,
+		MaxLimit:     cfg.Pagination.MaxLimit,
+	}
+
+	authHandler := handlers.NewAuthHandler(authService, accountService)
+	accountHandler := handlers.NewAccountHandler(accountService)
+	adminHandler := handlers.NewAdminHandler(authService, adminService, historyService, securityLog, pagination)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	policyHandler := handlers.NewPolicyHandler(policyService)
+	orgHandler := handlers.NewOrganizationHandler(orgService)
+	tenderHandler := handlers.NewTenderHandler(tenderService, notificationService, pagination)
+	documentHandler := handlers.NewDocumentHandler(documentService, cfg.Documents.MaxSize, cfg.Documents.TransferTimeout.Duration)
+	clarificationHandler := handlers.NewClarificationHandler(clarificationService, notificationService)
+	bidHandler := handlers.NewBidHandler(bidService, notificationService, pagination)
+	wsHandler := handlers.NewWebSocketHandler(notificationService, authorizer, cfg.Notification.AllowedOrigins)
+	historyHandler := handlers.NewHistoryHandler(historyService, pagination)
+	healthHandler := handlers.NewHealthHandler(healthService)
+	keysHandler := handlers.NewKeysHandler(jwtUtil, cfg.JWT.KeyRefreshInterval.Duration)
+
+	// Sensitive actions optionally require a session opened with 2FA
+	var sensitive gin.HandlerFunc = func(c *gin.Context) { c.Next() }
+	if cfg.TwoFactor.RequireForSensitiveActions {
+		sensitive = middleware.RequireTwoFactor()
+	}
 
-	bidLimiter := middleware.NewBidRateLimiter()
-	authHandler := handlers.NewAuthHandler(authService)
-	tenderHandler := handlers.NewTenderHandler(tenderService)
-	notificationService := utils.NewNotificationService()
-	bidHandler := handlers.NewBidHandler(bidService, notificationService)
-	wsHandler := handlers.NewWebSocketHandler(notificationService)
-	historyHandler := handlers.NewHistoryHandler(historyService)
 	// Public routes (no authorization required)
 	router.POST("/register", authHandler.Register)
 	router.POST("/login", authHandler.Login)
+	router.POST("/login/2fa", authHandler.LoginTwoFactor)
+	router.POST("/auth/refresh", authHandler.Refresh)
+	router.POST("/auth/logout", authHandler.Logout)
+	router.POST("/auth/verify-email", accountHandler.VerifyEmail)
+	router.POST("/auth/password-reset/request", accountHandler.RequestPasswordReset)
+	router.POST("/auth/password-reset", accountHandler.ResetPassword)
+
+	// Probes
+	router.GET("/healthz", healthHandler.Liveness)
+	router.GET("/readyz", healthHandler.Readiness)
+
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", keysHandler.JWKS)
 
 	// Protected routes (require authorization)
 	api := router.Group("/api")
-	api.Use(AuthorizationMiddleware(enforcer, jwtSecret))
+	api.Use(middleware.Authenticate(authService, apiKeyService), middleware.Authorize(enforcer))
 	{
+		api.POST("/auth/logout-all", authHandler.LogoutAll)
+		api.POST("/auth/verify-email/resend", accountHandler.ResendVerification)
+		api.GET("/auth/2fa", authHandler.TwoFactorStatus)
+		api.POST("/auth/2fa/enroll", authHandler.EnrollTwoFactor)
+		api.POST("/auth/2fa/confirm", authHandler.ConfirmTwoFactor)
+		api.POST("/auth/2fa/disable", authHandler.DisableTwoFactor)
+		api.POST("/auth/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+		api.GET("/auth/api-keys", apiKeyHandler.ListAPIKeys)
+		api.POST("/auth/api-keys", apiKeyHandler.CreateAPIKey)
+		api.DELETE("/auth/api-keys/:id", apiKeyHandler.RevokeAPIKey)
+
+		api.GET("/organizations", orgHandler.ListOrganizations)
+		api.POST("/organizations", orgHandler.CreateOrganization)
+		api.POST("/organizations/invitations/accept", orgHandler.AcceptInvitation)
+		api.GET("/organizations/:id", orgHandler.GetOrganization)
+		api.PUT("/organizations/:id", orgHandler.UpdateOrganization)
+		api.GET("/organizations/:id/members", orgHandler.ListMembers)
+		api.PUT("/organizations/:id/members/:user_id", orgHandler.UpdateMemberRole)
+		api.DELETE("/organizations/:id/members/:user_id", orgHandler.RemoveMember)
+		api.GET("/organizations/:id/invitations", orgHandler.ListInvitations)
+		api.POST("/organizations/:id/invitations", orgHandler.InviteMember)
+		api.DELETE("/organizations/:id/invitations/:invitation_id", orgHandler.RevokeInvitation)
+
 		api.GET("/ws", wsHandler.HandleWebSocket)
-		api.POST("/client/tenders", tenderHandler.CreateTender)
+		api.GET("/categories", tenderHandler.ListCategories)
+		api.POST("/client/tenders", middleware.RequireVerifiedEmail(accountService), tenderHandler.CreateTender)
 		api.GET("/client/tenders", tenderHandler.ListTenders)
 		api.PUT("/client/tenders/:id", tenderHandler.UpdateTenderStatus)
-		api.DELETE("/client/tenders/:id", tenderHandler.DeleteTender)
+		api.PATCH("/client/tenders/:id", tenderHandler.UpdateTender)
+		api.GET("/client/tenders/:tender_id/transitions", tenderHandler.GetTenderLifecycle)
+		api.POST("/client/tenders/:tender_id/transitions", tenderHandler.TransitionTender)
+		api.DELETE("/client/tenders/:id", sensitive, tenderHandler.DeleteTender)
+		api.POST("/client/tenders/:tender_id/documents", documentHandler.UploadDocument)
+		api.GET("/client/tenders/:tender_id/documents", documentHandler.ListDocuments)
+		api.GET("/client/tenders/:tender_id/documents/:document_id", documentHandler.DownloadDocument)
+		api.DELETE("/client/tenders/:id/documents/:document_id", documentHandler.DeleteDocument)
+		api.GET("/client/tenders/:tender_id/questions", clarificationHandler.ListClientQuestions)
+		api.POST("/client/tenders/:tender_id/questions/:question_id/answer", clarificationHandler.AnswerQuestion)
 		api.GET("/client/tenders/:tender_id/bids", bidHandler.GetBidsByClientID)
-		api.POST("/client/tenders/:tender_id/award/:bid_id", bidHandler.AwardBid)
+		api.POST("/client/tenders/:tender_id/award/:bid_id", sensitive, bidHandler.AwardBid)
 		api.GET("/client/tenders/filter", tenderHandler.ListTendersFiltering)
 
-		api.POST("/contractor/tenders/:tender_id/bid", bidLimiter.BidRateLimitMiddleware(jwtSecret), bidHandler.CreateBid)
+		api.GET("/contractor/tenders", tenderHandler.ListMarketplace)
+		api.GET("/contractor/tenders/:tender_id", tenderHandler.GetMarketplaceTender)
+		api.POST("/contractor/tenders/:tender_id/bid", middleware.RequireVerifiedEmail(accountService), bidLimiter.BidRateLimitMiddleware(), bidHandler.CreateBid)
+		api.GET("/contractor/tenders/:tender_id/documents", documentHandler.ListDocuments)
+		api.GET("/contractor/tenders/:tender_id/documents/:document_id", documentHandler.DownloadDocument)
+		api.GET("/contractor/tenders/:tender_id/questions", clarificationHandler.ListContractorQuestions)
+		api.POST("/contractor/tenders/:tender_id/questions", middleware.RequireVerifiedEmail(accountService), clarificationHandler.AskQuestion)
+		api.POST("/contractor/tenders/:tender_id/watch", tenderHandler.WatchTender)
+		api.DELETE("/contractor/tenders/:tender_id/watch", tenderHandler.UnwatchTender)
 		api.GET("/contractor/bids", bidHandler.GetBidsByContractorID)
 		api.DELETE("/contractor/bids/:bid_id", bidHandler.DeleteBidByContractorID)
 
 		api.GET("/users/:id/tenders", historyHandler.GetTenderHistory)
 		api.GET("/users/:id/bids", historyHandler.GetBidHistory)
+
+		api.GET("/admin/users", adminHandler.ListUsers)
+		api.GET("/admin/users/:id", adminHandler.GetUser)
+		api.POST("/admin/users/:id/suspend", adminHandler.SuspendUser)
+		api.POST("/admin/users/:id/reactivate", adminHandler.ReactivateUser)
+		api.PUT("/admin/users/:id/role", adminHandler.ChangeUserRole)
+		api.POST("/admin/users/:id/password-reset", adminHandler.ForcePasswordReset)
+		api.GET("/admin/users/:id/tenders", adminHandler.GetUserTenders)
+		api.GET("/admin/users/:id/bids", adminHandler.GetUserBids)
+		api.POST("/admin/users/:id/unlock", adminHandler.UnlockUser)
+		api.POST("/admin/ips/:ip/unlock", adminHandler.UnlockIP)
+		api.GET("/admin/security-events", adminHandler.ListSecurityEvents)
+		api.GET("/admin/policies", policyHandler.ListPolicies)
+		api.POST("/admin/policies", policyHandler.AddPolicy)
+		api.DELETE("/admin/policies", policyHandler.RemovePolicy)
+		api.GET("/admin/policies/audit", policyHandler.ListPolicyAudit)
+		api.GET("/admin/policies/explain", policyHandler.ExplainPolicy)
+		api.POST("/admin/role-groupings", policyHandler.AddRoleGrouping)
+		api.DELETE("/admin/role-groupings", policyHandler.RemoveRoleGrouping)
 	}
 
 	// Swagger route
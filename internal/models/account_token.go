@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccountTokenPurpose is what an AccountToken may be used for.
+type AccountTokenPurpose string
+
+const (
+	AccountTokenEmailVerification AccountTokenPurpose = "email_verification"
+	AccountTokenPasswordReset     AccountTokenPurpose = "password_reset"
+)
+
+// AccountToken is a stored, hashed one-time token sent by email. It is valid
+// until ExpiresAt and can be used once.
+type AccountToken struct {
+	ID        uuid.UUID           `json:"id" db:"id"`
+	UserID    uuid.UUID           `json:"user_id" db:"user_id"`
+	Purpose   AccountTokenPurpose `json:"purpose" db:"purpose"`
+	TokenHash string              `json:"-" db:"token_hash"`
+	ExpiresAt time.Time           `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time          `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time           `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// API key scopes. Each scope is a casbin subject "scope:<name>" in the
+// policy, listing the routes a key with that scope may call.
+const (
+	ScopeTendersRead  = "tenders:read"
+	ScopeTendersWrite = "tenders:write"
+	ScopeBidsRead     = "bids:read"
+	ScopeBidsWrite    = "bids:write"
+)
+
+// APIKeyScopes are the scopes an API key may be given.
+var APIKeyScopes = []string{ScopeTendersRead, ScopeTendersWrite, ScopeBidsRead, ScopeBidsWrite}
+
+// ValidAPIKeyScope reports whether scope is one of APIKeyScopes.
+func ValidAPIKeyScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey is a long-lived credential a user creates for an integration. It
+// acts with the user's role, limited to Scopes. Only the hash of the key is
+// stored; Prefix identifies it in listings.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	KeyHash    string     `json:"-" db:"key_hash"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Active reports whether the key can still be used at now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
@@ -7,13 +7,16 @@ import (
 )
 
 type Bid struct {
-	ID           uuid.UUID `json:"id" db:"id"`
-	TenderID     uuid.UUID `json:"tender_id" db:"tender_id"`
-	ContractorID uuid.UUID `json:"contractor_id" db:"contractor_id"`
-	Price        float64   `json:"price" db:"price"`
-	DeliveryTime int       `json:"delivery_time" db:"delivery_time"`
-	Comments     string    `json:"comments" db:"comments"`
-	Status       string    `json:"status" db:"status"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	ID             uuid.UUID `json:"id" db:"id"`
+	TenderID       uuid.UUID `json:"tender_id" db:"tender_id"`
+	ContractorID   uuid.UUID `json:"contractor_id" db:"contractor_id"`
+	OrganizationID uuid.UUID `json:"organization_id" db:"organization_id"`
+	Price          float64   `json:"price" db:"price"`
+	DeliveryTime   int       `json:"delivery_time" db:"delivery_time"`
+	Comments       string    `json:"comments" db:"comments"`
+	Status         string    `json:"status" db:"status"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+	// Match is set on the results of a search.
+	Match *SearchMatch `json:"match,omitempty" db:"-"`
 }
@@ -0,0 +1,25 @@
+package models
+
+// Category is a node of the tender classification, keyed by a CPV-style
+// code. Top level categories have no parent.
+type Category struct {
+	Code       string  `json:"code" db:"code"`
+	ParentCode *string `json:"parent_code,omitempty" db:"parent_code"`
+	Name       string  `json:"name" db:"name"`
+}
+
+// CategoryCount is the number of tenders in a category or any of its
+// subcategories.
+type CategoryCount struct {
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// TenderFacets counts the tenders matching a filter by category and by
+// status. Each facet ignores its own filter, so the counts show what
+// choosing another category or status would return.
+type TenderFacets struct {
+	Categories []CategoryCount      `json:"categories"`
+	Statuses   map[TenderStatus]int `json:"statuses"`
+}
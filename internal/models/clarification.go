@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AnswerVisibility tells who sees the answer to a clarification question.
+type AnswerVisibility string
+
+const (
+	// AnswerPublic answers are shown to every contractor, without the asker.
+	AnswerPublic AnswerVisibility = "public"
+	// AnswerPrivate answers are only shown to the organization that asked.
+	AnswerPrivate AnswerVisibility = "private"
+)
+
+func (v AnswerVisibility) IsValid() bool {
+	return v == AnswerPublic || v == AnswerPrivate
+}
+
+// TenderQuestion is a clarification question a contractor asked about a
+// tender, and the answer of the client once given.
+type TenderQuestion struct {
+	ID       uuid.UUID `json:"id" db:"id"`
+	TenderID uuid.UUID `json:"tender_id" db:"tender_id"`
+	// AskedBy and OrganizationID are the user and the contractor
+	// organization who asked. They are always set but left out of the
+	// questions of others shown to contractors, see Anonymized.
+	AskedBy        *uuid.UUID `json:"asked_by,omitempty" db:"asked_by"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty" db:"organization_id"`
+	Question       string     `json:"question" db:"question"`
+	// Answer, Visibility, AnsweredBy and AnsweredAt are set together when
+	// the client answers.
+	Answer     *string           `json:"answer,omitempty" db:"answer"`
+	Visibility *AnswerVisibility `json:"visibility,omitempty" db:"visibility"`
+	AnsweredBy *uuid.UUID        `json:"answered_by,omitempty" db:"answered_by"`
+	AnsweredAt *time.Time        `json:"answered_at,omitempty" db:"answered_at"`
+	CreatedAt  time.Time         `json:"created_at" db:"created_at"`
+}
+
+// Anonymized returns the question without the users and organization
+// involved, as contractors see the questions of others.
+func (q TenderQuestion) Anonymized() TenderQuestion {
+	q.AskedBy = nil
+	q.OrganizationID = nil
+	q.AnsweredBy = nil
+	return q
+}
+
+// AskingOrganization returns the organization who asked, or uuid.Nil when
+// the question has been anonymized.
+func (q TenderQuestion) AskingOrganization() uuid.UUID {
+	if q.OrganizationID == nil {
+		return uuid.Nil
+	}
+	return *q.OrganizationID
+}
+
+// TenderClarifications are the clarification questions about a tender.
+type TenderClarifications struct {
+	// ClosesAt is when the tender stops taking questions.
+	ClosesAt  time.Time        `json:"closes_at"`
+	Questions []TenderQuestion `json:"questions"`
+}
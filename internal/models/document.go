@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TenderDocument is a file uploaded to a tender. The contents live in the
+// blob store under StorageKey.
+type TenderDocument struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	TenderID    uuid.UUID `json:"tender_id" db:"tender_id"`
+	Filename    string    `json:"filename" db:"filename"`
+	ContentType string    `json:"content_type" db:"content_type"`
+	Size        int64     `json:"size" db:"size"`
+	// SHA256 is the hex encoded digest of the contents.
+	SHA256     string    `json:"sha256" db:"sha256"`
+	StorageKey string    `json:"-" db:"storage_key"`
+	UploadedBy uuid.UUID `json:"uploaded_by" db:"uploaded_by"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
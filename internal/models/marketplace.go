@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MarketplaceTender is a tender as contractors browsing the marketplace see
+// it. The client user who created it and the internal details of its
+// lifecycle are left out.
+type MarketplaceTender struct {
+	ID uuid.UUID `json:"id"`
+	// OrganizationID is the client organization issuing the tender.
+	OrganizationID uuid.UUID    `json:"organization_id"`
+	Title          string       `json:"title"`
+	Description    string       `json:"description"`
+	Deadline       time.Time    `json:"deadline"`
+	Budget         float64      `json:"budget"`
+	Status         TenderStatus `json:"status"`
+	Attachment     *string      `json:"attachment,omitempty"`
+	CategoryCode   *string      `json:"category_code,omitempty"`
+	Tags           []string     `json:"tags"`
+	CreatedAt      time.Time    `json:"created_at"`
+	// HasBid tells whether an organization of the caller has bid on the
+	// tender.
+	HasBid bool `json:"has_bid"`
+	// Documents are only set on a single tender.
+	Documents []MarketplaceDocument `json:"documents,omitempty"`
+	Match     *SearchMatch          `json:"match,omitempty"`
+}
+
+// NewMarketplaceTender returns the marketplace view of t.
+func NewMarketplaceTender(t *Tender) MarketplaceTender {
+	tags := t.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return MarketplaceTender{
+		ID:             t.ID,
+		OrganizationID: t.OrganizationID,
+		Title:          t.Title,
+		Description:    t.Description,
+		Deadline:       t.Deadline,
+		Budget:         t.Budget,
+		Status:         t.Status,
+		Attachment:     t.Attachment,
+		CategoryCode:   t.CategoryCode,
+		Tags:           tags,
+		CreatedAt:      t.CreatedAt,
+		Match:          t.Match,
+	}
+}
+
+// MarketplaceDocument is a tender document as contractors see it, without
+// the user who uploaded it.
+type MarketplaceDocument struct {
+	ID          uuid.UUID `json:"id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NewMarketplaceDocument returns the marketplace view of d.
+func NewMarketplaceDocument(d *TenderDocument) MarketplaceDocument {
+	return MarketplaceDocument{
+		ID:          d.ID,
+		Filename:    d.Filename,
+		ContentType: d.ContentType,
+		Size:        d.Size,
+		SHA256:      d.SHA256,
+		CreatedAt:   d.CreatedAt,
+	}
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrganizationKind says which side of a tender an organization is on. Client
+// organizations own tenders and contractor organizations own bids.
+type OrganizationKind string
+
+const (
+	OrganizationClient     OrganizationKind = "client"
+	OrganizationContractor OrganizationKind = "contractor"
+)
+
+func (k OrganizationKind) Valid() bool {
+	return k == OrganizationClient || k == OrganizationContractor
+}
+
+// OrgRole is the role of a member inside an organization.
+type OrgRole string
+
+const (
+	// OrgRoleOwner manages the organization and its members.
+	OrgRoleOwner OrgRole = "owner"
+	// OrgRoleManager manages the tenders or bids of the organization.
+	OrgRoleManager OrgRole = "manager"
+	// OrgRoleViewer reads the tenders or bids of the organization.
+	OrgRoleViewer OrgRole = "viewer"
+	// OrgRoleBidder places and withdraws bids of a contractor organization.
+	OrgRoleBidder OrgRole = "bidder"
+)
+
+// ValidFor reports whether the role exists in organizations of kind.
+// Bidders only exist in contractor organizations.
+func (r OrgRole) ValidFor(kind OrganizationKind) bool {
+	switch r {
+	case OrgRoleOwner, OrgRoleManager, OrgRoleViewer:
+		return true
+	case OrgRoleBidder:
+		return kind == OrganizationContractor
+	}
+	return false
+}
+
+// Organization is a company or department. Tenders and bids belong to an
+// organization and its members act on them according to their OrgRole.
+type Organization struct {
+	ID        uuid.UUID        `json:"id" db:"id"`
+	Name      string           `json:"name" db:"name"`
+	Kind      OrganizationKind `json:"kind" db:"kind"`
+	CreatedBy *uuid.UUID       `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at" db:"updated_at"`
+	// Role is the role of the user the organization was listed for.
+	Role OrgRole `json:"role,omitempty" db:"-"`
+}
+
+// Membership is a user's place in an organization. Username and Email are
+// filled in when members are listed.
+type Membership struct {
+	OrganizationID uuid.UUID `json:"organization_id" db:"organization_id"`
+	UserID         uuid.UUID `json:"user_id" db:"user_id"`
+	Username       string    `json:"username,omitempty" db:"username"`
+	Email          string    `json:"email,omitempty" db:"email"`
+	Role           OrgRole   `json:"role" db:"role"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// OrganizationInvitation invites an email address to join an organization
+// with a role. The token is emailed; only its hash is stored.
+type OrganizationInvitation struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id" db:"organization_id"`
+	Email          string     `json:"email" db:"email"`
+	Role           OrgRole    `json:"role" db:"role"`
+	TokenHash      string     `json:"-" db:"token_hash"`
+	InvitedBy      *uuid.UUID `json:"invited_by,omitempty" db:"invited_by"`
+	ExpiresAt      time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,10 @@
+package models
+
+// Page is a page of a list. NextCursor is passed back to get the next page
+// and is null on the last one. Total counts the whole list and is only set
+// when asked for.
+type Page[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+	Total      *int    `json:"total,omitempty"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PolicyAuditAction is what a PolicyAudit entry records.
+type PolicyAuditAction string
+
+const (
+	PolicyAuditAdd    PolicyAuditAction = "add"
+	PolicyAuditRemove PolicyAuditAction = "remove"
+)
+
+// Policy is a casbin permission: Subject (a role or "scope:<name>") may
+// call Action on paths matching Object.
+type Policy struct {
+	Subject string `json:"subject"`
+	Object  string `json:"object"`
+	Action  string `json:"action"`
+}
+
+// RoleGrouping makes Member inherit every permission of Role.
+type RoleGrouping struct {
+	Member string `json:"member"`
+	Role   string `json:"role"`
+}
+
+// PolicyAudit is an entry of the audit trail of policy changes. Ptype is the
+// casbin policy type, "p" for policies and "g" for role groupings.
+type PolicyAudit struct {
+	ID        uuid.UUID         `json:"id" db:"id"`
+	ActorID   *uuid.UUID        `json:"actor_id,omitempty" db:"actor_id"`
+	Action    PolicyAuditAction `json:"action" db:"action"`
+	Ptype     string            `json:"ptype" db:"ptype"`
+	Rule      []string          `json:"rule" db:"rule"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuthMethod is how a Principal proved its identity.
+type AuthMethod string
+
+const (
+	AuthMethodJWT    AuthMethod = "jwt"
+	AuthMethodAPIKey AuthMethod = "api_key"
+)
+
+// Principal is the authenticated caller of a request. It is built once by
+// the authentication middleware and read by everything after it.
+type Principal struct {
+	UserID uuid.UUID
+	Roles  []UserRole
+	// TokenID is the jti of an access token or the ID of an API key.
+	TokenID string
+	// Scopes restricts what the caller may do on top of its roles. It is
+	// empty for user sessions, which carry every permission of their roles.
+	Scopes []string
+	Method AuthMethod
+	// MFA is set when the session was opened with a second factor.
+	MFA       bool
+	ExpiresAt time.Time
+}
+
+// HasRole reports whether the principal has role.
+func (p *Principal) HasRole(role UserRole) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying p.
+func ContextWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored in ctx, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
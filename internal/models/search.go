@@ -0,0 +1,10 @@
+package models
+
+// SearchMatch tells how a search result matched the query. Title and Snippet
+// are HTML escaped, with the matched words in <mark> elements.
+type SearchMatch struct {
+	// Rank orders the results; higher is better.
+	Rank    float64 `json:"rank"`
+	Title   string  `json:"title,omitempty"`
+	Snippet string  `json:"snippet"`
+}
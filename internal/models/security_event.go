@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SecurityEventType classifies a SecurityEvent.
+type SecurityEventType string
+
+const (
+	SecurityEventLoginSucceeded  SecurityEventType = "login_succeeded"
+	SecurityEventLoginFailed     SecurityEventType = "login_failed"
+	SecurityEventLoginBlocked    SecurityEventType = "login_blocked"
+	SecurityEventAccountLocked   SecurityEventType = "account_locked"
+	SecurityEventIPLocked        SecurityEventType = "ip_locked"
+	SecurityEventAccountUnlocked SecurityEventType = "account_unlocked"
+	SecurityEventIPUnlocked      SecurityEventType = "ip_unlocked"
+	SecurityEventUserSuspended   SecurityEventType = "user_suspended"
+	SecurityEventUserReactivated SecurityEventType = "user_reactivated"
+	SecurityEventRoleChanged     SecurityEventType = "role_changed"
+	SecurityEventPasswordReset   SecurityEventType = "password_reset_forced"
+)
+
+// SecurityEvent is an entry of the security audit log. UserID is the account
+// the event is about, ActorID the user who caused it when that is someone
+// else, such as an admin unlocking an account.
+type SecurityEvent struct {
+	ID        uuid.UUID         `json:"id" db:"id"`
+	Type      SecurityEventType `json:"type" db:"type"`
+	UserID    *uuid.UUID        `json:"user_id,omitempty" db:"user_id"`
+	ActorID   *uuid.UUID        `json:"actor_id,omitempty" db:"actor_id"`
+	Username  string            `json:"username,omitempty" db:"username"`
+	IP        string            `json:"ip,omitempty" db:"ip"`
+	UserAgent string            `json:"user_agent,omitempty" db:"user_agent"`
+	Detail    string            `json:"detail,omitempty" db:"detail"`
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+}
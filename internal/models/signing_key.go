@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// SigningKey is a stored JWT signing key. PrivateKey is encrypted at rest.
+type SigningKey struct {
+	ID          string    `json:"id" db:"id"`
+	Algorithm   string    `json:"algorithm" db:"algorithm"`
+	PrivateKey  []byte    `json:"-" db:"private_key"`
+	ActivatesAt time.Time `json:"activates_at" db:"activates_at"`
+	ExpiresAt   time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
@@ -10,27 +10,137 @@ type TenderStatus string
 
 func (s TenderStatus) IsValid() bool {
 	switch s {
-	case TenderStatusOpen, TenderStatusClosed, TenderStatusAwarded:
+	case TenderStatusDraft, TenderStatusOpen, TenderStatusClosed, TenderStatusEvaluating, TenderStatusAwarded, TenderStatusCancelled:
 		return true
 	}
 	return false
 }
 
+// Editable reports whether the details of a tender in this status may still
+// change.
+func (s TenderStatus) Editable() bool {
+	return s != TenderStatusAwarded && s != TenderStatusCancelled
+}
+
+// Transitions returns the transitions allowed from this status.
+func (s TenderStatus) Transitions() []TenderTransition {
+	allowed := []TenderTransition{}
+	for _, t := range tenderTransitionOrder {
+		if _, ok := t.Target(s); ok {
+			allowed = append(allowed, t)
+		}
+	}
+	return allowed
+}
+
+const (
+	TenderStatusDraft TenderStatus = "draft"
+	// TenderStatusOpen is a published tender that accepts bids.
+	TenderStatusOpen       TenderStatus = "open"
+	TenderStatusClosed     TenderStatus = "closed"
+	TenderStatusEvaluating TenderStatus = "evaluating"
+	TenderStatusAwarded    TenderStatus = "awarded"
+	TenderStatusCancelled  TenderStatus = "cancelled"
+)
+
+// TenderTransition moves a tender from one status to another.
+type TenderTransition string
+
 const (
-	TenderStatusOpen    TenderStatus = "open"
-	TenderStatusClosed  TenderStatus = "closed"
-	TenderStatusAwarded TenderStatus = "awarded"
+	TenderTransitionPublish  TenderTransition = "publish"
+	TenderTransitionClose    TenderTransition = "close"
+	TenderTransitionReopen   TenderTransition = "reopen"
+	TenderTransitionEvaluate TenderTransition = "evaluate"
+	TenderTransitionAward    TenderTransition = "award"
+	TenderTransitionCancel   TenderTransition = "cancel"
 )
 
+type tenderTransitionRule struct {
+	from []TenderStatus
+	to   TenderStatus
+}
+
+// tenderTransitions is the tender lifecycle:
+//
+//	draft -> open -> closed -> evaluating -> awarded
+//
+// A closed tender may be reopened or awarded right away, and anything short
+// of an award may be cancelled.
+var tenderTransitions = map[TenderTransition]tenderTransitionRule{
+	TenderTransitionPublish:  {from: []TenderStatus{TenderStatusDraft}, to: TenderStatusOpen},
+	TenderTransitionClose:    {from: []TenderStatus{TenderStatusOpen}, to: TenderStatusClosed},
+	TenderTransitionReopen:   {from: []TenderStatus{TenderStatusClosed}, to: TenderStatusOpen},
+	TenderTransitionEvaluate: {from: []TenderStatus{TenderStatusClosed}, to: TenderStatusEvaluating},
+	TenderTransitionAward:    {from: []TenderStatus{TenderStatusClosed, TenderStatusEvaluating}, to: TenderStatusAwarded},
+	TenderTransitionCancel:   {from: []TenderStatus{TenderStatusDraft, TenderStatusOpen, TenderStatusClosed, TenderStatusEvaluating}, to: TenderStatusCancelled},
+}
+
+var tenderTransitionOrder = []TenderTransition{
+	TenderTransitionPublish,
+	TenderTransitionClose,
+	TenderTransitionReopen,
+	TenderTransitionEvaluate,
+	TenderTransitionAward,
+	TenderTransitionCancel,
+}
+
+// Target returns the status a tender in status from moves to, and false when
+// the transition is not allowed from there.
+func (t TenderTransition) Target(from TenderStatus) (TenderStatus, bool) {
+	rule, ok := tenderTransitions[t]
+	if !ok {
+		return "", false
+	}
+	for _, s := range rule.from {
+		if s == from {
+			return rule.to, true
+		}
+	}
+	return "", false
+}
+
+// TenderTransitionTo returns the transition that moves a tender from one
+// status to another, and false when there is none.
+func TenderTransitionTo(from, to TenderStatus) (TenderTransition, bool) {
+	for _, t := range tenderTransitionOrder {
+		if target, ok := t.Target(from); ok && target == to {
+			return t, true
+		}
+	}
+	return "", false
+}
+
 type Tender struct {
-	ID          uuid.UUID    `json:"id" db:"id"`
-	ClientID    uuid.UUID    `json:"client_id" db:"client_id"`
-	Title       string       `json:"title" db:"title"`
-	Description string       `json:"description" db:"description"`
-	Deadline    time.Time    `json:"deadline" db:"deadline"`
-	Budget      float64      `json:"budget" db:"budget"`
-	Status      TenderStatus `json:"status" db:"status"`
-	Attachment  *string      `json:"attachment,omitempty" db:"attachment"`
-	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time    `json:"updated_at" db:"updated_at"`
+	ID             uuid.UUID    `json:"id" db:"id"`
+	ClientID       uuid.UUID    `json:"client_id" db:"client_id"`
+	OrganizationID uuid.UUID    `json:"organization_id" db:"organization_id"`
+	Title          string       `json:"title" db:"title"`
+	Description    string       `json:"description" db:"description"`
+	Deadline       time.Time    `json:"deadline" db:"deadline"`
+	Budget         float64      `json:"budget" db:"budget"`
+	Status         TenderStatus `json:"status" db:"status"`
+	Attachment     *string      `json:"attachment,omitempty" db:"attachment"`
+	// CategoryCode is the code of the category the tender is classified in.
+	CategoryCode *string `json:"category_code,omitempty" db:"category_code"`
+	// Tags are free-form lowercase labels.
+	Tags []string `json:"tags" db:"tags"`
+	// CancellationReason is set when the tender was cancelled.
+	CancellationReason *string   `json:"cancellation_reason,omitempty" db:"cancellation_reason"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
+	// Match is set on the results of a search.
+	Match *SearchMatch `json:"match,omitempty" db:"-"`
+}
+
+// TenderStatusChange is an entry of the status history of a tender. ActorID
+// is empty for changes made by the server itself.
+type TenderStatusChange struct {
+	ID         uuid.UUID        `json:"id" db:"id"`
+	TenderID   uuid.UUID        `json:"tender_id" db:"tender_id"`
+	Transition TenderTransition `json:"transition" db:"transition"`
+	FromStatus TenderStatus     `json:"from_status" db:"from_status"`
+	ToStatus   TenderStatus     `json:"to_status" db:"to_status"`
+	Reason     *string          `json:"reason,omitempty" db:"reason"`
+	ActorID    *uuid.UUID       `json:"actor_id,omitempty" db:"actor_id"`
+	CreatedAt  time.Time        `json:"created_at" db:"created_at"`
 }
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+var allTenderStatuses = []TenderStatus{
+	TenderStatusDraft,
+	TenderStatusOpen,
+	TenderStatusClosed,
+	TenderStatusEvaluating,
+	TenderStatusAwarded,
+	TenderStatusCancelled,
+}
+
+func TestTenderTransitionTarget(t *testing.T) {
+	// allowed lists every legal transition; any pair not in it must be refused.
+	allowed := map[TenderTransition]map[TenderStatus]TenderStatus{
+		TenderTransitionPublish:  {TenderStatusDraft: TenderStatusOpen},
+		TenderTransitionClose:    {TenderStatusOpen: TenderStatusClosed},
+		TenderTransitionReopen:   {TenderStatusClosed: TenderStatusOpen},
+		TenderTransitionEvaluate: {TenderStatusClosed: TenderStatusEvaluating},
+		TenderTransitionAward: {
+			TenderStatusClosed:     TenderStatusAwarded,
+			TenderStatusEvaluating: TenderStatusAwarded,
+		},
+		TenderTransitionCancel: {
+			TenderStatusDraft:      TenderStatusCancelled,
+			TenderStatusOpen:       TenderStatusCancelled,
+			TenderStatusClosed:     TenderStatusCancelled,
+			TenderStatusEvaluating: TenderStatusCancelled,
+		},
+	}
+	for transition, targets := range allowed {
+		for _, from := range allTenderStatuses {
+			want, wantOK := targets[from]
+			got, ok := transition.Target(from)
+			if got != want || ok != wantOK {
+				t.Errorf("%s.Target(%s) = %q, %v, want %q, %v", transition, from, got, ok, want, wantOK)
+			}
+		}
+	}
+	if got, ok := TenderTransition("archive").Target(TenderStatusOpen); ok {
+		t.Errorf("archive.Target(open) = %q, true, want unknown transition refused", got)
+	}
+}
+
+func TestTenderStatusTransitions(t *testing.T) {
+	tests := []struct {
+		status TenderStatus
+		want   []TenderTransition
+	}{
+		{TenderStatusDraft, []TenderTransition{TenderTransitionPublish, TenderTransitionCancel}},
+		{TenderStatusOpen, []TenderTransition{TenderTransitionClose, TenderTransitionCancel}},
+		{TenderStatusClosed, []TenderTransition{TenderTransitionReopen, TenderTransitionEvaluate, TenderTransitionAward, TenderTransitionCancel}},
+		{TenderStatusEvaluating, []TenderTransition{TenderTransitionAward, TenderTransitionCancel}},
+		{TenderStatusAwarded, []TenderTransition{}},
+		{TenderStatusCancelled, []TenderTransition{}},
+	}
+	for _, tt := range tests {
+		if got := tt.status.Transitions(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s.Transitions() = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestTenderTransitionTo(t *testing.T) {
+	tests := []struct {
+		from, to TenderStatus
+		want     TenderTransition
+		ok       bool
+	}{
+		{TenderStatusDraft, TenderStatusOpen, TenderTransitionPublish, true},
+		{TenderStatusClosed, TenderStatusOpen, TenderTransitionReopen, true},
+		{TenderStatusEvaluating, TenderStatusAwarded, TenderTransitionAward, true},
+		{TenderStatusOpen, TenderStatusCancelled, TenderTransitionCancel, true},
+		{TenderStatusOpen, TenderStatusAwarded, "", false},
+		{TenderStatusAwarded, TenderStatusCancelled, "", false},
+		{TenderStatusOpen, TenderStatusOpen, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := TenderTransitionTo(tt.from, tt.to)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("TenderTransitionTo(%s, %s) = %q, %v, want %q, %v", tt.from, tt.to, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTenderStatusGuards(t *testing.T) {
+	for _, s := range allTenderStatuses {
+		if !s.IsValid() {
+			t.Errorf("%s.IsValid() = false, want true", s)
+		}
+		want := s != TenderStatusAwarded && s != TenderStatusCancelled
+		if got := s.Editable(); got != want {
+			t.Errorf("%s.Editable() = %v, want %v", s, got, want)
+		}
+	}
+	for _, s := range []TenderStatus{"", "Open", "archived"} {
+		if s.IsValid() {
+			t.Errorf("%q.IsValid() = true, want false", s)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TwoFactor is the TOTP enrollment of a user. Secret is encrypted at rest.
+// An enrollment is pending until the user proves it works with a first code.
+type TwoFactor struct {
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	Secret    []byte     `json:"-" db:"secret"`
+	EnabledAt *time.Time `json:"enabled_at,omitempty" db:"enabled_at"`
+	// LastUsedStep is the TOTP time step of the last accepted code. Codes
+	// from that step or earlier are rejected so a code cannot be replayed.
+	LastUsedStep int64     `json:"-" db:"last_used_step"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// Enabled reports whether the enrollment was confirmed.
+func (t *TwoFactor) Enabled() bool {
+	return t.EnabledAt != nil
+}
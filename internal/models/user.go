@@ -11,14 +11,35 @@ type UserRole string
 const (
 	RoleClient     UserRole = "client"
 	RoleContractor UserRole = "contractor"
+	RoleAdmin      UserRole = "admin"
 )
 
+// Valid reports whether r is a known role.
+func (r UserRole) Valid() bool {
+	return r == RoleClient || r == RoleContractor || r == RoleAdmin
+}
+
 type User struct {
 	ID           uuid.UUID `json:"id" db:"id"`
 	Username     string    `json:"username" db:"username"`
 	Email        string    `json:"email" db:"email"`
 	PasswordHash string    `json:"-" db:"password_hash"`
 	Role         UserRole  `json:"role" db:"role"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+	// EmailVerifiedAt is nil until the user confirms their email address.
+	EmailVerifiedAt *time.Time `json:"email_verified_at" db:"email_verified_at"`
+	// SuspendedAt is set while an admin has suspended the account.
+	SuspendedAt     *time.Time `json:"suspended_at,omitempty" db:"suspended_at"`
+	SuspendedReason string     `json:"suspended_reason,omitempty" db:"suspended_reason"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Suspended reports whether the account is suspended.
+func (u *User) Suspended() bool {
+	return u.SuspendedAt != nil
+}
+
+// EmailVerified reports whether the user confirmed their email address.
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
@@ -2,4 +2,10 @@ package repository
 
 import "errors"
 
-var ErrNotFound = errors.New("not found")
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a conditional update lost a race.
+	ErrConflict = errors.New("conflict")
+	// ErrInvalidCursor is returned for a page cursor no list issued.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
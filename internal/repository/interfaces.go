@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/Dostonlv/hackathon-nt/internal/models"
 	"github.com/google/uuid"
@@ -13,56 +14,205 @@ type UserRepository interface {
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
 	ExistsByEmail(ctx context.Context, email string) (bool, error)
+	MarkEmailVerified(ctx context.Context, id uuid.UUID, at time.Time) error
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error
+	List(ctx context.Context, filters UserFilters) ([]models.User, error)
+	SetSuspended(ctx context.Context, id uuid.UUID, at *time.Time, reason string) error
+	UpdateRole(ctx context.Context, id uuid.UUID, role models.UserRole) error
 }
 
 type TenderRepository interface {
 	Create(ctx context.Context, tender *models.Tender) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Tender, error)
-	UpdateStatus(ctx context.Context, id uuid.UUID, status string) error
+	Transition(ctx context.Context, change *models.TenderStatusChange) error
+	ListStatusChanges(ctx context.Context, tenderID uuid.UUID) ([]models.TenderStatusChange, error)
+	Update(ctx context.Context, tender *models.Tender) error
+	// Delete returns ErrConflict when the tender has bids.
 	Delete(ctx context.Context, id uuid.UUID) error
-	ListByClientID(ctx context.Context, clientID uuid.UUID) ([]models.Tender, error)
-	List(ctx context.Context, filters TenderFilters) ([]models.Tender, error)
+	ListByOrganizationIDs(ctx context.Context, organizationIDs []uuid.UUID, page PageRequest) (*models.Page[models.Tender], error)
+	List(ctx context.Context, filters TenderFilters, page PageRequest) (*models.Page[models.Tender], error)
+	Facets(ctx context.Context, filters TenderFilters) (*models.TenderFacets, error)
 	GetClientIDByTenderID(ctx context.Context, tenderID uuid.UUID) (uuid.UUID, error)
+	CloseExpired(ctx context.Context, now time.Time, limit int) ([]models.Tender, error)
+	ClaimDeadlineReminders(ctx context.Context, lead time.Duration, from, to, now time.Time, limit int) ([]models.Tender, error)
+	Watch(ctx context.Context, tenderID, userID uuid.UUID, at time.Time) error
+	Unwatch(ctx context.Context, tenderID, userID uuid.UUID) error
+	ListWatcherIDs(ctx context.Context, tenderID uuid.UUID) ([]uuid.UUID, error)
 }
 
 type BidRepository interface {
 	Create(ctx context.Context, bid *models.Bid) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Bid, error)
-	ListByTenderID(ctx context.Context, tenderID uuid.UUID, filters BidFilters) ([]models.Bid, error)
-	ListByContractorID(ctx context.Context, contractorID uuid.UUID) ([]models.Bid, error)
+	ListByContractorID(ctx context.Context, contractorID uuid.UUID, page PageRequest) (*models.Page[models.Bid], error)
 	Update(ctx context.Context, bid *models.Bid) error
-	ListByClientTenderID(ctx context.Context, clientID, tenderID uuid.UUID) ([]models.Bid, error)
-	AwardBidByTenderID(ctx context.Context, clientID, tenderID, bidID uuid.UUID) error
-	DeleteByContractorID(ctx context.Context, contractorID, bidID uuid.UUID) error
+	ListByOrganizationTenderID(ctx context.Context, organizationID, tenderID uuid.UUID, page PageRequest) (*models.Page[models.Bid], error)
+	SearchByTenderID(ctx context.Context, tenderID uuid.UUID, search string, page PageRequest) (*models.Page[models.Bid], error)
+	ListByOrganizationIDs(ctx context.Context, organizationIDs []uuid.UUID, page PageRequest) (*models.Page[models.Bid], error)
+	Award(ctx context.Context, bidID uuid.UUID, change *models.TenderStatusChange) error
+	Delete(ctx context.Context, bidID uuid.UUID) error
+	ListBidderOrganizationIDs(ctx context.Context, tenderID uuid.UUID) ([]uuid.UUID, error)
+	ListBidTenderIDs(ctx context.Context, organizationIDs, tenderIDs []uuid.UUID) ([]uuid.UUID, error)
+}
+
+type CategoryRepository interface {
+	List(ctx context.Context) ([]models.Category, error)
+	GetByCode(ctx context.Context, code string) (*models.Category, error)
+}
+
+type DocumentRepository interface {
+	Create(ctx context.Context, doc *models.TenderDocument, limit int) error
+	GetByID(ctx context.Context, tenderID, id uuid.UUID) (*models.TenderDocument, error)
+	ListByTenderID(ctx context.Context, tenderID uuid.UUID) ([]models.TenderDocument, error)
+	CountByTenderID(ctx context.Context, tenderID uuid.UUID) (int, error)
+	Delete(ctx context.Context, tenderID, id uuid.UUID) error
+}
+
+type ClarificationRepository interface {
+	Create(ctx context.Context, question *models.TenderQuestion) error
+	GetByID(ctx context.Context, tenderID, id uuid.UUID) (*models.TenderQuestion, error)
+	ListByTenderID(ctx context.Context, tenderID uuid.UUID) ([]models.TenderQuestion, error)
+	ListVisible(ctx context.Context, tenderID uuid.UUID, organizationIDs []uuid.UUID) ([]models.TenderQuestion, error)
+	Answer(ctx context.Context, question *models.TenderQuestion) error
 }
 
 type NotificationRepository interface {
 	Create(ctx context.Context, notification *models.Notification) error
-	ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.Notification, error)
+	ListByUserID(ctx context.Context, userID uuid.UUID, page PageRequest) (*models.Page[models.Notification], error)
 	MarkAsRead(ctx context.Context, id uuid.UUID) error
 }
 
 type HistoryRepository interface {
-	GetTenderHistory(userID uuid.UUID) ([]models.Tender, error)
-	GetBidHistory(userID uuid.UUID) ([]models.Bid, error)
+	GetTenderHistory(userID uuid.UUID, page PageRequest) (*models.Page[models.Tender], error)
+	GetBidHistory(userID uuid.UUID, page PageRequest) (*models.Page[models.Bid], error)
+}
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldID uuid.UUID, next *models.RefreshToken) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error
+	RevokeAccessToken(ctx context.Context, jti string, ttl time.Duration) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	RevokeUserAccessTokens(ctx context.Context, userID uuid.UUID, before time.Time, ttl time.Duration) error
+	UserAccessTokensRevokedAt(ctx context.Context, userID uuid.UUID) (time.Time, error)
+}
+
+type SigningKeyRepository interface {
+	ListSigningKeys(ctx context.Context, algorithm string) ([]models.SigningKey, error)
+	CreateSigningKeyIfDue(ctx context.Context, key *models.SigningKey, dueBefore time.Time) (bool, error)
+	DeleteExpiredSigningKeys(ctx context.Context, now time.Time) error
+}
+
+type AccountTokenRepository interface {
+	CreateAccountToken(ctx context.Context, token *models.AccountToken) error
+	ConsumeAccountToken(ctx context.Context, purpose models.AccountTokenPurpose, hash string, now time.Time) (*models.AccountToken, error)
+}
+
+type TwoFactorRepository interface {
+	GetTwoFactor(ctx context.Context, userID uuid.UUID) (*models.TwoFactor, error)
+	SavePendingTwoFactor(ctx context.Context, userID uuid.UUID, secret []byte) error
+	EnableTwoFactor(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error
+	DisableTwoFactor(ctx context.Context, userID uuid.UUID) error
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error
+	CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error)
+	CreateLoginChallenge(ctx context.Context, hash string, userID uuid.UUID, ttl time.Duration) error
+	GetLoginChallenge(ctx context.Context, hash string) (uuid.UUID, error)
+	CountLoginChallengeAttempt(ctx context.Context, hash string) (int64, error)
+	DeleteLoginChallenge(ctx context.Context, hash string) error
+}
+
+// LoginAttemptRepository tracks failed logins per scope ("user" or "ip").
+type LoginAttemptRepository interface {
+	RecordLoginFailure(ctx context.Context, scope, id string, at time.Time, window time.Duration) (int64, error)
+	LoginFailures(ctx context.Context, scope, id string) (int64, time.Time, error)
+	ResetLoginFailures(ctx context.Context, scope, id string) error
+	LockLogin(ctx context.Context, scope, id string, until time.Time) error
+	LoginLockedUntil(ctx context.Context, scope, id string) (time.Time, error)
+	UnlockLogin(ctx context.Context, scope, id string) error
+}
+
+type SecurityEventRepository interface {
+	Create(ctx context.Context, event *models.SecurityEvent) error
+	List(ctx context.Context, filters SecurityEventFilters) ([]models.SecurityEvent, error)
+}
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error)
+	CountActiveAPIKeys(ctx context.Context, userID uuid.UUID, now time.Time) (int, error)
+	RevokeAPIKey(ctx context.Context, userID, id uuid.UUID, at time.Time) error
+	TouchAPIKey(ctx context.Context, id uuid.UUID, at time.Time, interval time.Duration) error
+}
+
+type OrganizationRepository interface {
+	Create(ctx context.Context, org *models.Organization, ownerID uuid.UUID) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Organization, error)
+	Update(ctx context.Context, org *models.Organization) error
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]models.Organization, error)
+	ListMembers(ctx context.Context, organizationID uuid.UUID) ([]models.Membership, error)
+	AddMember(ctx context.Context, member *models.Membership) error
+	UpdateMemberRole(ctx context.Context, organizationID, userID uuid.UUID, role models.OrgRole) error
+	RemoveMember(ctx context.Context, organizationID, userID uuid.UUID) error
+	CreateInvitation(ctx context.Context, invitation *models.OrganizationInvitation) error
+	ListInvitations(ctx context.Context, organizationID uuid.UUID, now time.Time) ([]models.OrganizationInvitation, error)
+	GetInvitationByHash(ctx context.Context, hash string) (*models.OrganizationInvitation, error)
+	DeleteInvitation(ctx context.Context, organizationID, id uuid.UUID) error
+	AcceptInvitation(ctx context.Context, id, userID uuid.UUID, now time.Time) error
+}
+
+type PolicyAuditRepository interface {
+	Create(ctx context.Context, entry *models.PolicyAudit) error
+	List(ctx context.Context, limit int) ([]models.PolicyAudit, error)
 }
 
 type TenderFilters struct {
 	Status string
 	Search string
+	// OrganizationIDs limits the list to the tenders of these organizations.
+	// Nil means every organization.
+	OrganizationIDs []uuid.UUID
+	// Category matches the tenders in a category or any of its
+	// subcategories.
+	Category string
+	Tag      string
+	// MinBudget, MaxBudget, DeadlineAfter and DeadlineBefore are inclusive
+	// bounds; nil leaves a side open.
+	MinBudget      *float64
+	MaxBudget      *float64
+	DeadlineAfter  *time.Time
+	DeadlineBefore *time.Time
+	Sort           TenderSort
+}
+
+// TenderSort is the order of a tender list.
+type TenderSort string
+
+const (
+	// TenderSortNewest lists the newest tenders first, or the best matches
+	// of a search.
+	TenderSortNewest TenderSort = ""
+	// TenderSortDeadline lists the tenders closing soonest first.
+	TenderSortDeadline TenderSort = "deadline"
+	// TenderSortDeadlineDesc lists the tenders closing latest first.
+	TenderSortDeadlineDesc TenderSort = "-deadline"
+)
+
+type SecurityEventFilters struct {
+	UserID *uuid.UUID
+	IP     string
+	Type   string
+	Limit  int
 }
 
-type BidFilters struct {
-	Status          string
-	Search          string
-	Price           *float64
-	DeliveryTime    *int
-	MinDeliveryTime *int
-	MaxDeliveryTime *int
-	MinPrice        *float64
-	MaxPrice        *float64
-	MinDelivery     *int
-	MaxDelivery     *int
-	SortBy          string
-	SortOrder       string
+type UserFilters struct {
+	// Search matches part of the username or email.
+	Search    string
+	Role      string
+	Suspended *bool
+	Limit     int
+	Offset    int
 }
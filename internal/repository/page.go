@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cursor is a position in a list ordered newest first by created_at, then
+// by id. Search results are ordered by rank before that, so their cursors
+// carry the rank too, and lists ordered by deadline carry the deadline.
+type Cursor struct {
+	Rank      *float64   `json:"r,omitempty"`
+	Deadline  *time.Time `json:"d,omitempty"`
+	CreatedAt time.Time  `json:"t"`
+	ID        uuid.UUID  `json:"i"`
+}
+
+// String encodes the cursor as an opaque URL-safe token.
+func (c Cursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseCursor decodes a token returned by Cursor.String.
+func ParseCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == uuid.Nil || c.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// PageRequest selects a page of a list.
+type PageRequest struct {
+	// Limit is the most items on the page. It must be positive; callers
+	// bound it.
+	Limit int
+	// After is the position of the last item of the previous page. Nil
+	// starts at the first item.
+	After *Cursor
+	// WithTotal also counts the items of the whole list.
+	WithTotal bool
+}
+
+// CacheKey is the part of a cache key that tells pages apart.
+func (p PageRequest) CacheKey() string {
+	key := ":limit=" + strconv.Itoa(p.Limit)
+	if p.After != nil {
+		key += ":after=" + p.After.String()
+	}
+	if p.WithTotal {
+		key += ":total"
+	}
+	return key
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 5, 17, 10, 30, 0, 123456789, time.UTC)
+	deadline := createdAt.Add(72 * time.Hour)
+	rank := 0.0607927
+	id := uuid.New()
+
+	tests := []struct {
+		name   string
+		cursor Cursor
+	}{
+		{"newest first", Cursor{CreatedAt: createdAt, ID: id}},
+		{"search rank", Cursor{Rank: &rank, CreatedAt: createdAt, ID: id}},
+		{"deadline", Cursor{Deadline: &deadline, CreatedAt: createdAt, ID: id}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.cursor.String()
+			if strings.ContainsAny(token, "+/=") {
+				t.Errorf("cursor %q is not URL-safe", token)
+			}
+			got, err := ParseCursor(token)
+			if err != nil {
+				t.Fatalf("ParseCursor(%q) = %v", token, err)
+			}
+			if !got.CreatedAt.Equal(tt.cursor.CreatedAt) || got.ID != tt.cursor.ID {
+				t.Errorf("ParseCursor = %v %v, want %v %v", got.CreatedAt, got.ID, tt.cursor.CreatedAt, tt.cursor.ID)
+			}
+			if (got.Rank == nil) != (tt.cursor.Rank == nil) || got.Rank != nil && *got.Rank != *tt.cursor.Rank {
+				t.Errorf("ParseCursor rank = %v, want %v", got.Rank, tt.cursor.Rank)
+			}
+			if (got.Deadline == nil) != (tt.cursor.Deadline == nil) || got.Deadline != nil && !got.Deadline.Equal(*tt.cursor.Deadline) {
+				t.Errorf("ParseCursor deadline = %v, want %v", got.Deadline, tt.cursor.Deadline)
+			}
+		})
+	}
+}
+
+func TestParseCursorInvalid(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"not base64", "not a cursor!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"t":"2024-05-17T10:30:00Z","i":"` + uuid.NewString() + `"}`))},
+		{"not JSON", encode("cursor")},
+		{"no ID", encode(`{"t":"2024-05-17T10:30:00Z"}`)},
+		{"no time", encode(`{"i":"` + uuid.NewString() + `"}`)},
+		{"bad ID", encode(`{"t":"2024-05-17T10:30:00Z","i":"42"}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCursor(tt.token); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("ParseCursor(%q) = %v, want ErrInvalidCursor", tt.token, err)
+			}
+		})
+	}
+}
+
+func TestPageRequestCacheKey(t *testing.T) {
+	after := &Cursor{CreatedAt: time.Now(), ID: uuid.New()}
+	keys := map[string]PageRequest{}
+	for _, page := range []PageRequest{
+		{Limit: 50},
+		{Limit: 20},
+		{Limit: 50, WithTotal: true},
+		{Limit: 50, After: after},
+		{Limit: 50, After: after, WithTotal: true},
+	} {
+		key := page.CacheKey()
+		if other, ok := keys[key]; ok {
+			t.Errorf("%+v and %+v share the cache key %q", page, other, key)
+		}
+		keys[key] = page
+	}
+}
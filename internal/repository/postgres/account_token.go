@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+)
+
+// AccountTokenRepo stores the one-time email verification and password reset
+// tokens.
+type AccountTokenRepo struct {
+	db *sql.DB
+}
+
+func NewAccountTokenRepo(db *sql.DB) *AccountTokenRepo {
+	return &AccountTokenRepo{db: db}
+}
+
+// CreateAccountToken stores token and invalidates the user's earlier unused
+// tokens with the same purpose, so only the most recent email works.
+func (r *AccountTokenRepo) CreateAccountToken(ctx context.Context, token *models.AccountToken) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE account_tokens
+		SET used_at = $3
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`
+	if _, err := tx.ExecContext(ctx, query, token.UserID, token.Purpose, token.CreatedAt); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO account_tokens (id, user_id, purpose, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err = tx.ExecContext(ctx, query,
+		token.ID,
+		token.UserID,
+		token.Purpose,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ConsumeAccountToken marks the unused, unexpired token with the given hash
+// and purpose as used and returns it. It returns repository.ErrNotFound when
+// no such token exists, so a token can only ever be consumed once.
+func (r *AccountTokenRepo) ConsumeAccountToken(ctx context.Context, purpose models.AccountTokenPurpose, hash string, now time.Time) (*models.AccountToken, error) {
+	query := `
+		UPDATE account_tokens
+		SET used_at = $3
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+		RETURNING id, user_id, purpose, token_hash, expires_at, used_at, created_at
+	`
+	var t models.AccountToken
+	err := r.db.QueryRowContext(ctx, query, hash, purpose, now).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Purpose,
+		&t.TokenHash,
+		&t.ExpiresAt,
+		&t.UsedAt,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
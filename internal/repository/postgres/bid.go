@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/Dostonlv/hackathon-nt/internal/repository"
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type BidRepo struct {
@@ -25,13 +27,14 @@ func NewBidRepo(db *sql.DB, redisClient *redis.Client) *BidRepo {
 func (r *BidRepo) Create(ctx context.Context, bid *models.Bid) error {
 	query := `
 		INSERT INTO bids (
-			id, tender_id, contractor_id, price, delivery_time, comments, status, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			id, tender_id, contractor_id, organization_id, price, delivery_time, comments, status, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
 	_, err := r.db.ExecContext(ctx, query,
 		bid.ID,
 		bid.TenderID,
 		bid.ContractorID,
+		bid.OrganizationID,
 		bid.Price,
 		bid.DeliveryTime,
 		bid.Comments,
@@ -39,12 +42,17 @@ func (r *BidRepo) Create(ctx context.Context, bid *models.Bid) error {
 		bid.CreatedAt,
 		bid.UpdatedAt,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	invalidateBidCaches(ctx, r.redis, bid.TenderID, bid.ContractorID)
+	return nil
 }
 
 func (r *BidRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Bid, error) {
 	query := `
-		SELECT id, tender_id, contractor_id, price, delivery_time, comments, status, created_at, updated_at
+		SELECT id, tender_id, contractor_id, organization_id, price, delivery_time, comments, status, created_at, updated_at
 		FROM bids
 		WHERE id = $1
 	`
@@ -55,6 +63,7 @@ func (r *BidRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Bid, error
 		&b.ID,
 		&b.TenderID,
 		&b.ContractorID,
+		&b.OrganizationID,
 		&b.Price,
 		&b.DeliveryTime,
 		&b.Comments,
@@ -71,63 +80,46 @@ func (r *BidRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Bid, error
 	return &b, nil
 }
 
-func (r *BidRepo) ListByTenderIDWithFilters(ctx context.Context, tenderID uuid.UUID, filters repository.BidFilters) ([]models.Bid, error) {
+// ListByContractorID returns a page of the bids a contractor placed, newest
+// first.
+func (r *BidRepo) ListByContractorID(ctx context.Context, contractorID uuid.UUID, page repository.PageRequest) (*models.Page[models.Bid], error) {
 	// Check if the data is available in the cache
-	cacheKey := fmt.Sprintf("bids:tender:%s:price:%s:delivery_time:%s", tenderID.String(), filters.Price, filters.DeliveryTime)
+	cacheKey := fmt.Sprintf("bids:contractor:%s", contractorID.String()) + page.CacheKey()
 	cachedData, err := r.redis.Get(ctx, cacheKey).Result()
 	if err == nil {
 		// Data found in cache, unmarshal and return
-		var bids []models.Bid
-		err = json.Unmarshal([]byte(cachedData), &bids)
+		var cached models.Page[models.Bid]
+		err = json.Unmarshal([]byte(cachedData), &cached)
 		if err != nil {
 			return nil, err
 		}
-		return bids, nil
+		return &cached, nil
 	} else if err != redis.Nil {
 		// Error occurred while accessing cache
 		return nil, err
 	}
-	query := `
-		SELECT id, tender_id, contractor_id, price, delivery_time, comments, status, created_at, updated_at
+
+	// Data not found in cache, fetch from database
+	createdAt, id, limit := pageArgs(page)
+	bids, err := r.query(ctx, `
+		SELECT id, tender_id, contractor_id, organization_id, price, delivery_time, comments, status, created_at, updated_at
 		FROM bids
-		WHERE tender_id = $1
-		AND ($2::float8 IS NULL OR price >= $2)
-		AND ($3::float8 IS NULL OR price <= $3)
-		AND ($4::interval IS NULL OR delivery_time >= $4)
-		AND ($5::interval IS NULL OR delivery_time <= $5)
-	`
-	if filters.SortBy != "" {
-		query += " ORDER BY " + filters.SortBy
-		if filters.SortOrder == "desc" {
-			query += " DESC"
-		}
-	}
-	rows, err := r.db.QueryContext(ctx, query, tenderID, filters.MinPrice, filters.MaxPrice, filters.MinDeliveryTime, filters.MaxDeliveryTime)
+		WHERE contractor_id = $1
+		AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
+	`, contractorID, createdAt, id, limit)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	var bids []models.Bid
-	for rows.Next() {
-		var b models.Bid
-		err := rows.Scan(
-			&b.ID,
-			&b.TenderID,
-			&b.ContractorID,
-			&b.Price,
-			&b.DeliveryTime,
-			&b.Comments,
-			&b.Status,
-			&b.CreatedAt,
-			&b.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		bids = append(bids, b)
+	result := newPage(bids, page, bidCursor)
+	err = countTotal(ctx, r.db, result, page, `SELECT COUNT(*) FROM bids WHERE contractor_id = $1`, contractorID)
+	if err != nil {
+		return nil, err
 	}
+
 	// Store the fetched data in cache
-	dataBytes, err := json.Marshal(bids)
+	dataBytes, err := json.Marshal(result)
 	if err != nil {
 		return nil, err
 	}
@@ -135,104 +127,110 @@ func (r *BidRepo) ListByTenderIDWithFilters(ctx context.Context, tenderID uuid.U
 	if err != nil {
 		return nil, err
 	}
-	return bids, nil
-}
 
-func (r *BidRepo) ListByTenderID(ctx context.Context, tenderID uuid.UUID, filters repository.BidFilters) ([]models.Bid, error) {
-	// Check if the data is available in the cache
-	cacheKey := fmt.Sprintf("bids:tender:%s", tenderID.String())
-	cachedData, err := r.redis.Get(ctx, cacheKey).Result()
-	if err == nil {
-		// Data found in cache, unmarshal and return
-		var bids []models.Bid
-		err = json.Unmarshal([]byte(cachedData), &bids)
-		if err != nil {
-			return nil, err
-		}
-		return bids, nil
-	} else if err != redis.Nil {
-		// Error occurred while accessing cache
-		return nil, err
-	}
+	return result, nil
+}
 
+func (r *BidRepo) Update(ctx context.Context, bid *models.Bid) error {
 	query := `
-		SELECT id, tender_id, contractor_id, price, delivery_time, comments, status, created_at, updated_at
-		FROM bids
-		WHERE tender_id = $1
-		AND ($2::float8 IS NULL OR price >= $2)
-		AND ($3::float8 IS NULL OR price <= $3)
+		UPDATE bids
+		SET tender_id = $2, contractor_id = $3, price = $4, delivery_time = $5, comments = $6, status = $7, updated_at = $8
+		WHERE id = $1
 	`
-
-	if filters.SortBy != "" {
-		query += " ORDER BY " + filters.SortBy
-		if filters.SortOrder == "desc" {
-			query += " DESC"
-		}
+	_, err := r.db.ExecContext(ctx, query,
+		bid.ID,
+		bid.TenderID,
+		bid.ContractorID,
+		bid.Price,
+		bid.DeliveryTime,
+		bid.Comments,
+		bid.Status,
+		bid.UpdatedAt,
+	)
+	if err != nil {
+		return err
 	}
 
-	rows, err := r.db.QueryContext(ctx, query, tenderID, filters.MinPrice, filters.MaxPrice)
+	invalidateBidCaches(ctx, r.redis, bid.TenderID, bid.ContractorID)
+	return nil
+}
+
+// ListByOrganizationTenderID returns a page of the bids on a tender of the
+// client organization, newest first.
+func (r *BidRepo) ListByOrganizationTenderID(ctx context.Context, organizationID, tenderID uuid.UUID, page repository.PageRequest) (*models.Page[models.Bid], error) {
+	conditions := `
+		b.tender_id = $2
+		AND EXISTS (SELECT 1 FROM tenders t WHERE t.id = b.tender_id AND t.organization_id = $1)
+	`
+	createdAt, id, limit := pageArgs(page)
+	bids, err := r.query(ctx, `
+		SELECT b.id, b.tender_id, b.contractor_id, b.organization_id, b.price, b.delivery_time, b.comments, b.status, b.created_at, b.updated_at
+		FROM bids b
+		WHERE `+conditions+`
+		AND ($3::timestamptz IS NULL OR (b.created_at, b.id) < ($3, $4::uuid))
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT $5
+	`, organizationID, tenderID, createdAt, id, limit)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var bids []models.Bid
-	for rows.Next() {
-		var b models.Bid
-		err := rows.Scan(
-			&b.ID,
-			&b.TenderID,
-			&b.ContractorID,
-			&b.Price,
-			&b.DeliveryTime,
-			&b.Comments,
-			&b.Status,
-			&b.CreatedAt,
-			&b.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		bids = append(bids, b)
+	result := newPage(bids, page, bidCursor)
+	err = countTotal(ctx, r.db, result, page, `SELECT COUNT(*) FROM bids b WHERE `+conditions, organizationID, tenderID)
+	if err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	// Store the fetched data in cache
-	dataBytes, err := json.Marshal(bids)
+// ListByOrganizationIDs returns a page of the bids of the given contractor
+// organizations, newest first.
+func (r *BidRepo) ListByOrganizationIDs(ctx context.Context, organizationIDs []uuid.UUID, page repository.PageRequest) (*models.Page[models.Bid], error) {
+	ids := pq.Array(uuidStrings(organizationIDs))
+	createdAt, id, limit := pageArgs(page)
+	bids, err := r.query(ctx, `
+		SELECT id, tender_id, contractor_id, organization_id, price, delivery_time, comments, status, created_at, updated_at
+		FROM bids
+		WHERE organization_id = ANY($1)
+		AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
+	`, ids, createdAt, id, limit)
 	if err != nil {
 		return nil, err
 	}
-	err = r.redis.Set(ctx, cacheKey, dataBytes, time.Hour).Err()
+	result := newPage(bids, page, bidCursor)
+	err = countTotal(ctx, r.db, result, page, `SELECT COUNT(*) FROM bids WHERE organization_id = ANY($1)`, ids)
 	if err != nil {
 		return nil, err
 	}
-
-	return bids, nil
+	return result, nil
 }
 
-func (r *BidRepo) ListByContractorID(ctx context.Context, contractorID uuid.UUID) ([]models.Bid, error) {
-	// Check if the data is available in the cache
-	cacheKey := fmt.Sprintf("bids:contractor:%s", contractorID.String())
-	cachedData, err := r.redis.Get(ctx, cacheKey).Result()
-	if err == nil {
-		// Data found in cache, unmarshal and return
-		var bids []models.Bid
-		err = json.Unmarshal([]byte(cachedData), &bids)
-		if err != nil {
-			return nil, err
+// SearchByTenderID returns a page of the bids on a tender whose comments
+// match a web search style query, best match first, with highlighted
+// snippets. Their cursors carry the rank.
+func (r *BidRepo) SearchByTenderID(ctx context.Context, tenderID uuid.UUID, search string, page repository.PageRequest) (*models.Page[models.Bid], error) {
+	var rank interface{}
+	if page.After != nil {
+		if page.After.Rank == nil {
+			return nil, repository.ErrInvalidCursor
 		}
-		return bids, nil
-	} else if err != redis.Nil {
-		// Error occurred while accessing cache
-		return nil, err
+		rank = *page.After.Rank
 	}
-
-	// Data not found in cache, fetch from database
+	createdAt, id, limit := pageArgs(page)
 	query := `
-		SELECT id, tender_id, contractor_id, price, delivery_time, comments, status, created_at, updated_at
-		FROM bids
-		WHERE contractor_id = $1
+		SELECT id, tender_id, contractor_id, organization_id, price, delivery_time, comments, status, created_at, updated_at, rank,
+			ts_headline(search_language(), comments, query, $3)
+		FROM (
+			SELECT b.*, q.query, ts_rank_cd(b.search_vector, q.query) AS rank
+			FROM bids b, websearch_to_tsquery(search_language(), $2) AS q(query)
+			WHERE b.tender_id = $1 AND b.search_vector @@ q.query
+		) AS matches
+		WHERE ($4::timestamptz IS NULL OR (rank, created_at, id) < ($6::real, $4, $5::uuid))
+		ORDER BY rank DESC, created_at DESC, id DESC
+		LIMIT $7
 	`
-	rows, err := r.db.QueryContext(ctx, query, contractorID)
+	rows, err := r.db.QueryContext(ctx, query, tenderID, search, snippetOptions, createdAt, id, rank, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -241,63 +239,67 @@ func (r *BidRepo) ListByContractorID(ctx context.Context, contractorID uuid.UUID
 	var bids []models.Bid
 	for rows.Next() {
 		var b models.Bid
+		var m models.SearchMatch
 		err := rows.Scan(
 			&b.ID,
 			&b.TenderID,
 			&b.ContractorID,
+			&b.OrganizationID,
 			&b.Price,
 			&b.DeliveryTime,
 			&b.Comments,
 			&b.Status,
 			&b.CreatedAt,
 			&b.UpdatedAt,
+			&m.Rank,
+			&m.Snippet,
 		)
 		if err != nil {
 			return nil, err
 		}
+		m.Snippet = highlight(m.Snippet)
+		b.Match = &m
 		bids = append(bids, b)
 	}
-
-	// Store the fetched data in cache
-	dataBytes, err := json.Marshal(bids)
-	if err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	err = r.redis.Set(ctx, cacheKey, dataBytes, time.Hour).Err()
+
+	result := newPage(bids, page, bidCursor)
+	err = countTotal(ctx, r.db, result, page, `
+		SELECT COUNT(*) FROM bids
+		WHERE tender_id = $1 AND search_vector @@ websearch_to_tsquery(search_language(), $2)
+	`, tenderID, search)
 	if err != nil {
 		return nil, err
 	}
+	return result, nil
+}
 
-	return bids, nil
+// bidCursor is the position of a bid in a list, or in search results when it
+// has a Match.
+func bidCursor(b *models.Bid) repository.Cursor {
+	c := repository.Cursor{CreatedAt: b.CreatedAt, ID: b.ID}
+	if b.Match != nil {
+		rank := b.Match.Rank
+		c.Rank = &rank
+	}
+	return c
 }
 
-func (r *BidRepo) Update(ctx context.Context, bid *models.Bid) error {
-	query := `
-		UPDATE bids
-		SET tender_id = $2, contractor_id = $3, price = $4, delivery_time = $5, comments = $6, status = $7, updated_at = $8
-		WHERE id = $1
-	`
-	_, err := r.db.ExecContext(ctx, query,
-		bid.ID,
-		bid.TenderID,
-		bid.ContractorID,
-		bid.Price,
-		bid.DeliveryTime,
-		bid.Comments,
-		bid.Status,
-		bid.UpdatedAt,
-	)
-	return err
+// invalidateBidCaches drops every cached page of the bids on a tender and
+// of the bids of a contractor.
+func invalidateBidCaches(ctx context.Context, redisClient *redis.Client, tenderID, contractorID uuid.UUID) {
+	for _, pattern := range []string{"bids:tender:" + tenderID.String() + "*", "bids:contractor:" + contractorID.String() + "*"} {
+		iter := redisClient.Scan(ctx, 0, pattern, 0).Iterator()
+		for iter.Next(ctx) {
+			redisClient.Del(ctx, iter.Val())
+		}
+	}
 }
 
-func (r *BidRepo) ListByClientTenderID(ctx context.Context, clientID, tenderID uuid.UUID) ([]models.Bid, error) {
-	query := `
-		SELECT b.id, b.tender_id, b.contractor_id, b.price, b.delivery_time, b.comments, b.status, b.created_at, b.updated_at
-		FROM bids b
-		INNER JOIN tenders t ON b.tender_id = t.id
-		WHERE t.client_id = $1 AND b.tender_id = $2
-	`
-	rows, err := r.db.QueryContext(ctx, query, clientID, tenderID)
+func (r *BidRepo) query(ctx context.Context, query string, args ...interface{}) ([]models.Bid, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -310,6 +312,7 @@ func (r *BidRepo) ListByClientTenderID(ctx context.Context, clientID, tenderID u
 			&b.ID,
 			&b.TenderID,
 			&b.ContractorID,
+			&b.OrganizationID,
 			&b.Price,
 			&b.DeliveryTime,
 			&b.Comments,
@@ -322,83 +325,101 @@ func (r *BidRepo) ListByClientTenderID(ctx context.Context, clientID, tenderID u
 		}
 		bids = append(bids, b)
 	}
-	return bids, nil
+	return bids, rows.Err()
 }
 
-func (r *BidRepo) AwardBidByTenderID(ctx context.Context, clientID, tenderID, bidID uuid.UUID) error {
-	// Check if the tender belongs to the client
-	var existingClientID uuid.UUID
-	query := `
-		SELECT client_id
-		FROM tenders
-		WHERE id = $1
-	`
-	err := r.db.QueryRowContext(ctx, query, tenderID).Scan(&existingClientID)
+// Award marks a bid as awarded and moves its tender to awarded in one
+// transaction, recording change in the tender's status history. It returns
+// repository.ErrNotFound when the bid is not on change.TenderID and
+// repository.ErrConflict when the tender is no longer in change.FromStatus.
+// Callers check that the caller may award the tender.
+func (r *BidRepo) Award(ctx context.Context, bidID uuid.UUID, change *models.TenderStatusChange) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("tender not found")
-		}
 		return err
 	}
+	defer tx.Rollback()
 
-	if existingClientID != clientID {
-		return fmt.Errorf("unauthorized: client does not own the tender")
+	if err := transitionTender(ctx, tx, change); err != nil {
+		return err
 	}
-
-	// Check if the bid belongs to the tender
-	var existingTenderID uuid.UUID
-	query = `
-		SELECT tender_id
-		FROM bids
-		WHERE id = $1
-	`
-	err = r.db.QueryRowContext(ctx, query, bidID).Scan(&existingTenderID)
+	var contractorID uuid.UUID
+	err = tx.QueryRowContext(ctx, `
+		UPDATE bids
+		SET status = 'awarded', updated_at = $3
+		WHERE id = $1 AND tender_id = $2
+		RETURNING contractor_id
+	`, bidID, change.TenderID, change.CreatedAt).Scan(&contractorID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("bid not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrNotFound
 		}
 		return err
 	}
-
-	if existingTenderID != tenderID {
-		return fmt.Errorf("unauthorized: bid does not belong to the tender")
+	if err := tx.Commit(); err != nil {
+		return err
 	}
 
-	// Award the bid
-	query = `
-		UPDATE bids
-		SET status = 'awarded'
-		WHERE id = $1 AND tender_id = $2
-	`
-	_, err = r.db.ExecContext(ctx, query, bidID, tenderID)
-	return err
+	invalidateTenderCaches(ctx, r.redis, change.TenderID)
+	invalidateBidCaches(ctx, r.redis, change.TenderID, contractorID)
+	return nil
 }
 
-func (r *BidRepo) DeleteByContractorID(ctx context.Context, contractorID, bidID uuid.UUID) error {
-	// Check if the bid belongs to the contractor
-	var existingContractorID uuid.UUID
-	query := `
-		SELECT contractor_id
-		FROM bids
-		WHERE id = $1
-	`
-	err := r.db.QueryRowContext(ctx, query, bidID).Scan(&existingContractorID)
+// Delete deletes a bid. Callers check that the caller may withdraw it.
+func (r *BidRepo) Delete(ctx context.Context, bidID uuid.UUID) error {
+	var tenderID, contractorID uuid.UUID
+	query := `DELETE FROM bids WHERE id = $1 RETURNING tender_id, contractor_id`
+	err := r.db.QueryRowContext(ctx, query, bidID).Scan(&tenderID, &contractorID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("bid not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrNotFound
 		}
 		return err
 	}
 
-	if existingContractorID != contractorID {
-		return fmt.Errorf("unauthorized: contractor does not own the bid")
+	invalidateBidCaches(ctx, r.redis, tenderID, contractorID)
+	return nil
+}
+
+// ListBidderOrganizationIDs returns the organizations that bid on a tender.
+// It bypasses the cache so that no bidder is missed.
+func (r *BidRepo) ListBidderOrganizationIDs(ctx context.Context, tenderID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT organization_id FROM bids WHERE tender_id = $1`, tenderID)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Delete the bid
-	query = `
-		DELETE FROM bids
-		WHERE id = $1 AND contractor_id = $2
-	`
-	_, err = r.db.ExecContext(ctx, query, bidID, contractorID)
-	return err
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ListBidTenderIDs returns which of the given tenders the given organizations
+// have bid on.
+func (r *BidRepo) ListBidTenderIDs(ctx context.Context, organizationIDs, tenderIDs []uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT tender_id FROM bids
+		WHERE organization_id = ANY($1) AND tender_id = ANY($2)
+	`, pq.Array(uuidStrings(organizationIDs)), pq.Array(uuidStrings(tenderIDs)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
 }
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// casbinRuleFields is the number of value columns (v0 to v5) of casbin_rules.
+const casbinRuleFields = 6
+
+// CasbinAdapter stores casbin policies in the casbin_rules table. It
+// implements persist.Adapter including auto-save, so policy changes made
+// through the enforcer are written straight to Postgres.
+type CasbinAdapter struct {
+	db *sql.DB
+}
+
+func NewCasbinAdapter(db *sql.DB) *CasbinAdapter {
+	return &CasbinAdapter{db: db}
+}
+
+var _ persist.Adapter = (*CasbinAdapter)(nil)
+
+// LoadPolicy loads every rule into m.
+func (a *CasbinAdapter) LoadPolicy(m model.Model) error {
+	rows, err := a.db.Query(`SELECT ptype, v0, v1, v2, v3, v4, v5 FROM casbin_rules ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			ptype  string
+			values [casbinRuleFields]string
+		)
+		if err := rows.Scan(&ptype, &values[0], &values[1], &values[2], &values[3], &values[4], &values[5]); err != nil {
+			return err
+		}
+		rule := values[:]
+		for len(rule) > 0 && rule[len(rule)-1] == "" {
+			rule = rule[:len(rule)-1]
+		}
+		if err := persist.LoadPolicyArray(append([]string{ptype}, rule...), m); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// SavePolicy replaces every stored rule with the rules of m.
+func (a *CasbinAdapter) SavePolicy(m model.Model) error {
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM casbin_rules`); err != nil {
+		return err
+	}
+	if err := insertModelRules(context.Background(), tx, m); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Seed stores the rules of m when the table is empty and reports whether it
+// did. It is used to import the policy file on first start; replicas
+// starting together seed only once.
+func (a *CasbinAdapter) Seed(ctx context.Context, m model.Model) (bool, error) {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE casbin_rules IN EXCLUSIVE MODE`); err != nil {
+		return false, err
+	}
+	var empty bool
+	if err := tx.QueryRowContext(ctx, `SELECT NOT EXISTS (SELECT 1 FROM casbin_rules)`).Scan(&empty); err != nil {
+		return false, err
+	}
+	if !empty {
+		return false, nil
+	}
+	if err := insertModelRules(ctx, tx, m); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (a *CasbinAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return insertRule(context.Background(), a.db, ptype, rule)
+}
+
+func (a *CasbinAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	values, err := ruleValues(rule)
+	if err != nil {
+		return err
+	}
+	query := `
+		DELETE FROM casbin_rules
+		WHERE ptype = $1 AND v0 = $2 AND v1 = $3 AND v2 = $4 AND v3 = $5 AND v4 = $6 AND v5 = $7
+	`
+	_, err = a.db.Exec(query, ptype, values[0], values[1], values[2], values[3], values[4], values[5])
+	return err
+}
+
+// RemoveFilteredPolicy removes the rules whose values starting at fieldIndex
+// equal fieldValues. Empty values match anything.
+func (a *CasbinAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	if fieldIndex < 0 || fieldIndex+len(fieldValues) > casbinRuleFields {
+		return fmt.Errorf("invalid policy filter at field %d with %d values", fieldIndex, len(fieldValues))
+	}
+	conditions := []string{"ptype = $1"}
+	args := []interface{}{ptype}
+	for i, value := range fieldValues {
+		if value == "" {
+			continue
+		}
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf("v%d = $%d", fieldIndex+i, len(args)))
+	}
+	_, err := a.db.Exec(`DELETE FROM casbin_rules WHERE `+strings.Join(conditions, " AND "), args...)
+	return err
+}
+
+func insertModelRules(ctx context.Context, db execer, m model.Model) error {
+	for _, sec := range []string{"p", "g"} {
+		for ptype, ast := range m[sec] {
+			for _, rule := range ast.Policy {
+				if err := insertRule(ctx, db, ptype, rule); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func insertRule(ctx context.Context, db execer, ptype string, rule []string) error {
+	values, err := ruleValues(rule)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO casbin_rules (ptype, v0, v1, v2, v3, v4, v5)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT DO NOTHING
+	`
+	_, err = db.ExecContext(ctx, query, ptype, values[0], values[1], values[2], values[3], values[4], values[5])
+	return err
+}
+
+func ruleValues(rule []string) ([casbinRuleFields]string, error) {
+	var values [casbinRuleFields]string
+	if len(rule) > casbinRuleFields {
+		return values, fmt.Errorf("policy rule has %d values, at most %d are supported", len(rule), casbinRuleFields)
+	}
+	copy(values[:], rule)
+	return values, nil
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+)
+
+type CategoryRepo struct {
+	db *sql.DB
+}
+
+func NewCategoryRepo(db *sql.DB) *CategoryRepo {
+	return &CategoryRepo{db: db}
+}
+
+// List returns every category ordered by code, which puts each category
+// right before its subcategories.
+func (r *CategoryRepo) List(ctx context.Context) ([]models.Category, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT code, parent_code, name FROM categories ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		var c models.Category
+		if err := rows.Scan(&c.Code, &c.ParentCode, &c.Name); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+func (r *CategoryRepo) GetByCode(ctx context.Context, code string) (*models.Category, error) {
+	var c models.Category
+	err := r.db.QueryRowContext(ctx, `SELECT code, parent_code, name FROM categories WHERE code = $1`, code).
+		Scan(&c.Code, &c.ParentCode, &c.Name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type ClarificationRepo struct {
+	db *sql.DB
+}
+
+func NewClarificationRepo(db *sql.DB) *ClarificationRepo {
+	return &ClarificationRepo{db: db}
+}
+
+const questionColumns = `id, tender_id, asked_by, organization_id, question, answer, visibility, answered_by, answered_at, created_at`
+
+func scanQuestion(row rowScanner) (*models.TenderQuestion, error) {
+	var q models.TenderQuestion
+	err := row.Scan(&q.ID, &q.TenderID, &q.AskedBy, &q.OrganizationID, &q.Question, &q.Answer, &q.Visibility, &q.AnsweredBy, &q.AnsweredAt, &q.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+func (r *ClarificationRepo) Create(ctx context.Context, q *models.TenderQuestion) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO tender_questions (id, tender_id, asked_by, organization_id, question, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`,
+		q.ID,
+		q.TenderID,
+		q.AskedBy,
+		q.OrganizationID,
+		q.Question,
+		q.CreatedAt,
+	)
+	return err
+}
+
+// GetByID returns a question about a tender.
+func (r *ClarificationRepo) GetByID(ctx context.Context, tenderID, id uuid.UUID) (*models.TenderQuestion, error) {
+	query := `SELECT ` + questionColumns + ` FROM tender_questions WHERE id = $1 AND tender_id = $2`
+	q, err := scanQuestion(r.db.QueryRowContext(ctx, query, id, tenderID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return q, nil
+}
+
+// ListByTenderID returns every question about a tender, oldest first.
+func (r *ClarificationRepo) ListByTenderID(ctx context.Context, tenderID uuid.UUID) ([]models.TenderQuestion, error) {
+	return r.list(ctx, `
+		SELECT `+questionColumns+`
+		FROM tender_questions
+		WHERE tender_id = $1
+		ORDER BY created_at, id
+	`, tenderID)
+}
+
+// ListVisible returns the questions about a tender that the given
+// organizations may see, oldest first: the ones with a public answer and
+// their own.
+func (r *ClarificationRepo) ListVisible(ctx context.Context, tenderID uuid.UUID, organizationIDs []uuid.UUID) ([]models.TenderQuestion, error) {
+	return r.list(ctx, `
+		SELECT `+questionColumns+`
+		FROM tender_questions
+		WHERE tender_id = $1
+		AND (visibility = 'public' OR organization_id = ANY($2))
+		ORDER BY created_at, id
+	`, tenderID, pq.Array(uuidStrings(organizationIDs)))
+}
+
+// Answer stores the answer of a question. It returns repository.ErrConflict
+// when the question has been answered already.
+func (r *ClarificationRepo) Answer(ctx context.Context, q *models.TenderQuestion) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE tender_questions
+		SET answer = $1, visibility = $2, answered_by = $3, answered_at = $4
+		WHERE id = $5 AND tender_id = $6 AND answer IS NULL
+	`,
+		q.Answer,
+		q.Visibility,
+		q.AnsweredBy,
+		q.AnsweredAt,
+		q.ID,
+		q.TenderID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := requireAffected(res); !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	var exists bool
+	err = r.db.QueryRowContext(ctx, `SELECT true FROM tender_questions WHERE id = $1 AND tender_id = $2`, q.ID, q.TenderID).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrNotFound
+		}
+		return err
+	}
+	return repository.ErrConflict
+}
+
+func (r *ClarificationRepo) list(ctx context.Context, query string, args ...interface{}) ([]models.TenderQuestion, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	questions := []models.TenderQuestion{}
+	for rows.Next() {
+		q, err := scanQuestion(rows)
+		if err != nil {
+			return nil, err
+		}
+		questions = append(questions, *q)
+	}
+	return questions, rows.Err()
+}
@@ -8,20 +8,8 @@ import (
 	_ "github.com/lib/pq"
 )
 
-
-
-func NewConnection(config config.Config) (*sql.DB, error) {
-	dsn := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		config.Host,
-		config.Port,
-		config.User,
-		config.Password,
-		config.DBName,
-		config.SSLMode,
-	)
-
-	db, err := sql.Open("postgres", dsn)
+func NewConnection(config config.DatabaseConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", config.DSN())
 	if err != nil {
 		return nil, fmt.Errorf("error opening database: %w", err)
 	}
@@ -31,8 +19,8 @@ func NewConnection(config config.Config) (*sql.DB, error) {
 	}
 
 	// Set connection pool settings
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(10)
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
 
 	return db, nil
 }
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/google/uuid"
+)
+
+type DocumentRepo struct {
+	db *sql.DB
+}
+
+func NewDocumentRepo(db *sql.DB) *DocumentRepo {
+	return &DocumentRepo{db: db}
+}
+
+const documentColumns = `id, tender_id, filename, content_type, size, sha256, storage_key, uploaded_by, created_at`
+
+func scanDocument(row rowScanner) (*models.TenderDocument, error) {
+	var d models.TenderDocument
+	err := row.Scan(&d.ID, &d.TenderID, &d.Filename, &d.ContentType, &d.Size, &d.SHA256, &d.StorageKey, &d.UploadedBy, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Create stores the metadata of a document unless its tender already has
+// limit documents, in which case it returns repository.ErrConflict. The
+// tender row is locked so concurrent uploads cannot exceed the limit.
+func (r *DocumentRepo) Create(ctx context.Context, doc *models.TenderDocument, limit int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT true FROM tenders WHERE id = $1 FOR UPDATE`, doc.TenderID).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrNotFound
+		}
+		return err
+	}
+	var count int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM tender_documents WHERE tender_id = $1`, doc.TenderID).Scan(&count); err != nil {
+		return err
+	}
+	if count >= limit {
+		return repository.ErrConflict
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO tender_documents (`+documentColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`,
+		doc.ID,
+		doc.TenderID,
+		doc.Filename,
+		doc.ContentType,
+		doc.Size,
+		doc.SHA256,
+		doc.StorageKey,
+		doc.UploadedBy,
+		doc.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetByID returns a document of a tender.
+func (r *DocumentRepo) GetByID(ctx context.Context, tenderID, id uuid.UUID) (*models.TenderDocument, error) {
+	query := `SELECT ` + documentColumns + ` FROM tender_documents WHERE id = $1 AND tender_id = $2`
+	doc, err := scanDocument(r.db.QueryRowContext(ctx, query, id, tenderID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return doc, nil
+}
+
+// ListByTenderID returns the documents of a tender, oldest first.
+func (r *DocumentRepo) ListByTenderID(ctx context.Context, tenderID uuid.UUID) ([]models.TenderDocument, error) {
+	query := `SELECT ` + documentColumns + ` FROM tender_documents WHERE tender_id = $1 ORDER BY created_at, id`
+	rows, err := r.db.QueryContext(ctx, query, tenderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	docs := []models.TenderDocument{}
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, *doc)
+	}
+	return docs, rows.Err()
+}
+
+func (r *DocumentRepo) CountByTenderID(ctx context.Context, tenderID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM tender_documents WHERE tender_id = $1`, tenderID).Scan(&count)
+	return count, err
+}
+
+func (r *DocumentRepo) Delete(ctx context.Context, tenderID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM tender_documents WHERE id = $1 AND tender_id = $2`, id, tenderID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
@@ -1,10 +1,12 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
 	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
 	"github.com/google/uuid"
 )
 
@@ -20,16 +22,20 @@ func NewHistoryRepo(db *sql.DB) *HistoryRepo {
 	}
 }
 
-// GetTenderHistory retrieves the tender history for a specific user.
-func (h *HistoryRepo) GetTenderHistory(userID uuid.UUID) ([]models.Tender, error) {
+// GetTenderHistory retrieves a page of the tender history for a specific
+// user.
+func (h *HistoryRepo) GetTenderHistory(userID uuid.UUID, page repository.PageRequest) (*models.Page[models.Tender], error) {
 	query := `
-		SELECT t.id, t.title, t.description, t.status, t.created_at, t.updated_at
+		SELECT ` + tenderColumns + `
 		FROM tenders t
-		INNER JOIN users u ON t.user_id = u.id
-		WHERE u.id = $1
+		WHERE t.client_id = $1
+		AND ($2::timestamptz IS NULL OR (t.created_at, t.id) < ($2, $3::uuid))
+		ORDER BY t.created_at DESC, t.id DESC
+		LIMIT $4
 	`
 
-	rows, err := h.db.Query(query, userID)
+	createdAt, id, limit := pageArgs(page)
+	rows, err := h.db.Query(query, userID, createdAt, id, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %v", err)
 	}
@@ -37,36 +43,39 @@ func (h *HistoryRepo) GetTenderHistory(userID uuid.UUID) ([]models.Tender, error
 
 	var tenders []models.Tender
 	for rows.Next() {
-		var tender models.Tender
-		err := rows.Scan(
-			&tender.ID,
-			&tender.Title,
-			&tender.Description,
-			&tender.Status,
-			&tender.CreatedAt,
-			&tender.UpdatedAt,
-		)
+		tender, err := scanTender(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %v", err)
 		}
 
-		tenders = append(tenders, tender)
+		tenders = append(tenders, *tender)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %v", err)
 	}
 
-	return tenders, nil
-
+	result := newPage(tenders, page, tenderCursor)
+	err = countTotal(context.Background(), h.db, result, page, `SELECT COUNT(*) FROM tenders WHERE client_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rows: %v", err)
+	}
+	return result, nil
 }
 
-// GetBidHistory retrieves the bid history for a specific contractor.
-func (h *HistoryRepo) GetBidHistory(userID uuid.UUID) ([]models.Bid, error) {
+// GetBidHistory retrieves a page of the bid history for a specific
+// contractor.
+func (h *HistoryRepo) GetBidHistory(userID uuid.UUID, page repository.PageRequest) (*models.Page[models.Bid], error) {
 	query := `
-		SELECT b.id, b.tender_id, b.contractor_id, b.price, b.status, b.created_at, b.updated_at
+		SELECT b.id, b.tender_id, b.contractor_id, b.organization_id, b.price, b.delivery_time, b.comments, b.status, b.created_at, b.updated_at
 		FROM bids b
-		INNER JOIN contractors c ON b.contractor_id = c.id
-		WHERE c.id = $1
+		WHERE b.contractor_id = $1
+		AND ($2::timestamptz IS NULL OR (b.created_at, b.id) < ($2, $3::uuid))
+		ORDER BY b.created_at DESC, b.id DESC
+		LIMIT $4
 	`
 
-	rows, err := h.db.Query(query, userID)
+	createdAt, id, limit := pageArgs(page)
+	rows, err := h.db.Query(query, userID, createdAt, id, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %v", err)
 	}
@@ -79,7 +88,10 @@ func (h *HistoryRepo) GetBidHistory(userID uuid.UUID) ([]models.Bid, error) {
 			&bid.ID,
 			&bid.TenderID,
 			&bid.ContractorID,
+			&bid.OrganizationID,
 			&bid.Price,
+			&bid.DeliveryTime,
+			&bid.Comments,
 			&bid.Status,
 			&bid.CreatedAt,
 			&bid.UpdatedAt,
@@ -90,6 +102,14 @@ func (h *HistoryRepo) GetBidHistory(userID uuid.UUID) ([]models.Bid, error) {
 
 		bids = append(bids, bid)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %v", err)
+	}
 
-	return bids, nil
+	result := newPage(bids, page, bidCursor)
+	err = countTotal(context.Background(), h.db, result, page, `SELECT COUNT(*) FROM bids WHERE contractor_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count rows: %v", err)
+	}
+	return result, nil
 }
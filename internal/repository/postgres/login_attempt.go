@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// LoginAttemptRepo keeps failed login counters and lockouts in Redis. Every
+// key expires on its own, so nothing has to be cleaned up.
+type LoginAttemptRepo struct {
+	redis *redis.Client
+}
+
+func NewLoginAttemptRepo(redisClient *redis.Client) *LoginAttemptRepo {
+	return &LoginAttemptRepo{redis: redisClient}
+}
+
+// RecordLoginFailure counts a failure at time at and returns the number of
+// failures so far. The count is forgotten once no failure happened for
+// window.
+func (r *LoginAttemptRepo) RecordLoginFailure(ctx context.Context, scope, id string, at time.Time, window time.Duration) (int64, error) {
+	key := loginFailuresKey(scope, id)
+	var count *redis.IntCmd
+	_, err := r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.HIncrBy(ctx, key, "count", 1)
+		pipe.HSet(ctx, key, "last", at.UnixMilli())
+		pipe.Expire(ctx, key, window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+// LoginFailures returns the failure count and the time of the last failure.
+func (r *LoginAttemptRepo) LoginFailures(ctx context.Context, scope, id string) (int64, time.Time, error) {
+	values, err := r.redis.HMGet(ctx, loginFailuresKey(scope, id), "count", "last").Result()
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	count, _ := parseRedisInt(values[0])
+	last, _ := parseRedisInt(values[1])
+	if count == 0 {
+		return 0, time.Time{}, nil
+	}
+	return count, time.UnixMilli(last), nil
+}
+
+func (r *LoginAttemptRepo) ResetLoginFailures(ctx context.Context, scope, id string) error {
+	return r.redis.Del(ctx, loginFailuresKey(scope, id)).Err()
+}
+
+func (r *LoginAttemptRepo) LockLogin(ctx context.Context, scope, id string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	_, err := r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, loginLockKey(scope, id), until.Unix(), ttl)
+		pipe.Del(ctx, loginFailuresKey(scope, id))
+		return nil
+	})
+	return err
+}
+
+// LoginLockedUntil returns when the lock on id ends, or the zero time if it
+// is not locked.
+func (r *LoginAttemptRepo) LoginLockedUntil(ctx context.Context, scope, id string) (time.Time, error) {
+	value, err := r.redis.Get(ctx, loginLockKey(scope, id)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	unix, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(unix, 0), nil
+}
+
+// UnlockLogin lifts the lock on id and forgets its failures.
+func (r *LoginAttemptRepo) UnlockLogin(ctx context.Context, scope, id string) error {
+	return r.redis.Del(ctx, loginLockKey(scope, id), loginFailuresKey(scope, id)).Err()
+}
+
+func parseRedisInt(value interface{}) (int64, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil
+}
+
+func loginFailuresKey(scope, id string) string {
+	return "auth:login:failures:" + scope + ":" + id
+}
+
+func loginLockKey(scope, id string) string {
+	return "auth:login:lock:" + scope + ":" + id
+}
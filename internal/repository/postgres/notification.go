@@ -5,6 +5,7 @@ import (
 	"database/sql"
 
 	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
 	"github.com/google/uuid"
 )
 
@@ -33,15 +34,18 @@ func (r *NotificationRepo) Create(ctx context.Context, notification *models.Noti
 	return err
 }
 
-func (r *NotificationRepo) ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.Notification, error) {
+func (r *NotificationRepo) ListByUserID(ctx context.Context, userID uuid.UUID, page repository.PageRequest) (*models.Page[models.Notification], error) {
 	query := `
         SELECT id, user_id, message, relation_id, type, read, created_at
         FROM notifications
         WHERE user_id = $1
-        ORDER BY created_at DESC
+        AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3::uuid))
+        ORDER BY created_at DESC, id DESC
+        LIMIT $4
     `
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	createdAt, id, limit := pageArgs(page)
+	rows, err := r.db.QueryContext(ctx, query, userID, createdAt, id, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -64,7 +68,18 @@ func (r *NotificationRepo) ListByUserID(ctx context.Context, userID uuid.UUID) (
 		}
 		notifications = append(notifications, n)
 	}
-	return notifications, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := newPage(notifications, page, func(n *models.Notification) repository.Cursor {
+		return repository.Cursor{CreatedAt: n.CreatedAt, ID: n.ID}
+	})
+	err = countTotal(ctx, r.db, result, page, `SELECT COUNT(*) FROM notifications WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 func (r *NotificationRepo) MarkAsRead(ctx context.Context, id uuid.UUID) error {
@@ -0,0 +1,295 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/google/uuid"
+)
+
+type OrganizationRepo struct {
+	db *sql.DB
+}
+
+func NewOrganizationRepo(db *sql.DB) *OrganizationRepo {
+	return &OrganizationRepo{db: db}
+}
+
+const organizationColumns = `o.id, o.name, o.kind, o.created_by, o.created_at, o.updated_at`
+
+// Create stores a new organization with ownerID as its first owner.
+func (r *OrganizationRepo) Create(ctx context.Context, org *models.Organization, ownerID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO organizations (id, name, kind, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, org.ID, org.Name, org.Kind, org.CreatedBy, org.CreatedAt, org.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO organization_members (organization_id, user_id, role, created_at)
+		VALUES ($1, $2, $3, $4)
+	`, org.ID, ownerID, models.OrgRoleOwner, org.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *OrganizationRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	query := `SELECT ` + organizationColumns + ` FROM organizations o WHERE o.id = $1`
+	var org models.Organization
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&org.ID, &org.Name, &org.Kind, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *OrganizationRepo) Update(ctx context.Context, org *models.Organization) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE organizations SET name = $2, updated_at = $3 WHERE id = $1`, org.ID, org.Name, org.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// ListForUser returns the organizations the user is a member of, oldest
+// first, with Role set to the user's role in each.
+func (r *OrganizationRepo) ListForUser(ctx context.Context, userID uuid.UUID) ([]models.Organization, error) {
+	query := `
+		SELECT ` + organizationColumns + `, m.role
+		FROM organizations o
+		JOIN organization_members m ON m.organization_id = o.id
+		WHERE m.user_id = $1
+		ORDER BY o.created_at, o.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []models.Organization{}
+	for rows.Next() {
+		var org models.Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.Kind, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt, &org.Role); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+func (r *OrganizationRepo) ListMembers(ctx context.Context, organizationID uuid.UUID) ([]models.Membership, error) {
+	query := `
+		SELECT m.organization_id, m.user_id, u.username, u.email, m.role, m.created_at
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1
+		ORDER BY m.created_at, u.username
+	`
+	rows, err := r.db.QueryContext(ctx, query, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.Membership{}
+	for rows.Next() {
+		var m models.Membership
+		if err := rows.Scan(&m.OrganizationID, &m.UserID, &m.Username, &m.Email, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// AddMember returns repository.ErrConflict when the user already is a
+// member.
+func (r *OrganizationRepo) AddMember(ctx context.Context, member *models.Membership) error {
+	return addMember(ctx, r.db, member)
+}
+
+func addMember(ctx context.Context, db execer, member *models.Membership) error {
+	res, err := db.ExecContext(ctx, `
+		INSERT INTO organization_members (organization_id, user_id, role, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`, member.OrganizationID, member.UserID, member.Role, member.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrConflict
+	}
+	return nil
+}
+
+// UpdateMemberRole changes the role of a member. It returns
+// repository.ErrConflict when that would leave the organization without an
+// owner.
+func (r *OrganizationRepo) UpdateMemberRole(ctx context.Context, organizationID, userID uuid.UUID, role models.OrgRole) error {
+	return r.changeMember(ctx, organizationID, userID, role != models.OrgRoleOwner, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `UPDATE organization_members SET role = $3 WHERE organization_id = $1 AND user_id = $2`, organizationID, userID, role)
+		return err
+	})
+}
+
+// RemoveMember returns repository.ErrConflict when the member is the last
+// owner.
+func (r *OrganizationRepo) RemoveMember(ctx context.Context, organizationID, userID uuid.UUID) error {
+	return r.changeMember(ctx, organizationID, userID, true, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2`, organizationID, userID)
+		return err
+	})
+}
+
+// changeMember runs change with the organization locked, after checking that
+// the member exists and, when demotes is set, that an owner is left.
+func (r *OrganizationRepo) changeMember(ctx context.Context, organizationID, userID uuid.UUID, demotes bool, change func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM organizations WHERE id = $1 FOR UPDATE`, organizationID); err != nil {
+		return err
+	}
+
+	var role models.OrgRole
+	err = tx.QueryRowContext(ctx, `SELECT role FROM organization_members WHERE organization_id = $1 AND user_id = $2`, organizationID, userID).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrNotFound
+		}
+		return err
+	}
+
+	if demotes && role == models.OrgRoleOwner {
+		var owners int
+		err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM organization_members WHERE organization_id = $1 AND role = 'owner'`, organizationID).Scan(&owners)
+		if err != nil {
+			return err
+		}
+		if owners <= 1 {
+			return repository.ErrConflict
+		}
+	}
+
+	if err := change(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const invitationColumns = `id, organization_id, email, role, token_hash, invited_by, expires_at, accepted_at, created_at`
+
+func (r *OrganizationRepo) CreateInvitation(ctx context.Context, inv *models.OrganizationInvitation) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO organization_invitations (`+invitationColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, inv.ID, inv.OrganizationID, inv.Email, inv.Role, inv.TokenHash, inv.InvitedBy, inv.ExpiresAt, inv.AcceptedAt, inv.CreatedAt)
+	return err
+}
+
+// ListInvitations returns the invitations of the organization that are
+// neither accepted nor expired, newest first.
+func (r *OrganizationRepo) ListInvitations(ctx context.Context, organizationID uuid.UUID, now time.Time) ([]models.OrganizationInvitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM organization_invitations
+		WHERE organization_id = $1 AND accepted_at IS NULL AND expires_at > $2
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, organizationID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.OrganizationInvitation{}
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *inv)
+	}
+	return invitations, rows.Err()
+}
+
+func (r *OrganizationRepo) GetInvitationByHash(ctx context.Context, hash string) (*models.OrganizationInvitation, error) {
+	query := `SELECT ` + invitationColumns + ` FROM organization_invitations WHERE token_hash = $1`
+	inv, err := scanInvitation(r.db.QueryRowContext(ctx, query, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (r *OrganizationRepo) DeleteInvitation(ctx context.Context, organizationID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM organization_invitations WHERE id = $1 AND organization_id = $2`, id, organizationID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// AcceptInvitation marks an open invitation as accepted and adds the user to
+// the organization with the invited role. It returns repository.ErrNotFound
+// when the invitation was accepted or expired in the meantime and
+// repository.ErrConflict when the user already is a member.
+func (r *OrganizationRepo) AcceptInvitation(ctx context.Context, id, userID uuid.UUID, now time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	member := models.Membership{UserID: userID, CreatedAt: now}
+	err = tx.QueryRowContext(ctx, `
+		UPDATE organization_invitations SET accepted_at = $2
+		WHERE id = $1 AND accepted_at IS NULL AND expires_at > $2
+		RETURNING organization_id, role
+	`, id, now).Scan(&member.OrganizationID, &member.Role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return repository.ErrNotFound
+		}
+		return err
+	}
+	if err := addMember(ctx, tx, &member); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func scanInvitation(row rowScanner) (*models.OrganizationInvitation, error) {
+	var inv models.OrganizationInvitation
+	err := row.Scan(&inv.ID, &inv.OrganizationID, &inv.Email, &inv.Role, &inv.TokenHash, &inv.InvitedBy, &inv.ExpiresAt, &inv.AcceptedAt, &inv.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+)
+
+// Lists are paged by keyset: ordered by created_at DESC, id DESC, a page
+// starts after the created_at and id of the cursor,
+//
+//	($n::timestamptz IS NULL OR (created_at, id) < ($n, $m::uuid))
+//
+// which uses the same index as the ordering, however deep the page.
+
+// pageArgs returns the created_at and id of the cursor of page, NULL on the
+// first page, and the number of rows to fetch: one more than the limit, to
+// tell whether another page follows.
+func pageArgs(page repository.PageRequest) (createdAt, id interface{}, limit int) {
+	if page.After != nil {
+		createdAt, id = page.After.CreatedAt, page.After.ID
+	}
+	return createdAt, id, page.Limit + 1
+}
+
+// newPage trims the row fetched beyond the limit and sets the cursor of the
+// next page from the last item kept.
+func newPage[T any](items []T, page repository.PageRequest, cursor func(*T) repository.Cursor) *models.Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	p := &models.Page[T]{Items: items}
+	if len(items) > page.Limit {
+		p.Items = items[:page.Limit]
+		next := cursor(&p.Items[page.Limit-1]).String()
+		p.NextCursor = &next
+	}
+	return p
+}
+
+// countTotal sets the total of p from a COUNT query when page asks for it.
+func countTotal[T any](ctx context.Context, db *sql.DB, p *models.Page[T], page repository.PageRequest, query string, args ...interface{}) error {
+	if !page.WithTotal {
+		return nil
+	}
+	var total int
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&total); err != nil {
+		return err
+	}
+	p.Total = &total
+	return nil
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/lib/pq"
+)
+
+// policyAuditListLimit caps how many entries one List call returns.
+const policyAuditListLimit = 500
+
+// PolicyAuditRepo stores the audit trail of policy changes.
+type PolicyAuditRepo struct {
+	db *sql.DB
+}
+
+func NewPolicyAuditRepo(db *sql.DB) *PolicyAuditRepo {
+	return &PolicyAuditRepo{db: db}
+}
+
+func (r *PolicyAuditRepo) Create(ctx context.Context, entry *models.PolicyAudit) error {
+	query := `
+		INSERT INTO policy_audit (id, actor_id, action, ptype, rule, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		entry.ID,
+		entry.ActorID,
+		entry.Action,
+		entry.Ptype,
+		pq.Array(entry.Rule),
+		entry.CreatedAt,
+	)
+	return err
+}
+
+// List returns the most recent entries, newest first.
+func (r *PolicyAuditRepo) List(ctx context.Context, limit int) ([]models.PolicyAudit, error) {
+	if limit <= 0 || limit > policyAuditListLimit {
+		limit = policyAuditListLimit
+	}
+	query := `
+		SELECT id, actor_id, action, ptype, rule, created_at
+		FROM policy_audit
+		ORDER BY created_at DESC
+		LIMIT $1
+	`
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.PolicyAudit{}
+	for rows.Next() {
+		var e models.PolicyAudit
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.Ptype, pq.Array(&e.Rule), &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
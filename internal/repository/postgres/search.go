@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Matched words are wrapped in these private use characters by ts_headline,
+// so that the rest of a snippet can be escaped before they become <mark>
+// tags.
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+// snippetOptions are the ts_headline options of description and comment
+// snippets; titleOptions highlight a whole title.
+var (
+	snippetOptions = `MaxFragments=2, MaxWords=30, MinWords=10, FragmentDelimiter=" … ", StartSel="` + highlightStart + `", StopSel="` + highlightStop + `"`
+	titleOptions   = `HighlightAll=true, StartSel="` + highlightStart + `", StopSel="` + highlightStop + `"`
+)
+
+// highlight turns a ts_headline result into HTML with the matched words in
+// <mark> elements.
+func highlight(headline string) string {
+	return strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").Replace(html.EscapeString(headline))
+}
+
+// searchVectors are the expressions of the generated search columns for a
+// quoted text search configuration. They match the ones created by the
+// migrations.
+var searchVectors = []struct {
+	table, index, expression string
+}{
+	{"tenders", "idx_tenders_search_vector", `setweight(to_tsvector(%[1]s, coalesce(title, '')), 'A') || setweight(to_tsvector(%[1]s, coalesce(description, '')), 'B')`},
+	{"bids", "idx_bids_search_vector", `to_tsvector(%[1]s, coalesce(comments, ''))`},
+}
+
+// SearchLanguage returns the text search configuration the search columns
+// are built with.
+func SearchLanguage(ctx context.Context, db *sql.DB) (string, error) {
+	var language string
+	err := db.QueryRowContext(ctx, `SELECT language::text FROM search_settings`).Scan(&language)
+	return language, err
+}
+
+// SetSearchLanguage makes language, a text search configuration such as
+// "english" or "simple", the one tenders and bids are indexed and searched
+// with. When it changes the search columns are rebuilt, which locks both
+// tables until every row is indexed again, so it runs under the migration
+// lock from "server migrate up" and never at server startup.
+func (m *Migrator) SetSearchLanguage(ctx context.Context, language string) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		return setSearchLanguage(ctx, conn, language)
+	})
+}
+
+func setSearchLanguage(ctx context.Context, conn *sql.Conn, language string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current, wanted string
+	err = tx.QueryRowContext(ctx, `SELECT language::text FROM search_settings FOR UPDATE`).Scan(&current)
+	if err != nil {
+		return err
+	}
+	if err := tx.QueryRowContext(ctx, `SELECT $1::regconfig::text`, language).Scan(&wanted); err != nil {
+		return fmt.Errorf("unknown text search configuration %q: %w", language, err)
+	}
+	if current == wanted {
+		return nil
+	}
+
+	quoted := pq.QuoteLiteral(wanted)
+	for _, v := range searchVectors {
+		statements := []string{
+			`ALTER TABLE ` + v.table + ` DROP COLUMN search_vector`,
+			`ALTER TABLE ` + v.table + ` ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (` + fmt.Sprintf(v.expression, quoted) + `) STORED`,
+			`CREATE INDEX ` + v.index + ` ON ` + v.table + ` USING GIN (search_vector)`,
+		}
+		for _, statement := range statements {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("error rebuilding the search column of %s: %w", v.table, err)
+			}
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE search_settings SET language = $1::regconfig`, wanted); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
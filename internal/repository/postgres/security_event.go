@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+)
+
+// securityEventListLimit caps how many events one List call returns.
+const securityEventListLimit = 500
+
+type SecurityEventRepo struct {
+	db *sql.DB
+}
+
+func NewSecurityEventRepo(db *sql.DB) *SecurityEventRepo {
+	return &SecurityEventRepo{db: db}
+}
+
+func (r *SecurityEventRepo) Create(ctx context.Context, event *models.SecurityEvent) error {
+	query := `
+		INSERT INTO security_events (id, type, user_id, actor_id, username, ip, user_agent, detail, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		event.ID,
+		event.Type,
+		event.UserID,
+		event.ActorID,
+		emptyToNull(event.Username),
+		emptyToNull(event.IP),
+		emptyToNull(event.UserAgent),
+		emptyToNull(event.Detail),
+		event.CreatedAt,
+	)
+	return err
+}
+
+// List returns the most recent events matching filters, newest first.
+func (r *SecurityEventRepo) List(ctx context.Context, filters repository.SecurityEventFilters) ([]models.SecurityEvent, error) {
+	limit := filters.Limit
+	if limit <= 0 || limit > securityEventListLimit {
+		limit = securityEventListLimit
+	}
+
+	query := `
+		SELECT id, type, user_id, actor_id, COALESCE(username, ''), COALESCE(ip, ''),
+			COALESCE(user_agent, ''), COALESCE(detail, ''), created_at
+		FROM security_events
+		WHERE ($1::uuid IS NULL OR user_id = $1)
+		AND ($2::varchar IS NULL OR ip = $2)
+		AND ($3::varchar IS NULL OR type = $3)
+		ORDER BY created_at DESC
+		LIMIT $4
+	`
+	rows, err := r.db.QueryContext(ctx, query, filters.UserID, emptyToNull(filters.IP), emptyToNull(filters.Type), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.SecurityEvent{}
+	for rows.Next() {
+		var e models.SecurityEvent
+		if err := rows.Scan(
+			&e.ID,
+			&e.Type,
+			&e.UserID,
+			&e.ActorID,
+			&e.Username,
+			&e.IP,
+			&e.UserAgent,
+			&e.Detail,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// emptyToNull stores empty strings as NULL.
+func emptyToNull(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
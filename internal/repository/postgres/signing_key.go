@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+)
+
+// signingKeyLockID serialises key rotation across replicas.
+const signingKeyLockID = 7_245_113_902
+
+type SigningKeyRepo struct {
+	db *sql.DB
+}
+
+func NewSigningKeyRepo(db *sql.DB) *SigningKeyRepo {
+	return &SigningKeyRepo{db: db}
+}
+
+func (r *SigningKeyRepo) ListSigningKeys(ctx context.Context, algorithm string) ([]models.SigningKey, error) {
+	query := `
+		SELECT id, algorithm, private_key, activates_at, expires_at, created_at
+		FROM jwt_signing_keys
+		WHERE algorithm = $1 AND expires_at > NOW()
+		ORDER BY activates_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, algorithm)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.SigningKey
+	for rows.Next() {
+		var k models.SigningKey
+		err := rows.Scan(
+			&k.ID,
+			&k.Algorithm,
+			&k.PrivateKey,
+			&k.ActivatesAt,
+			&k.ExpiresAt,
+			&k.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// CreateSigningKeyIfDue stores key unless another key of the same algorithm
+// was created at or after dueBefore. It holds a transaction-level advisory
+// lock, so only one replica rotates at a time.
+func (r *SigningKeyRepo) CreateSigningKeyIfDue(ctx context.Context, key *models.SigningKey, dueBefore time.Time) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, signingKeyLockID); err != nil {
+		return false, err
+	}
+
+	var recent bool
+	query := `SELECT EXISTS(SELECT 1 FROM jwt_signing_keys WHERE algorithm = $1 AND created_at >= $2)`
+	if err := tx.QueryRowContext(ctx, query, key.Algorithm, dueBefore).Scan(&recent); err != nil {
+		return false, err
+	}
+	if recent {
+		return false, nil
+	}
+
+	query = `
+		INSERT INTO jwt_signing_keys (id, algorithm, private_key, activates_at, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err = tx.ExecContext(ctx, query,
+		key.ID,
+		key.Algorithm,
+		key.PrivateKey,
+		key.ActivatesAt,
+		key.ExpiresAt,
+		key.CreatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *SigningKeyRepo) DeleteExpiredSigningKeys(ctx context.Context, now time.Time) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM jwt_signing_keys WHERE expires_at <= $1`, now)
+	return err
+}
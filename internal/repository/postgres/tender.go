@@ -5,12 +5,16 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Dostonlv/hackathon-nt/internal/models"
 	"github.com/Dostonlv/hackathon-nt/internal/repository"
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type TenderRepo struct {
@@ -22,56 +26,224 @@ func NewTenderRepo(db *sql.DB, redisClient *redis.Client) *TenderRepo {
 	return &TenderRepo{db: db, redis: redisClient}
 }
 
+const tenderColumns = `id, client_id, organization_id, title, description, deadline, budget, status, attachment, category_code, tags, cancellation_reason, created_at, updated_at`
+
+// tenderFields are the scan destinations of tenderColumns.
+func tenderFields(t *models.Tender) []interface{} {
+	return []interface{}{
+		&t.ID,
+		&t.ClientID,
+		&t.OrganizationID,
+		&t.Title,
+		&t.Description,
+		&t.Deadline,
+		&t.Budget,
+		&t.Status,
+		&t.Attachment,
+		&t.CategoryCode,
+		pq.Array(&t.Tags),
+		&t.CancellationReason,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+	}
+}
+
+func scanTender(row rowScanner) (*models.Tender, error) {
+	var t models.Tender
+	if err := row.Scan(tenderFields(&t)...); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// scanTenderMatch scans tenderColumns followed by the rank, title and
+// snippet of a search.
+func scanTenderMatch(row rowScanner) (*models.Tender, error) {
+	var t models.Tender
+	var m models.SearchMatch
+	if err := row.Scan(append(tenderFields(&t), &m.Rank, &m.Title, &m.Snippet)...); err != nil {
+		return nil, err
+	}
+	m.Title = highlight(m.Title)
+	m.Snippet = highlight(m.Snippet)
+	t.Match = &m
+	return &t, nil
+}
+
 func (r *TenderRepo) Create(ctx context.Context, tender *models.Tender) error {
 	query := `
 		INSERT INTO tenders (
-			id, client_id, title, description, deadline, budget, status, attachment, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			id, client_id, organization_id, title, description, deadline, budget, status, attachment, category_code, tags, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 	_, err := r.db.ExecContext(ctx, query,
 		tender.ID,
 		tender.ClientID,
+		tender.OrganizationID,
 		tender.Title,
 		tender.Description,
 		tender.Deadline,
 		tender.Budget,
 		tender.Status,
 		tender.Attachment,
+		tender.CategoryCode,
+		pq.Array(tender.Tags),
 		tender.CreatedAt,
 		tender.UpdatedAt,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	invalidateTenderCaches(ctx, r.redis, tender.ID)
+	return nil
+}
+
+// tenderFilterConditions matches the tenders of a repository.TenderFilters
+// given as the arguments returned by tenderFilterArgs. The search is a web
+// search style query of the full-text index, and a category matches its
+// whole subtree.
+const tenderFilterConditions = `
+		($1::text IS NULL OR search_vector @@ websearch_to_tsquery(search_language(), $1))
+		AND ($2::tender_status IS NULL OR status = $2)
+		AND ($3::uuid[] IS NULL OR organization_id = ANY($3))
+		AND ($4::text IS NULL OR category_code IN (
+			WITH RECURSIVE subtree(code) AS (
+				SELECT code FROM categories WHERE code = $4
+				UNION ALL
+				SELECT c.code FROM categories c JOIN subtree s ON c.parent_code = s.code
+			)
+			SELECT code FROM subtree
+		))
+		AND ($5::text IS NULL OR tags @> ARRAY[$5::text])
+		AND ($6::numeric IS NULL OR budget >= $6)
+		AND ($7::numeric IS NULL OR budget <= $7)
+		AND ($8::timestamptz IS NULL OR deadline >= $8)
+		AND ($9::timestamptz IS NULL OR deadline <= $9)
+`
+
+func tenderFilterArgs(filters repository.TenderFilters) []interface{} {
+	return []interface{}{
+		nullIfEmpty(filters.Search),
+		nullIfEmpty(filters.Status),
+		pq.Array(uuidStrings(filters.OrganizationIDs)),
+		nullIfEmpty(filters.Category),
+		nullIfEmpty(filters.Tag),
+		filters.MinBudget,
+		filters.MaxBudget,
+		filters.DeadlineAfter,
+		filters.DeadlineBefore,
+	}
 }
 
-func (r *TenderRepo) List(ctx context.Context, filters repository.TenderFilters) ([]models.Tender, error) {
-	// Generate a unique cache key based on filters
-	cacheKey := "tenders"
+// tenderFiltersCacheKey generates a unique cache key for filters. Every key
+// starts with "tenders" so that invalidateTenderCaches drops it.
+func tenderFiltersCacheKey(prefix string, filters repository.TenderFilters) string {
+	cacheKey := prefix
 	if filters.Search != "" {
 		cacheKey += ":search=" + filters.Search
 	}
 	if filters.Status != "" {
 		cacheKey += ":status=" + filters.Status
 	}
+	if filters.OrganizationIDs != nil {
+		cacheKey += ":organizations=" + strings.Join(uuidStrings(filters.OrganizationIDs), ",")
+	}
+	if filters.Category != "" {
+		cacheKey += ":category=" + filters.Category
+	}
+	if filters.Tag != "" {
+		cacheKey += ":tag=" + filters.Tag
+	}
+	if filters.MinBudget != nil {
+		cacheKey += ":min_budget=" + strconv.FormatFloat(*filters.MinBudget, 'f', -1, 64)
+	}
+	if filters.MaxBudget != nil {
+		cacheKey += ":max_budget=" + strconv.FormatFloat(*filters.MaxBudget, 'f', -1, 64)
+	}
+	if filters.DeadlineAfter != nil {
+		cacheKey += ":deadline_after=" + filters.DeadlineAfter.UTC().Format(time.RFC3339)
+	}
+	if filters.DeadlineBefore != nil {
+		cacheKey += ":deadline_before=" + filters.DeadlineBefore.UTC().Format(time.RFC3339)
+	}
+	if filters.Sort != repository.TenderSortNewest {
+		cacheKey += ":sort=" + string(filters.Sort)
+	}
+	return cacheKey
+}
+
+// List returns a page of the tenders matching filters in the order of
+// filters.Sort. Search results come with highlighted snippets, best match
+// first unless sorted by deadline, and their cursors carry the rank.
+func (r *TenderRepo) List(ctx context.Context, filters repository.TenderFilters, page repository.PageRequest) (*models.Page[models.Tender], error) {
+	byRank := filters.Search != "" && filters.Sort == repository.TenderSortNewest
+	byDeadline := filters.Sort == repository.TenderSortDeadline || filters.Sort == repository.TenderSortDeadlineDesc
+	if page.After != nil && (byRank && page.After.Rank == nil || byDeadline && page.After.Deadline == nil) {
+		return nil, repository.ErrInvalidCursor
+	}
+	cacheKey := tenderFiltersCacheKey("tenders", filters) + page.CacheKey()
 
-	// Check Redis for cached list
+	// Check Redis for cached page
 	cachedData, err := r.redis.Get(ctx, cacheKey).Result()
 	if err == nil {
-		// Cache hit: unmarshal JSON and return the list
-		var tenders []models.Tender
-		if err := json.Unmarshal([]byte(cachedData), &tenders); err == nil {
-			return tenders, nil
+		// Cache hit: unmarshal JSON and return the page
+		var cached models.Page[models.Tender]
+		if err := json.Unmarshal([]byte(cachedData), &cached); err == nil {
+			return &cached, nil
 		}
 	}
 
-	// Cache miss: query the database
+	// Cache miss: query the database. The cursor is compared in $10 and $11
+	// against the columns the list is ordered by.
+	after, id, limit := pageArgs(page)
+	keyset, order := "(created_at, id) < ($10, $11::uuid)", "created_at DESC, id DESC"
+	switch filters.Sort {
+	case repository.TenderSortDeadline:
+		keyset, order = "(deadline, id) > ($10, $11::uuid)", "deadline, id"
+	case repository.TenderSortDeadlineDesc:
+		keyset, order = "(deadline, id) < ($10, $11::uuid)", "deadline DESC, id DESC"
+	}
+	if byDeadline && page.After != nil {
+		after = *page.After.Deadline
+	}
+	args := append(tenderFilterArgs(filters), after, id, limit)
 	query := `
-		SELECT id, client_id, title, description, deadline, budget, status, attachment, created_at, updated_at
+		SELECT ` + tenderColumns + `
 		FROM tenders
-		WHERE ($1 IS NULL OR (title ILIKE $1 OR description ILIKE $1))
-		AND ($2 IS NULL OR status = $2)
-		ORDER BY created_at DESC
+		WHERE ` + tenderFilterConditions + `
+		AND ($10::timestamptz IS NULL OR ` + keyset + `)
+		ORDER BY ` + order + `
+		LIMIT $12
 	`
-	rows, err := r.db.QueryContext(ctx, query, nullableString(filters.Search), nullableString(filters.Status))
+	scan := scanTender
+	if filters.Search != "" {
+		if byRank {
+			var rank interface{}
+			if page.After != nil {
+				rank = *page.After.Rank
+			}
+			args = append(args, rank)
+			keyset = "(rank, created_at, id) < ($13::real, $10, $11::uuid)"
+			order = "rank DESC, created_at DESC, id DESC"
+		}
+		args = append(args, titleOptions, snippetOptions)
+		query = fmt.Sprintf(`
+			SELECT `+tenderColumns+`, rank,
+				ts_headline(search_language(), title, query, $%d),
+				ts_headline(search_language(), description, query, $%d)
+			FROM (
+				SELECT tenders.*, q.query, ts_rank_cd(search_vector, q.query) AS rank
+				FROM tenders, websearch_to_tsquery(search_language(), $1) AS q(query)
+				WHERE `+tenderFilterConditions+`
+			) AS matches
+			WHERE ($10::timestamptz IS NULL OR `+keyset+`)
+			ORDER BY `+order+`
+			LIMIT $12
+		`, len(args)-1, len(args))
+		scan = scanTenderMatch
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -79,32 +251,162 @@ func (r *TenderRepo) List(ctx context.Context, filters repository.TenderFilters)
 
 	var tenders []models.Tender
 	for rows.Next() {
-		var t models.Tender
-		err := rows.Scan(
-			&t.ID,
-			&t.ClientID,
-			&t.Title,
-			&t.Description,
-			&t.Deadline,
-			&t.Budget,
-			&t.Status,
-			&t.Attachment,
-			&t.CreatedAt,
-			&t.UpdatedAt,
-		)
+		t, err := scan(rows)
 		if err != nil {
 			return nil, err
 		}
-		tenders = append(tenders, t)
+		tenders = append(tenders, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cursor := tenderCursor
+	if byDeadline {
+		cursor = tenderDeadlineCursor
+	}
+	result := newPage(tenders, page, cursor)
+	err = countTotal(ctx, r.db, result, page, `SELECT COUNT(*) FROM tenders WHERE `+tenderFilterConditions, tenderFilterArgs(filters)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache the page in Redis
+	pageJSON, err := json.Marshal(result)
+	if err == nil {
+		r.redis.Set(ctx, cacheKey, pageJSON, 10*time.Minute)
+	}
+
+	return result, nil
+}
+
+// tenderCursor is the position of a tender in a list, or in search results
+// when it has a Match.
+func tenderCursor(t *models.Tender) repository.Cursor {
+	c := repository.Cursor{CreatedAt: t.CreatedAt, ID: t.ID}
+	if t.Match != nil {
+		rank := t.Match.Rank
+		c.Rank = &rank
+	}
+	return c
+}
+
+// tenderDeadlineCursor is the position of a tender in a list ordered by
+// deadline.
+func tenderDeadlineCursor(t *models.Tender) repository.Cursor {
+	c := tenderCursor(t)
+	deadline := t.Deadline
+	c.Deadline = &deadline
+	return c
+}
+
+// Facets counts the tenders matching filters by category and by status. The
+// category counts ignore filters.Category and include the tenders of every
+// subcategory; the status counts ignore filters.Status. Categories without
+// tenders are left out.
+func (r *TenderRepo) Facets(ctx context.Context, filters repository.TenderFilters) (*models.TenderFacets, error) {
+	cacheKey := tenderFiltersCacheKey("tenders:facets", filters)
+
+	cachedData, err := r.redis.Get(ctx, cacheKey).Result()
+	if err == nil {
+		var facets models.TenderFacets
+		if err := json.Unmarshal([]byte(cachedData), &facets); err == nil {
+			return &facets, nil
+		}
+	}
+
+	facets := &models.TenderFacets{
+		Categories: []models.CategoryCount{},
+		Statuses:   map[models.TenderStatus]int{},
+	}
+
+	withoutCategory := filters
+	withoutCategory.Category = ""
+	rows, err := r.db.QueryContext(ctx, `
+		WITH RECURSIVE ancestors(code, ancestor) AS (
+			SELECT code, code FROM categories
+			UNION ALL
+			SELECT a.code, c.parent_code
+			FROM ancestors a JOIN categories c ON c.code = a.ancestor
+			WHERE c.parent_code IS NOT NULL
+		), matching AS (
+			SELECT category_code FROM tenders
+			WHERE `+tenderFilterConditions+`
+		)
+		SELECT c.code, c.name, COUNT(*)
+		FROM matching m
+		JOIN ancestors a ON a.code = m.category_code
+		JOIN categories c ON c.code = a.ancestor
+		GROUP BY c.code, c.name
+		ORDER BY c.code
+	`, tenderFilterArgs(withoutCategory)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var count models.CategoryCount
+		if err := rows.Scan(&count.Code, &count.Name, &count.Count); err != nil {
+			return nil, err
+		}
+		facets.Categories = append(facets.Categories, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	withoutStatus := filters
+	withoutStatus.Status = ""
+	rows, err = r.db.QueryContext(ctx, `
+		SELECT status, COUNT(*)
+		FROM tenders
+		WHERE `+tenderFilterConditions+`
+		GROUP BY status
+	`, tenderFilterArgs(withoutStatus)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status models.TenderStatus
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		facets.Statuses[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	// Cache the list in Redis
-	tendersJSON, err := json.Marshal(tenders)
+	facetsJSON, err := json.Marshal(facets)
 	if err == nil {
-		r.redis.Set(ctx, cacheKey, tendersJSON, 10*time.Minute)
+		r.redis.Set(ctx, cacheKey, facetsJSON, 10*time.Minute)
 	}
 
-	return tenders, nil
+	return facets, nil
+}
+
+// uuidStrings converts ids for pq.Array. A nil slice stays nil so that it is
+// sent as NULL.
+func uuidStrings(ids []uuid.UUID) []string {
+	if ids == nil {
+		return nil
+	}
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = id.String()
+	}
+	return s
+}
+
+// nullIfEmpty sends an empty s as NULL, for optional filters matched
+// exactly.
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
 }
 
 func nullableString(s string) interface{} {
@@ -116,62 +418,130 @@ func nullableString(s string) interface{} {
 
 func (r *TenderRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Tender, error) {
 	query := `
-		SELECT id, client_id, title, description, deadline, budget, status, attachment, created_at, updated_at
+		SELECT ` + tenderColumns + `
 		FROM tenders
 		WHERE id = $1
 	`
 
-	var t models.Tender
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&t.ID,
-		&t.ClientID,
-		&t.Title,
-		&t.Description,
-		&t.Deadline,
-		&t.Budget,
-		&t.Status,
-		&t.Attachment,
-		&t.CreatedAt,
-		&t.UpdatedAt,
-	)
+	t, err := scanTender(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, repository.ErrNotFound
 		}
 		return nil, err
 	}
-	return &t, nil
+	return t, nil
 }
 
-func (r *TenderRepo) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
-	exists, err := r.exists(ctx, id)
+// Transition moves a tender from change.FromStatus to change.ToStatus and
+// records the change in its status history. It returns
+// repository.ErrConflict when the tender is no longer in change.FromStatus.
+func (r *TenderRepo) Transition(ctx context.Context, change *models.TenderStatusChange) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	if !exists {
-		return repository.ErrNotFound
+	defer tx.Rollback()
+
+	if err := transitionTender(ctx, tx, change); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
 	}
 
-	query := `
+	invalidateTenderCaches(ctx, r.redis, change.TenderID)
+	return nil
+}
+
+// transitionTender changes the status of a tender if it still has
+// change.FromStatus and records the change. A cancellation stores its reason
+// on the tender.
+func transitionTender(ctx context.Context, db execer, change *models.TenderStatusChange) error {
+	var cancellationReason *string
+	if change.ToStatus == models.TenderStatusCancelled {
+		cancellationReason = change.Reason
+	}
+	res, err := db.ExecContext(ctx, `
 		UPDATE tenders
-		SET status = $1, updated_at = $2
-		WHERE id = $3
-	`
-	_, err = r.db.ExecContext(ctx, query, status, sql.NullTime{Time: time.Now(), Valid: true}, id)
+		SET status = $3, cancellation_reason = $4, updated_at = $5
+		WHERE id = $1 AND status = $2
+	`, change.TenderID, change.FromStatus, change.ToStatus, cancellationReason, change.CreatedAt)
 	if err != nil {
 		return err
 	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrConflict
+	}
 
-	// Invalidate related caches
-	r.invalidateListCache(ctx)
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO tender_status_history (id, tender_id, transition, from_status, to_status, reason, actor_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, change.ID, change.TenderID, change.Transition, change.FromStatus, change.ToStatus, change.Reason, change.ActorID, change.CreatedAt)
+	return err
+}
+
+// ListStatusChanges returns the status history of a tender, oldest first.
+func (r *TenderRepo) ListStatusChanges(ctx context.Context, tenderID uuid.UUID) ([]models.TenderStatusChange, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, tender_id, transition, from_status, to_status, reason, actor_id, created_at
+		FROM tender_status_history
+		WHERE tender_id = $1
+		ORDER BY created_at, id
+	`, tenderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []models.TenderStatusChange{}
+	for rows.Next() {
+		var c models.TenderStatusChange
+		if err := rows.Scan(&c.ID, &c.TenderID, &c.Transition, &c.FromStatus, &c.ToStatus, &c.Reason, &c.ActorID, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
 
-	// Invalidate specific ID cache
-	cacheKey := "tender:" + id.String()
-	r.redis.Del(ctx, cacheKey)
+// Update saves the details of a tender. It returns repository.ErrConflict
+// when the tender was awarded or cancelled, or one of its bids awarded.
+func (r *TenderRepo) Update(ctx context.Context, tender *models.Tender) error {
+	query := `
+		UPDATE tenders
+		SET title = $2, description = $3, deadline = $4, budget = $5, attachment = $6, category_code = $7, tags = $8, updated_at = $9
+		WHERE id = $1 AND status NOT IN ('awarded', 'cancelled')
+		AND NOT EXISTS (SELECT 1 FROM bids WHERE tender_id = $1 AND status = 'awarded')
+	`
+	res, err := r.db.ExecContext(ctx, query,
+		tender.ID,
+		tender.Title,
+		tender.Description,
+		tender.Deadline,
+		tender.Budget,
+		tender.Attachment,
+		tender.CategoryCode,
+		pq.Array(tender.Tags),
+		tender.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrConflict
+	}
 
+	invalidateTenderCaches(ctx, r.redis, tender.ID)
 	return nil
 }
 
+// Delete deletes a tender without bids. It returns repository.ErrConflict
+// when the tender has bids.
 func (r *TenderRepo) Delete(ctx context.Context, id uuid.UUID) error {
 	// Check if the tender exists
 	exists, err := r.exists(ctx, id)
@@ -186,31 +556,36 @@ func (r *TenderRepo) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `
 		DELETE FROM tenders
 		WHERE id = $1
+		AND NOT EXISTS (SELECT 1 FROM bids WHERE tender_id = $1)
 	`
-	_, err = r.db.ExecContext(ctx, query, id)
+	res, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrConflict
+	}
 
-	// Invalidate cache for the specific tender
-	cacheKey := "tender:" + id.String()
-	r.redis.Del(ctx, cacheKey)
-
-	// Invalidate all related list caches
-	r.invalidateListCache(ctx)
-
+	invalidateTenderCaches(ctx, r.redis, id)
 	return nil
 }
 
-func (r *TenderRepo) ListByClientID(ctx context.Context, clientID uuid.UUID) ([]models.Tender, error) {
+// ListByOrganizationIDs returns a page of the tenders of the given
+// organizations, newest first.
+func (r *TenderRepo) ListByOrganizationIDs(ctx context.Context, organizationIDs []uuid.UUID, page repository.PageRequest) (*models.Page[models.Tender], error) {
+	createdAt, id, limit := pageArgs(page)
 	query := `
-		SELECT id, client_id, title, description, deadline, budget, status, attachment, created_at, updated_at
+		SELECT ` + tenderColumns + `
 		FROM tenders
-		WHERE client_id = $1
-		ORDER BY created_at DESC
+		WHERE organization_id = ANY($1)
+		AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3::uuid))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
 	`
-
-	rows, err := r.db.QueryContext(ctx, query, clientID)
+	ids := pq.Array(uuidStrings(organizationIDs))
+	rows, err := r.db.QueryContext(ctx, query, ids, createdAt, id, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -218,25 +593,21 @@ func (r *TenderRepo) ListByClientID(ctx context.Context, clientID uuid.UUID) ([]
 
 	var tenders []models.Tender
 	for rows.Next() {
-		var t models.Tender
-		err := rows.Scan(
-			&t.ID,
-			&t.ClientID,
-			&t.Title,
-			&t.Description,
-			&t.Deadline,
-			&t.Budget,
-			&t.Status,
-			&t.Attachment,
-			&t.CreatedAt,
-			&t.UpdatedAt,
-		)
+		t, err := scanTender(rows)
 		if err != nil {
 			return nil, err
 		}
-		tenders = append(tenders, t)
+		tenders = append(tenders, *t)
 	}
-	return tenders, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := newPage(tenders, page, tenderCursor)
+	if err := countTotal(ctx, r.db, result, page, `SELECT COUNT(*) FROM tenders WHERE organization_id = ANY($1)`, ids); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 func (r *TenderRepo) exists(ctx context.Context, id uuid.UUID) (bool, error) {
@@ -266,10 +637,141 @@ func (r *TenderRepo) GetClientIDByTenderID(ctx context.Context, tenderID uuid.UU
 	return clientID, nil
 }
 
-func (r *TenderRepo) invalidateListCache(ctx context.Context) {
-	// Remove all list-related caches (e.g., tenders:*). You can refine this to target specific keys.
-	iter := r.redis.Scan(ctx, 0, "tenders:*", 0).Iterator()
+// invalidateTenderCaches removes the cached tender and every cached tender
+// list, which may contain it.
+func invalidateTenderCaches(ctx context.Context, redisClient *redis.Client, id uuid.UUID) {
+	redisClient.Del(ctx, "tender:"+id.String())
+	iter := redisClient.Scan(ctx, 0, "tenders:*", 0).Iterator()
 	for iter.Next(ctx) {
-		r.redis.Del(ctx, iter.Val())
+		redisClient.Del(ctx, iter.Val())
+	}
+}
+
+// CloseExpired closes up to limit open tenders whose deadline is at or
+// before now and records the changes, made by no user, in their status
+// history. Tenders locked by another replica are skipped and picked up by
+// the next run.
+func (r *TenderRepo) CloseExpired(ctx context.Context, now time.Time, limit int) ([]models.Tender, error) {
+	query := `
+		WITH due AS (
+			SELECT id FROM tenders
+			WHERE status = $2 AND deadline <= $1
+			ORDER BY deadline
+			LIMIT $5
+			FOR UPDATE SKIP LOCKED
+		), closed AS (
+			UPDATE tenders SET status = $3, updated_at = $1
+			WHERE id IN (SELECT id FROM due)
+			RETURNING ` + tenderColumns + `
+		), history AS (
+			INSERT INTO tender_status_history (tender_id, transition, from_status, to_status, created_at)
+			SELECT id, $4, $2, $3, $1 FROM closed
+		)
+		SELECT ` + tenderColumns + ` FROM closed
+	`
+	rows, err := r.db.QueryContext(ctx, query, now, models.TenderStatusOpen, models.TenderStatusClosed, models.TenderTransitionClose, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenders []models.Tender
+	for rows.Next() {
+		t, err := scanTender(rows)
+		if err != nil {
+			return nil, err
+		}
+		tenders = append(tenders, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, t := range tenders {
+		invalidateTenderCaches(ctx, r.redis, t.ID)
+	}
+	return tenders, nil
+}
+
+// ClaimDeadlineReminders returns up to limit open tenders whose deadline is
+// after from and at or before to and records that the reminder lead before
+// their current deadline was sent. A reminder is claimed by one replica only.
+func (r *TenderRepo) ClaimDeadlineReminders(ctx context.Context, lead time.Duration, from, to, now time.Time, limit int) ([]models.Tender, error) {
+	query := `
+		WITH due AS (
+			SELECT id, deadline FROM tenders t
+			WHERE status = $1 AND deadline > $2 AND deadline <= $3
+			AND NOT EXISTS (
+				SELECT 1 FROM tender_deadline_reminders r
+				WHERE r.tender_id = t.id AND r.lead_seconds = $4 AND r.deadline = t.deadline
+			)
+			ORDER BY deadline
+			LIMIT $6
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			INSERT INTO tender_deadline_reminders (tender_id, lead_seconds, deadline, sent_at)
+			SELECT id, $4, deadline, $5::timestamptz FROM due
+			ON CONFLICT DO NOTHING
+			RETURNING tender_id
+		)
+		SELECT ` + tenderColumns + `
+		FROM tenders
+		WHERE id IN (SELECT tender_id FROM claimed)
+		ORDER BY deadline
+	`
+	rows, err := r.db.QueryContext(ctx, query, models.TenderStatusOpen, from, to, int64(lead/time.Second), now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenders []models.Tender
+	for rows.Next() {
+		t, err := scanTender(rows)
+		if err != nil {
+			return nil, err
+		}
+		tenders = append(tenders, *t)
+	}
+	return tenders, rows.Err()
+}
+
+// Watch adds a user to the watchers of a tender. Watching twice is not an
+// error.
+func (r *TenderRepo) Watch(ctx context.Context, tenderID, userID uuid.UUID, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO tender_watchers (tender_id, user_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`, tenderID, userID, at)
+	return err
+}
+
+// Unwatch removes a user from the watchers of a tender. It returns
+// repository.ErrNotFound when the user was not watching it.
+func (r *TenderRepo) Unwatch(ctx context.Context, tenderID, userID uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM tender_watchers WHERE tender_id = $1 AND user_id = $2`, tenderID, userID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// ListWatcherIDs returns the users watching a tender.
+func (r *TenderRepo) ListWatcherIDs(ctx context.Context, tenderID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT user_id FROM tender_watchers WHERE tender_id = $1`, tenderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
 	}
+	return ids, rows.Err()
 }
@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// TokenRepo stores refresh tokens in Postgres and revoked access tokens in
+// Redis, where entries expire together with the tokens they revoke.
+type TokenRepo struct {
+	db    *sql.DB
+	redis *redis.Client
+}
+
+func NewTokenRepo(db *sql.DB, redisClient *redis.Client) *TokenRepo {
+	return &TokenRepo{db: db, redis: redisClient}
+}
+
+func (r *TokenRepo) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return insertRefreshToken(ctx, r.db, token)
+}
+
+func (r *TokenRepo) GetRefreshTokenByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, family_id, token_hash, expires_at, revoked_at, replaced_by, mfa, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+	var t models.RefreshToken
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.FamilyID,
+		&t.TokenHash,
+		&t.ExpiresAt,
+		&t.RevokedAt,
+		&t.ReplacedBy,
+		&t.MFA,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RotateRefreshToken revokes oldID and stores next in one transaction. It
+// returns repository.ErrConflict when oldID was already revoked, which means
+// the same refresh token was used twice.
+func (r *TokenRepo) RotateRefreshToken(ctx context.Context, oldID uuid.UUID, next *models.RefreshToken) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $2, replaced_by = $3
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+	res, err := tx.ExecContext(ctx, query, oldID, time.Now(), next.ID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+
+	return tx.Commit()
+}
+
+func (r *TokenRepo) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	query := `UPDATE refresh_tokens SET revoked_at = $2 WHERE family_id = $1 AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, familyID, time.Now())
+	return err
+}
+
+func (r *TokenRepo) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE refresh_tokens SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, userID, time.Now())
+	return err
+}
+
+func (r *TokenRepo) RevokeAccessToken(ctx context.Context, jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return r.redis.Set(ctx, revokedJTIKey(jti), 1, ttl).Err()
+}
+
+func (r *TokenRepo) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := r.redis.Exists(ctx, revokedJTIKey(jti)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *TokenRepo) RevokeUserAccessTokens(ctx context.Context, userID uuid.UUID, before time.Time, ttl time.Duration) error {
+	return r.redis.Set(ctx, revokedUserKey(userID), before.UnixMicro(), ttl).Err()
+}
+
+// UserAccessTokensRevokedAt returns the time before which every access token
+// of the user is revoked, or the zero time.
+func (r *TokenRepo) UserAccessTokensRevokedAt(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	value, err := r.redis.Get(ctx, revokedUserKey(userID)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	micro, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMicro(micro), nil
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func insertRefreshToken(ctx context.Context, db execer, token *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at, mfa, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := db.ExecContext(ctx, query,
+		token.ID,
+		token.UserID,
+		token.FamilyID,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.MFA,
+		token.CreatedAt,
+	)
+	return err
+}
+
+func revokedJTIKey(jti string) string {
+	return "auth:revoked:jti:" + jti
+}
+
+func revokedUserKey(userID uuid.UUID) string {
+	return "auth:revoked:user:" + userID.String()
+}
@@ -0,0 +1,247 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// TwoFactorRepo stores TOTP enrollments and recovery codes in Postgres and
+// pending two-step login challenges in Redis.
+type TwoFactorRepo struct {
+	db    *sql.DB
+	redis *redis.Client
+}
+
+func NewTwoFactorRepo(db *sql.DB, redisClient *redis.Client) *TwoFactorRepo {
+	return &TwoFactorRepo{db: db, redis: redisClient}
+}
+
+func (r *TwoFactorRepo) GetTwoFactor(ctx context.Context, userID uuid.UUID) (*models.TwoFactor, error) {
+	query := `
+		SELECT user_id, secret, enabled_at, last_used_step, created_at
+		FROM user_two_factor
+		WHERE user_id = $1
+	`
+	var t models.TwoFactor
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&t.UserID,
+		&t.Secret,
+		&t.EnabledAt,
+		&t.LastUsedStep,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SavePendingTwoFactor stores a new, not yet confirmed secret, replacing any
+// earlier pending one. It returns repository.ErrConflict when 2FA is already
+// enabled.
+func (r *TwoFactorRepo) SavePendingTwoFactor(ctx context.Context, userID uuid.UUID, secret []byte) error {
+	query := `
+		INSERT INTO user_two_factor (user_id, secret, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = EXCLUDED.created_at
+		WHERE user_two_factor.enabled_at IS NULL
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, secret, time.Now())
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+	return nil
+}
+
+// EnableTwoFactor confirms a pending enrollment with the step of the first
+// valid code and stores the recovery codes.
+func (r *TwoFactorRepo) EnableTwoFactor(ctx context.Context, userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE user_two_factor
+		SET enabled_at = $2, last_used_step = $3
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`
+	res, err := tx.ExecContext(ctx, query, userID, time.Now(), step)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *TwoFactorRepo) DisableTwoFactor(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM two_factor_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_two_factor WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseTOTPStep records step as used. It returns repository.ErrConflict when a
+// code of that step or a later one was already accepted.
+func (r *TwoFactorRepo) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	query := `UPDATE user_two_factor SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+	res, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repository.ErrConflict
+	}
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used. It returns
+// repository.ErrNotFound when the user has no such unused code.
+func (r *TwoFactorRepo) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) error {
+	query := `
+		UPDATE two_factor_recovery_codes
+		SET used_at = $3
+		WHERE id = (
+			SELECT id FROM two_factor_recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+		)
+	`
+	res, err := r.db.ExecContext(ctx, query, userID, codeHash, time.Now())
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *TwoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CountRecoveryCodes returns how many unused recovery codes the user has.
+func (r *TwoFactorRepo) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	var n int
+	query := `SELECT COUNT(*) FROM two_factor_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&n)
+	return n, err
+}
+
+func (r *TwoFactorRepo) CreateLoginChallenge(ctx context.Context, hash string, userID uuid.UUID, ttl time.Duration) error {
+	return r.redis.Set(ctx, loginChallengeKey(hash), userID.String(), ttl).Err()
+}
+
+// GetLoginChallenge returns the user a pending challenge belongs to, or
+// repository.ErrNotFound when it expired or was used.
+func (r *TwoFactorRepo) GetLoginChallenge(ctx context.Context, hash string) (uuid.UUID, error) {
+	value, err := r.redis.Get(ctx, loginChallengeKey(hash)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return uuid.Nil, repository.ErrNotFound
+		}
+		return uuid.Nil, err
+	}
+	return uuid.Parse(value)
+}
+
+// CountLoginChallengeAttempt increments and returns the number of codes tried
+// against a challenge. The counter expires with the challenge.
+func (r *TwoFactorRepo) CountLoginChallengeAttempt(ctx context.Context, hash string) (int64, error) {
+	key := loginChallengeKey(hash) + ":attempts"
+	n, err := r.redis.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if n == 1 {
+		ttl, err := r.redis.TTL(ctx, loginChallengeKey(hash)).Result()
+		if err != nil {
+			return 0, err
+		}
+		if ttl > 0 {
+			r.redis.Expire(ctx, key, ttl)
+		}
+	}
+	return n, nil
+}
+
+func (r *TwoFactorRepo) DeleteLoginChallenge(ctx context.Context, hash string) error {
+	return r.redis.Del(ctx, loginChallengeKey(hash), loginChallengeKey(hash)+":attempts").Err()
+}
+
+func replaceRecoveryCodes(ctx context.Context, db execer, userID uuid.UUID, codeHashes []string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM two_factor_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO two_factor_recovery_codes (id, user_id, code_hash, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	now := time.Now()
+	for _, hash := range codeHashes {
+		if _, err := db.ExecContext(ctx, query, uuid.New(), userID, hash, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loginChallengeKey(hash string) string {
+	return "auth:2fa:challenge:" + hash
+}
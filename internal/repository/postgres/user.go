@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
@@ -41,24 +43,27 @@ func (r *UserRepo) Create(ctx context.Context, user *models.User) error {
 	return nil
 }
 
+const userColumns = `id, username, email, password_hash, role, email_verified_at, suspended_at, COALESCE(suspended_reason, ''), created_at, updated_at`
+
 func (r *UserRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
-	user := &models.User{}
-	query := `
-        SELECT id, username, email, password_hash, role, created_at, updated_at
-        FROM users
-        WHERE id = $1
-    `
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.PasswordHash,
-		&user.Role,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+	return r.getBy(ctx, "id", id)
+}
+
+func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.getBy(ctx, "email", email)
+}
+
+func (r *UserRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.getBy(ctx, "username", username)
+}
+
+// getBy returns the user whose column equals value. column is never user
+// input.
+func (r *UserRepo) getBy(ctx context.Context, column string, value interface{}) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE ` + column + ` = $1`
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrNotFound
 	}
 	if err != nil {
 		return nil, err
@@ -66,59 +71,114 @@ func (r *UserRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.User, err
 	return user, nil
 }
 
-func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
-	user := &models.User{}
+// List returns the users matching filters, newest first.
+func (r *UserRepo) List(ctx context.Context, filters repository.UserFilters) ([]models.User, error) {
 	query := `
-        SELECT id, username, email, password_hash, role, created_at, updated_at
-        FROM users
-        WHERE email = $1
-    `
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.PasswordHash,
-		&user.Role,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE ($1::varchar IS NULL OR username ILIKE $1 OR email ILIKE $1)
+		AND ($2::varchar IS NULL OR role::text = $2)
+		AND ($3::boolean IS NULL OR (suspended_at IS NOT NULL) = $3)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4 OFFSET $5
+	`
+	var suspended sql.NullBool
+	if filters.Suspended != nil {
+		suspended = sql.NullBool{Bool: *filters.Suspended, Valid: true}
 	}
+	rows, err := r.db.QueryContext(ctx, query,
+		nullableString(filters.Search),
+		emptyToNull(filters.Role),
+		suspended,
+		filters.Limit,
+		filters.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
-	return user, nil
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
 }
 
-func (r *UserRepo) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+// SetSuspended suspends the user at the given time, or reactivates them when
+// at is nil.
+func (r *UserRepo) SetSuspended(ctx context.Context, id uuid.UUID, at *time.Time, reason string) error {
+	query := `UPDATE users SET suspended_at = $2, suspended_reason = $3, updated_at = $4 WHERE id = $1`
+	res, err := r.db.ExecContext(ctx, query, id, at, emptyToNull(reason), time.Now())
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *UserRepo) UpdateRole(ctx context.Context, id uuid.UUID, role models.UserRole) error {
+	query := `UPDATE users SET role = $2, updated_at = $3 WHERE id = $1`
+	res, err := r.db.ExecContext(ctx, query, id, role, time.Now())
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *UserRepo) MarkEmailVerified(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query := `UPDATE users SET email_verified_at = $2, updated_at = $2 WHERE id = $1 AND email_verified_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, id, at)
+	return err
+}
+
+func (r *UserRepo) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $2, updated_at = $3 WHERE id = $1`
+	res, err := r.db.ExecContext(ctx, query, id, passwordHash, time.Now())
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (r *UserRepo) ExistsByEmail(ctx context.Context, email string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`
+	err := r.db.QueryRowContext(ctx, query, email).Scan(&exists)
+	return exists, err
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
 	user := &models.User{}
-	query := `
-        SELECT id, username, email, password_hash, role, created_at, updated_at
-        FROM users
-        WHERE username = $1
-    `
-	err := r.db.QueryRowContext(ctx, query, username).Scan(
+	err := row.Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
 		&user.Role,
+		&user.EmailVerifiedAt,
+		&user.SuspendedAt,
+		&user.SuspendedReason,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
-	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
-	}
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (r *UserRepo) ExistsByEmail(ctx context.Context, email string) (bool, error) {
-	var exists bool
-	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`
-	err := r.db.QueryRowContext(ctx, query, email).Scan(&exists)
-	return exists, err
+// requireAffected returns repository.ErrNotFound when res changed no rows.
+func requireAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
 }
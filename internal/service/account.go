@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/Dostonlv/hackathon-nt/internal/utils"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidAccountToken  = errors.New("invalid or expired token")
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+	ErrEmailNotVerified     = errors.New("email not verified")
+	ErrWeakPassword         = errors.New("password must be at least 8 characters")
+)
+
+const minPasswordLength = 8
+
+// AccountConfig holds the lifetimes of account tokens and where the links in
+// account emails point.
+type AccountConfig struct {
+	VerificationTokenTTL  time.Duration
+	PasswordResetTokenTTL time.Duration
+	LinkBaseURL           string
+}
+
+// AccountService handles email verification and password resets. Both work
+// with one-time tokens that are emailed to the user and stored hashed.
+type AccountService struct {
+	userRepo    repository.UserRepository
+	tokenRepo   repository.AccountTokenRepository
+	authService *AuthService
+	mailer      utils.Mailer
+	cfg         AccountConfig
+}
+
+func NewAccountService(userRepo repository.UserRepository, tokenRepo repository.AccountTokenRepository, authService *AuthService, mailer utils.Mailer, cfg AccountConfig) *AccountService {
+	return &AccountService{
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		authService: authService,
+		mailer:      mailer,
+		cfg:         cfg,
+	}
+}
+
+type VerifyEmailInput struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type PasswordResetRequestInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type PasswordResetInput struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
+
+// SendVerificationEmail emails the user a link to confirm their address.
+func (s *AccountService) SendVerificationEmail(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := s.createToken(ctx, user.ID, models.AccountTokenEmailVerification, s.cfg.VerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, utils.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening the link below:\n\n%s\n\nThe link expires in %s.\n",
+			user.Username, s.link("/verify-email", token), s.cfg.VerificationTokenTTL),
+	})
+}
+
+// VerifyEmail consumes a verification token and marks the user's email as
+// verified.
+func (s *AccountService) VerifyEmail(ctx context.Context, input VerifyEmailInput) error {
+	token, err := s.consumeToken(ctx, models.AccountTokenEmailVerification, input.Token)
+	if err != nil {
+		return err
+	}
+	return s.userRepo.MarkEmailVerified(ctx, token.UserID, time.Now())
+}
+
+// RequestPasswordReset emails a reset link when an account with the address
+// exists. It succeeds either way so callers cannot probe for accounts.
+func (s *AccountService) RequestPasswordReset(ctx context.Context, input PasswordResetRequestInput) error {
+	user, err := s.userRepo.GetByEmail(ctx, strings.TrimSpace(input.Email))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, err := s.createToken(ctx, user.ID, models.AccountTokenPasswordReset, s.cfg.PasswordResetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, utils.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. If it was you, open the link below to choose a new one:\n\n%s\n\nThe link expires in %s. If you did not ask for this, you can ignore this email.\n",
+			user.Username, s.link("/reset-password", token), s.cfg.PasswordResetTokenTTL),
+	})
+}
+
+// ResetPassword consumes a reset token, sets the new password and signs the
+// user out of every session. Since the link was delivered by email, a reset
+// also verifies the address.
+func (s *AccountService) ResetPassword(ctx context.Context, input PasswordResetInput) error {
+	if len(input.Password) < minPasswordLength {
+		return ErrWeakPassword
+	}
+
+	token, err := s.consumeToken(ctx, models.AccountTokenPasswordReset, input.Token)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, token.UserID, string(hashedPassword)); err != nil {
+		return err
+	}
+	if err := s.userRepo.MarkEmailVerified(ctx, token.UserID, time.Now()); err != nil {
+		return err
+	}
+	return s.authService.LogoutAll(ctx, token.UserID)
+}
+
+// ForcePasswordReset replaces the user's password with a random one, signs
+// them out of every session and emails them a reset link.
+func (s *AccountService) ForcePasswordReset(ctx context.Context, user *models.User) error {
+	random, _, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(random), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, string(hashedPassword)); err != nil {
+		return err
+	}
+	if err := s.authService.LogoutAll(ctx, user.ID); err != nil {
+		return err
+	}
+
+	token, err := s.createToken(ctx, user.ID, models.AccountTokenPasswordReset, s.cfg.PasswordResetTokenTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, utils.Message{
+		To:      user.Email,
+		Subject: "Choose a new password",
+		Body: fmt.Sprintf("Hi %s,\n\nAn administrator has reset the password of your account. Open the link below to choose a new one:\n\n%s\n\nThe link expires in %s. Afterwards, use \"Forgot password\" to get a new link.\n",
+			user.Username, s.link("/reset-password", token), s.cfg.PasswordResetTokenTTL),
+	})
+}
+
+// RequireVerifiedEmail returns ErrEmailNotVerified unless the user confirmed
+// their email address.
+func (s *AccountService) RequireVerifiedEmail(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.EmailVerified() {
+		return ErrEmailNotVerified
+	}
+	return nil
+}
+
+func (s *AccountService) createToken(ctx context.Context, userID uuid.UUID, purpose models.AccountTokenPurpose, ttl time.Duration) (string, error) {
+	token, hash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = s.tokenRepo.CreateAccountToken(ctx, &models.AccountToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *AccountService) consumeToken(ctx context.Context, purpose models.AccountTokenPurpose, token string) (*models.AccountToken, error) {
+	if token == "" {
+		return nil, ErrInvalidAccountToken
+	}
+	t, err := s.tokenRepo.ConsumeAccountToken(ctx, purpose, utils.HashToken(token), time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidAccountToken
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *AccountService) link(path, token string) string {
+	return strings.TrimSuffix(s.cfg.LinkBaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
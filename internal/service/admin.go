@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrInvalidRole      = errors.New("invalid role")
+	ErrCannotModifySelf = errors.New("admins cannot suspend or change the role of their own account")
+	ErrAlreadySuspended = errors.New("user already suspended")
+	ErrNotSuspended     = errors.New("user not suspended")
+)
+
+const (
+	defaultUserListLimit = 50
+	maxUserListLimit     = 200
+)
+
+// AdminService implements user administration. Every change is recorded in
+// the security log with the admin as actor.
+type AdminService struct {
+	userRepo       repository.UserRepository
+	authService    *AuthService
+	accountService *AccountService
+	securityLog    *SecurityLog
+}
+
+func NewAdminService(userRepo repository.UserRepository, authService *AuthService, accountService *AccountService, securityLog *SecurityLog) *AdminService {
+	return &AdminService{
+		userRepo:       userRepo,
+		authService:    authService,
+		accountService: accountService,
+		securityLog:    securityLog,
+	}
+}
+
+type SuspendUserInput struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+type ChangeRoleInput struct {
+	Role models.UserRole `json:"role" validate:"required,oneof=client contractor admin"`
+}
+
+// ListUsers returns the users matching filters, newest first.
+func (s *AdminService) ListUsers(ctx context.Context, filters repository.UserFilters) ([]models.User, error) {
+	if filters.Limit <= 0 {
+		filters.Limit = defaultUserListLimit
+	}
+	if filters.Limit > maxUserListLimit {
+		filters.Limit = maxUserListLimit
+	}
+	if filters.Offset < 0 {
+		filters.Offset = 0
+	}
+	filters.Search = strings.TrimSpace(filters.Search)
+	if filters.Role != "" && !models.UserRole(filters.Role).Valid() {
+		return nil, ErrInvalidRole
+	}
+	return s.userRepo.List(ctx, filters)
+}
+
+func (s *AdminService) GetUser(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	return s.getUser(ctx, userID)
+}
+
+// SuspendUser blocks the account and ends every session of the user, so
+// their access tokens are rejected from the next request on. Suspending a
+// suspended user ends their sessions again before it returns
+// ErrAlreadySuspended, so a retry completes a suspension whose LogoutAll
+// failed.
+func (s *AdminService) SuspendUser(ctx context.Context, actorID, userID uuid.UUID, input SuspendUserInput) (*models.User, error) {
+	if actorID == userID {
+		return nil, ErrCannotModifySelf
+	}
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Suspended() {
+		if err := s.authService.LogoutAll(ctx, userID); err != nil {
+			return nil, err
+		}
+		return nil, ErrAlreadySuspended
+	}
+
+	now := time.Now()
+	reason := strings.TrimSpace(input.Reason)
+	if err := s.userRepo.SetSuspended(ctx, userID, &now, reason); err != nil {
+		return nil, err
+	}
+	if err := s.authService.LogoutAll(ctx, userID); err != nil {
+		return nil, err
+	}
+	s.record(ctx, models.SecurityEventUserSuspended, actorID, user, reason)
+
+	user.SuspendedAt = &now
+	user.SuspendedReason = reason
+	return user, nil
+}
+
+// ReactivateUser lifts a suspension. The user has to log in again.
+func (s *AdminService) ReactivateUser(ctx context.Context, actorID, userID uuid.UUID) (*models.User, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Suspended() {
+		return nil, ErrNotSuspended
+	}
+
+	if err := s.userRepo.SetSuspended(ctx, userID, nil, ""); err != nil {
+		return nil, err
+	}
+	s.record(ctx, models.SecurityEventUserReactivated, actorID, user, "")
+
+	user.SuspendedAt = nil
+	user.SuspendedReason = ""
+	return user, nil
+}
+
+// ChangeRole gives the user another role. Their sessions are ended because
+// access tokens carry the role.
+func (s *AdminService) ChangeRole(ctx context.Context, actorID, userID uuid.UUID, input ChangeRoleInput) (*models.User, error) {
+	if !input.Role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	if actorID == userID {
+		return nil, ErrCannotModifySelf
+	}
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == input.Role {
+		return user, nil
+	}
+
+	if err := s.userRepo.UpdateRole(ctx, userID, input.Role); err != nil {
+		return nil, err
+	}
+	if err := s.authService.LogoutAll(ctx, userID); err != nil {
+		return nil, err
+	}
+	s.record(ctx, models.SecurityEventRoleChanged, actorID, user, fmt.Sprintf("%s -> %s", user.Role, input.Role))
+
+	user.Role = input.Role
+	return user, nil
+}
+
+// ForcePasswordReset invalidates the user's password and sessions and emails
+// them a link to choose a new password.
+func (s *AdminService) ForcePasswordReset(ctx context.Context, actorID, userID uuid.UUID) error {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.accountService.ForcePasswordReset(ctx, user); err != nil {
+		return err
+	}
+	s.record(ctx, models.SecurityEventPasswordReset, actorID, user, "")
+	return nil
+}
+
+func (s *AdminService) getUser(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *AdminService) record(ctx context.Context, eventType models.SecurityEventType, actorID uuid.UUID, user *models.User, detail string) {
+	s.securityLog.Record(ctx, models.SecurityEvent{
+		Type:     eventType,
+		UserID:   &user.ID,
+		ActorID:  &actorID,
+		Username: user.Username,
+		Detail:   detail,
+	})
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/Dostonlv/hackathon-nt/internal/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidAPIKey      = errors.New("invalid API key")
+	ErrInvalidAPIKeyName  = errors.New("API key name must be 1 to 100 characters")
+	ErrInvalidAPIKeyScope = errors.New("invalid API key scope")
+	ErrAPIKeyExpiry       = errors.New("API key expiry must be in the future")
+	ErrAPIKeyNotFound     = errors.New("API key not found")
+	ErrTooManyAPIKeys     = errors.New("too many API keys")
+)
+
+const (
+	// APIKeyPrefix starts every API key, so keys can be told apart from
+	// access tokens and recognised by secret scanners.
+	APIKeyPrefix = "tnd_"
+	// apiKeyDisplayLength is how much of a key is kept in clear to identify
+	// it in listings.
+	apiKeyDisplayLength = len(APIKeyPrefix) + 8
+	// apiKeyTouchInterval is how often last_used_at is updated at most.
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyService manages API keys and authenticates requests made with them.
+type APIKeyService struct {
+	repo       repository.APIKeyRepository
+	userRepo   repository.UserRepository
+	maxPerUser int
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository, userRepo repository.UserRepository, maxPerUser int) *APIKeyService {
+	return &APIKeyService{
+		repo:       repo,
+		userRepo:   userRepo,
+		maxPerUser: maxPerUser,
+	}
+}
+
+type CreateAPIKeyInput struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+	// ExpiresAt is optional; keys without it stay valid until revoked.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreatedAPIKey is returned once when a key is created. Key is not stored
+// and cannot be shown again.
+type CreatedAPIKey struct {
+	Key string `json:"key"`
+	models.APIKey
+}
+
+// Create issues a new API key for the user.
+func (s *APIKeyService) Create(ctx context.Context, userID uuid.UUID, input CreateAPIKeyInput) (*CreatedAPIKey, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 100 {
+		return nil, ErrInvalidAPIKeyName
+	}
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return nil, ErrAPIKeyExpiry
+	}
+
+	count, err := s.repo.CountActiveAPIKeys(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	if count >= s.maxPerUser {
+		return nil, ErrTooManyAPIKeys
+	}
+
+	token, _, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	key := APIKeyPrefix + token
+
+	stored := models.APIKey{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    key[:apiKeyDisplayLength],
+		KeyHash:   utils.HashToken(key),
+		Scopes:    scopes,
+		ExpiresAt: input.ExpiresAt,
+		CreatedAt: now,
+	}
+	if err := s.repo.CreateAPIKey(ctx, &stored); err != nil {
+		return nil, err
+	}
+	return &CreatedAPIKey{Key: key, APIKey: stored}, nil
+}
+
+// List returns the user's keys, including revoked and expired ones.
+func (s *APIKeyService) List(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	return s.repo.ListAPIKeys(ctx, userID)
+}
+
+// Revoke stops a key of the user from working.
+func (s *APIKeyService) Revoke(ctx context.Context, userID, keyID uuid.UUID) error {
+	if err := s.repo.RevokeAPIKey(ctx, userID, keyID, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrAPIKeyNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Authenticate verifies an API key and returns the principal it identifies:
+// the key's owner with their current role, limited to the key's scopes.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*models.Principal, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	stored, err := s.repo.GetAPIKeyByHash(ctx, utils.HashToken(key))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if !stored.Active(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if user.Suspended() {
+		return nil, ErrAccountSuspended
+	}
+
+	if err := s.repo.TouchAPIKey(ctx, stored.ID, now, apiKeyTouchInterval); err != nil {
+		log.Printf("Failed to record use of API key %s: %v", stored.ID, err)
+	}
+
+	principal := &models.Principal{
+		UserID:  user.ID,
+		Roles:   []models.UserRole{user.Role},
+		TokenID: stored.ID.String(),
+		Scopes:  stored.Scopes,
+		Method:  models.AuthMethodAPIKey,
+	}
+	if stored.ExpiresAt != nil {
+		principal.ExpiresAt = *stored.ExpiresAt
+	}
+	return principal, nil
+}
+
+// normalizeScopes checks scopes against models.APIKeyScopes and drops
+// duplicates.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKeyScope)
+	}
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !models.ValidAPIKeyScope(scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidAPIKeyScope, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
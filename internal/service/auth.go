@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/Dostonlv/hackathon-nt/internal/models"
@@ -12,15 +13,41 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrTokenRevoked        = errors.New("token revoked")
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrAccountSuspended    = errors.New("account suspended")
+)
+
+var (
+	// dummyPasswordHash is compared against when the username does not
+	// exist, so unknown and known usernames take the same time to reject.
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
 type AuthService struct {
-	userRepo repository.UserRepository
-	jwtUtil  *utils.JWTUtil
+	userRepo      repository.UserRepository
+	tokenRepo     repository.TokenRepository
+	twoFactorRepo repository.TwoFactorRepository
+	loginGuard    *LoginGuard
+	jwtUtil       *utils.JWTUtil
+	refreshTTL    time.Duration
+	twoFactor     TwoFactorConfig
 }
 
-func NewAuthService(userRepo repository.UserRepository, jwtUtil *utils.JWTUtil) *AuthService {
+func NewAuthService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, twoFactorRepo repository.TwoFactorRepository, loginGuard *LoginGuard, jwtUtil *utils.JWTUtil, refreshTTL time.Duration, twoFactor TwoFactorConfig) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
-		jwtUtil:  jwtUtil,
+		userRepo:      userRepo,
+		tokenRepo:     tokenRepo,
+		twoFactorRepo: twoFactorRepo,
+		loginGuard:    loginGuard,
+		jwtUtil:       jwtUtil,
+		refreshTTL:    refreshTTL,
+		twoFactor:     twoFactor,
 	}
 }
 
@@ -34,11 +61,21 @@ type RegisterInput struct {
 type LoginInput struct {
 	Username string `json:"username" validate:"required"`
 	Password string `json:"password" validate:"required"`
+	// IP and UserAgent identify the client for brute-force protection and
+	// the security log. They are set by the handler.
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 type AuthResponse struct {
-	Token string      `json:"token"`
-	User  models.User `json:"user"`
+	Token        string      `json:"token"`
+	ExpiresAt    time.Time   `json:"expires_at"`
+	RefreshToken string      `json:"refresh_token"`
+	User         models.User `json:"user"`
+}
+
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
 func (s *AuthService) Register(ctx context.Context, input RegisterInput) (*AuthResponse, error) {
@@ -77,39 +114,257 @@ func (s *AuthService) Register(ctx context.Context, input RegisterInput) (*AuthR
 		return nil, err
 	}
 
-	// Generate JWT
-	token, err := s.jwtUtil.GenerateToken(user.ID, user.Role)
+	return s.issueTokens(ctx, user, uuid.New(), nil, false)
+}
+
+// Login checks the user's password. When the user has two-factor
+// authentication enabled no tokens are issued; instead a challenge is
+// returned that CompleteTwoFactorLogin exchanges for tokens together with a
+// TOTP or recovery code.
+//
+// Unknown usernames and wrong passwords both return ErrInvalidCredentials.
+// Repeated failures are throttled by the LoginGuard, which returns a
+// *LoginBlockedError.
+func (s *AuthService) Login(ctx context.Context, input LoginInput) (*AuthResponse, *TwoFactorChallenge, error) {
+	attempt := LoginAttempt{Username: input.Username, IP: input.IP, UserAgent: input.UserAgent}
+	if err := s.loginGuard.Check(ctx, attempt); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.userRepo.GetByUsername(ctx, input.Username)
+	if err != nil {
+		if !errors.Is(err, repository.ErrNotFound) {
+			return nil, nil, err
+		}
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(input.Password))
+		if err := s.loginGuard.Failed(ctx, attempt, nil, "unknown username"); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password)); err != nil {
+		if err := s.loginGuard.Failed(ctx, attempt, &user.ID, "wrong password"); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidCredentials
+	}
+	if user.Suspended() {
+		return nil, nil, ErrAccountSuspended
+	}
+
+	enabled, err := s.twoFactorEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if enabled {
+		// Failures are only forgotten once the second factor is checked too,
+		// so guessing codes still counts towards the lockout.
+		challenge, err := s.createLoginChallenge(ctx, user.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, challenge, nil
+	}
+
+	if err := s.loginGuard.Succeeded(ctx, attempt, user.ID, "password"); err != nil {
+		return nil, nil, err
+	}
+	resp, err := s.issueTokens(ctx, user, uuid.New(), nil, false)
+	return resp, nil, err
+}
+
+// UnlockAccount lifts a login lockout of the user. actorID is the
+// administrator doing it.
+func (s *AuthService) UnlockAccount(ctx context.Context, actorID, userID uuid.UUID) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.loginGuard.UnlockAccount(ctx, user, actorID)
+}
+
+// UnlockIP lifts a login lockout of an IP address. actorID is the
+// administrator doing it.
+func (s *AuthService) UnlockIP(ctx context.Context, actorID uuid.UUID, ip string) error {
+	return s.loginGuard.UnlockIP(ctx, ip, actorID)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token. The presented token is revoked. Presenting an already rotated token
+// revokes every token of its family, since it is likely stolen.
+func (s *AuthService) Refresh(ctx context.Context, input RefreshInput) (*AuthResponse, error) {
+	current, err := s.tokenRepo.GetRefreshTokenByHash(ctx, utils.HashToken(input.RefreshToken))
 	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
 		return nil, err
 	}
 
-	return &AuthResponse{
-		Token: token,
-		User:  *user,
-	}, nil
+	if current.RevokedAt != nil {
+		if current.ReplacedBy != nil {
+			if err := s.tokenRepo.RevokeRefreshTokenFamily(ctx, current.FamilyID); err != nil {
+				return nil, err
+			}
+			return nil, ErrRefreshTokenReused
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, current.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	if user.Suspended() {
+		return nil, ErrAccountSuspended
+	}
+
+	resp, err := s.issueTokens(ctx, user, current.FamilyID, &current.ID, current.MFA)
+	if err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			if err := s.tokenRepo.RevokeRefreshTokenFamily(ctx, current.FamilyID); err != nil {
+				return nil, err
+			}
+			return nil, ErrRefreshTokenReused
+		}
+		return nil, err
+	}
+	return resp, nil
 }
 
-func (s *AuthService) Login(ctx context.Context, input LoginInput) (*AuthResponse, error) {
-	user, err := s.userRepo.GetByUsername(ctx, input.Username)
+// Logout revokes the refresh token's session and, when given, the access
+// token it was used with.
+func (s *AuthService) Logout(ctx context.Context, refreshToken, accessToken string) error {
+	if refreshToken != "" {
+		current, err := s.tokenRepo.GetRefreshTokenByHash(ctx, utils.HashToken(refreshToken))
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+		if current != nil {
+			if err := s.tokenRepo.RevokeRefreshTokenFamily(ctx, current.FamilyID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if accessToken != "" {
+		claims, err := s.jwtUtil.ParseToken(accessToken)
+		if err == nil && claims.Id != "" {
+			ttl := time.Until(time.Unix(claims.ExpiresAt, 0))
+			if err := s.tokenRepo.RevokeAccessToken(ctx, claims.Id, ttl); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LogoutAll revokes every refresh token of the user and every access token
+// issued up to now.
+func (s *AuthService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	if err := s.tokenRepo.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return err
+	}
+	return s.tokenRepo.RevokeUserAccessTokens(ctx, userID, time.Now(), s.jwtUtil.TokenTTL())
+}
+
+// Authenticate verifies an access token, with or without the Bearer prefix,
+// and returns the principal it identifies. It returns ErrInvalidToken for
+// malformed or expired tokens and ErrTokenRevoked for logged out ones.
+func (s *AuthService) Authenticate(ctx context.Context, accessToken string) (*models.Principal, error) {
+	claims, err := s.jwtUtil.ParseToken(accessToken)
 	if err != nil {
-		if err.Error() == "user not found" {
-			return nil, errors.New("user not found")
+		return nil, ErrInvalidToken
+	}
+	if claims.UserID == uuid.Nil || claims.Role == "" {
+		return nil, ErrInvalidToken
+	}
+
+	if err := s.checkTokenRevoked(ctx, claims.UserID, claims.Id, claims.IssuedTime()); err != nil {
+		return nil, err
+	}
+
+	return &models.Principal{
+		UserID:    claims.UserID,
+		Roles:     []models.UserRole{claims.Role},
+		TokenID:   claims.Id,
+		Method:    models.AuthMethodJWT,
+		MFA:       claims.MFA,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+	}, nil
+}
+
+// checkTokenRevoked returns ErrTokenRevoked if the access token was revoked
+// by Logout or LogoutAll.
+func (s *AuthService) checkTokenRevoked(ctx context.Context, userID uuid.UUID, jti string, issuedAt time.Time) error {
+	if jti != "" {
+		revoked, err := s.tokenRepo.IsAccessTokenRevoked(ctx, jti)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return ErrTokenRevoked
 		}
+	}
 
-		return nil, errors.New("invalid credentials")
+	revokedAt, err := s.tokenRepo.UserAccessTokensRevokedAt(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !revokedAt.IsZero() && !issuedAt.After(revokedAt) {
+		return ErrTokenRevoked
 	}
+	return nil
+}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password)); err != nil {
-		return nil, errors.New("invalid credentials")
+// issueTokens creates an access token and a refresh token in the given
+// family. When previous is set the refresh token replaces it. mfa records
+// whether the session was opened with a second factor.
+func (s *AuthService) issueTokens(ctx context.Context, user *models.User, familyID uuid.UUID, previous *uuid.UUID, mfa bool) (*AuthResponse, error) {
+	token, err := s.jwtUtil.GenerateToken(user.ID, user.Role, mfa)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshHash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
 	}
 
-	token, err := s.jwtUtil.GenerateToken(user.ID, user.Role)
+	now := time.Now()
+	stored := &models.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: refreshHash,
+		ExpiresAt: now.Add(s.refreshTTL),
+		MFA:       mfa,
+		CreatedAt: now,
+	}
+	if previous != nil {
+		err = s.tokenRepo.RotateRefreshToken(ctx, *previous, stored)
+	} else {
+		err = s.tokenRepo.CreateRefreshToken(ctx, stored)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	return &AuthResponse{
-		Token: token,
-		User:  *user,
+		Token:        token,
+		ExpiresAt:    now.Add(s.jwtUtil.TokenTTL()),
+		RefreshToken: refreshToken,
+		User:         *user,
 	}, nil
 }
+
+func dummyHash() []byte {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	})
+	return dummyPasswordHash
+}
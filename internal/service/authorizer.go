@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/google/uuid"
+)
+
+// ErrForbidden is returned when the caller may not perform an action on a
+// resource, as opposed to ErrUnauthorized when there is no caller at all.
+var ErrForbidden = errors.New("action not allowed on resource")
+
+// Action is something a principal does to a resource.
+type Action string
+
+const (
+	ActionCreate        Action = "create"
+	ActionView          Action = "view"
+	ActionUpdate        Action = "update"
+	ActionDelete        Action = "delete"
+	ActionAward         Action = "award"
+	ActionBid           Action = "bid"
+	ActionWatch         Action = "watch"
+	ActionListBids      Action = "list_bids"
+	ActionAsk           Action = "ask"
+	ActionListQuestions Action = "list_questions"
+	ActionManageMembers Action = "manage_members"
+)
+
+// ResourceType is the kind of object an action is performed on.
+type ResourceType string
+
+const (
+	ResourceTender       ResourceType = "tender"
+	ResourceBid          ResourceType = "bid"
+	ResourceOrganization ResourceType = "organization"
+	ResourceQuestion     ResourceType = "question"
+	// ResourceTenderHistory and ResourceBidHistory are the tenders and bids
+	// of one user.
+	ResourceTenderHistory ResourceType = "tender_history"
+	ResourceBidHistory    ResourceType = "bid_history"
+	ResourceNotifications ResourceType = "notifications"
+)
+
+// Resource holds the attributes of an object that rules decide on.
+type Resource struct {
+	Type ResourceType
+	ID   uuid.UUID
+	// OwnerID is the user a history or notification stream belongs to.
+	OwnerID uuid.UUID
+	// OrganizationID is the organization the resource belongs to: the
+	// client organization of a tender, the contractor organization of a bid
+	// or question, or the organization itself.
+	OrganizationID uuid.UUID
+	// TenderOrganizationID is the client organization of the tender, or of
+	// the tender a bid was placed on or a question asked about.
+	TenderOrganizationID uuid.UUID
+	// TenderStatus is the status of the tender, or of the tender a bid was
+	// placed on or a question asked about.
+	TenderStatus models.TenderStatus
+}
+
+// TenderResource describes a tender.
+func TenderResource(t *models.Tender) Resource {
+	return Resource{Type: ResourceTender, ID: t.ID, OrganizationID: t.OrganizationID, TenderOrganizationID: t.OrganizationID, TenderStatus: t.Status}
+}
+
+// BidResource describes a bid placed on tender.
+func BidResource(b *models.Bid, tender *models.Tender) Resource {
+	return Resource{Type: ResourceBid, ID: b.ID, OrganizationID: b.OrganizationID, TenderOrganizationID: tender.OrganizationID, TenderStatus: tender.Status}
+}
+
+// QuestionResource describes a question asked about tender.
+func QuestionResource(q *models.TenderQuestion, tender *models.Tender) Resource {
+	return Resource{Type: ResourceQuestion, ID: q.ID, OrganizationID: q.AskingOrganization(), TenderOrganizationID: tender.OrganizationID, TenderStatus: tender.Status}
+}
+
+// OrganizationResource describes an organization.
+func OrganizationResource(id uuid.UUID) Resource {
+	return Resource{Type: ResourceOrganization, ID: id, OrganizationID: id}
+}
+
+// UserResource describes a resource of typ that belongs to userID as a
+// whole, such as their history.
+func UserResource(typ ResourceType, userID uuid.UUID) Resource {
+	return Resource{Type: typ, ID: userID, OwnerID: userID}
+}
+
+// subject is a principal together with its roles in organizations.
+type subject struct {
+	*models.Principal
+	orgs map[uuid.UUID]models.OrgRole
+}
+
+// memberOf reports whether the subject belongs to the organization with one
+// of roles, or with any role when none are given.
+func (s subject) memberOf(organizationID uuid.UUID, roles ...models.OrgRole) bool {
+	role, ok := s.orgs[organizationID]
+	if !ok || len(roles) == 0 {
+		return ok
+	}
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// rule decides whether s may perform an action on res.
+type rule func(s subject, res Resource) bool
+
+func owner(s subject, res Resource) bool {
+	return s.UserID == res.OwnerID
+}
+
+// tenderManager may change the tender: an owner or manager of its client
+// organization.
+func tenderManager(s subject, res Resource) bool {
+	return s.HasRole(models.RoleClient) && s.memberOf(res.TenderOrganizationID, models.OrgRoleOwner, models.OrgRoleManager)
+}
+
+func tenderTeam(s subject, res Resource) bool {
+	return s.memberOf(res.TenderOrganizationID)
+}
+
+// bidder may act for the contractor organization of a bid.
+func bidder(s subject, res Resource) bool {
+	return s.HasRole(models.RoleContractor) && s.memberOf(res.OrganizationID, models.OrgRoleOwner, models.OrgRoleManager, models.OrgRoleBidder)
+}
+
+func openToContractors(s subject, res Resource) bool {
+	return s.HasRole(models.RoleContractor) && res.TenderStatus == models.TenderStatusOpen
+}
+
+// openToOutsiders are contractors while the tender is open, unless they
+// belong to its organization.
+func openToOutsiders(s subject, res Resource) bool {
+	return openToContractors(s, res) && !tenderTeam(s, res)
+}
+
+func anyOf(rules ...rule) rule {
+	return func(s subject, res Resource) bool {
+		for _, r := range rules {
+			if r(s, res) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// defaultRules is the permission table. Admins are allowed everything and
+// anything not listed here is denied.
+var defaultRules = map[ResourceType]map[Action]rule{
+	ResourceTender: {
+		ActionCreate:        tenderManager,
+		ActionView:          anyOf(tenderTeam, openToContractors),
+		ActionUpdate:        tenderManager,
+		ActionDelete:        tenderManager,
+		ActionAward:         tenderManager,
+		ActionListBids:      tenderTeam,
+		ActionBid:           openToOutsiders,
+		ActionWatch:         openToContractors,
+		ActionAsk:           openToOutsiders,
+		ActionListQuestions: tenderTeam,
+	},
+	ResourceBid: {
+		ActionCreate: bidder,
+		ActionView: anyOf(tenderTeam, func(s subject, res Resource) bool {
+			return s.memberOf(res.OrganizationID)
+		}),
+		ActionUpdate: tenderManager,
+		ActionDelete: func(s subject, res Resource) bool {
+			return bidder(s, res) && res.TenderStatus == models.TenderStatusOpen
+		},
+	},
+	ResourceQuestion: {
+		ActionCreate: bidder,
+		ActionUpdate: tenderManager,
+	},
+	ResourceOrganization: {
+		ActionView: func(s subject, res Resource) bool {
+			return s.memberOf(res.OrganizationID)
+		},
+		ActionUpdate: func(s subject, res Resource) bool {
+			return s.memberOf(res.OrganizationID, models.OrgRoleOwner)
+		},
+		ActionManageMembers: func(s subject, res Resource) bool {
+			return s.memberOf(res.OrganizationID, models.OrgRoleOwner)
+		},
+	},
+	ResourceTenderHistory: {
+		ActionView: owner,
+	},
+	ResourceBidHistory: {
+		ActionView: owner,
+	},
+	ResourceNotifications: {
+		ActionView: owner,
+	},
+}
+
+// Authorizer decides whether the caller of a request may perform an action
+// on a resource, based on its roles, its organization memberships and the
+// ownership attributes of the resource. It complements the casbin route
+// policy, which only knows paths.
+type Authorizer struct {
+	orgRepo repository.OrganizationRepository
+	rules   map[ResourceType]map[Action]rule
+}
+
+func NewAuthorizer(orgRepo repository.OrganizationRepository) *Authorizer {
+	return &Authorizer{orgRepo: orgRepo, rules: defaultRules}
+}
+
+// Authorize checks action on res for the principal stored in ctx. It returns
+// ErrUnauthorized when ctx carries no principal and ErrForbidden when the
+// principal is not allowed.
+func (a *Authorizer) Authorize(ctx context.Context, action Action, res Resource) error {
+	p, ok := models.PrincipalFromContext(ctx)
+	if !ok {
+		return ErrUnauthorized
+	}
+	if p.HasRole(models.RoleAdmin) {
+		return nil
+	}
+	r, ok := a.rules[res.Type][action]
+	if !ok {
+		return ErrForbidden
+	}
+
+	orgs, err := a.orgRepo.ListForUser(ctx, p.UserID)
+	if err != nil {
+		return err
+	}
+	s := subject{Principal: p, orgs: make(map[uuid.UUID]models.OrgRole, len(orgs))}
+	for _, org := range orgs {
+		s.orgs[org.ID] = org.Role
+	}
+
+	if !r(s, res) {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/google/uuid"
+)
+
+// fakeOrgRepo returns fixed memberships. The other methods of the interface
+// are not used by the authorizer and panic.
+type fakeOrgRepo struct {
+	repository.OrganizationRepository
+	orgs map[uuid.UUID]models.OrgRole
+}
+
+func (r fakeOrgRepo) ListForUser(ctx context.Context, userID uuid.UUID) ([]models.Organization, error) {
+	var orgs []models.Organization
+	for id, role := range r.orgs {
+		orgs = append(orgs, models.Organization{ID: id, Role: role})
+	}
+	return orgs, nil
+}
+
+func TestAuthorize(t *testing.T) {
+	var (
+		userID        = uuid.New()
+		otherUserID   = uuid.New()
+		clientOrg     = uuid.New()
+		contractorOrg = uuid.New()
+		otherOrg      = uuid.New()
+	)
+	tender := func(status models.TenderStatus) Resource {
+		return Resource{Type: ResourceTender, OrganizationID: clientOrg, TenderOrganizationID: clientOrg, TenderStatus: status}
+	}
+	bidOn := func(org uuid.UUID, status models.TenderStatus) Resource {
+		return Resource{Type: ResourceBid, OrganizationID: org, TenderOrganizationID: clientOrg, TenderStatus: status}
+	}
+	bid := func(org uuid.UUID) Resource {
+		return bidOn(org, models.TenderStatusOpen)
+	}
+	question := func(org uuid.UUID) Resource {
+		return Resource{Type: ResourceQuestion, OrganizationID: org, TenderOrganizationID: clientOrg, TenderStatus: models.TenderStatusOpen}
+	}
+	client := []models.UserRole{models.RoleClient}
+	contractor := []models.UserRole{models.RoleContractor}
+	both := []models.UserRole{models.RoleClient, models.RoleContractor}
+
+	tests := []struct {
+		name   string
+		roles  []models.UserRole
+		orgs   map[uuid.UUID]models.OrgRole
+		action Action
+		res    Resource
+		allow  bool
+	}{
+		{"admin bypasses the table", []models.UserRole{models.RoleAdmin}, nil, ActionDelete, tender(models.TenderStatusAwarded), true},
+		{"admin bypasses unlisted actions", []models.UserRole{models.RoleAdmin}, nil, ActionManageMembers, tender(models.TenderStatusOpen), true},
+
+		{"owner manages the tender", client, map[uuid.UUID]models.OrgRole{clientOrg: models.OrgRoleOwner}, ActionUpdate, tender(models.TenderStatusOpen), true},
+		{"manager awards the tender", client, map[uuid.UUID]models.OrgRole{clientOrg: models.OrgRoleManager}, ActionAward, tender(models.TenderStatusEvaluating), true},
+		{"viewer does not manage the tender", client, map[uuid.UUID]models.OrgRole{clientOrg: models.OrgRoleViewer}, ActionUpdate, tender(models.TenderStatusOpen), false},
+		{"viewer is on the tender team", client, map[uuid.UUID]models.OrgRole{clientOrg: models.OrgRoleViewer}, ActionListBids, tender(models.TenderStatusOpen), true},
+		{"viewer views a draft", client, map[uuid.UUID]models.OrgRole{clientOrg: models.OrgRoleViewer}, ActionView, tender(models.TenderStatusDraft), true},
+		{"manager without the client role does not manage", contractor, map[uuid.UUID]models.OrgRole{clientOrg: models.OrgRoleManager}, ActionDelete, tender(models.TenderStatusOpen), false},
+		{"outsider does not list bids", client, map[uuid.UUID]models.OrgRole{otherOrg: models.OrgRoleOwner}, ActionListBids, tender(models.TenderStatusOpen), false},
+
+		{"contractor views an open tender", contractor, nil, ActionView, tender(models.TenderStatusOpen), true},
+		{"contractor does not view a closed tender", contractor, nil, ActionView, tender(models.TenderStatusClosed), false},
+		{"contractor does not view an awarded tender", contractor, nil, ActionView, tender(models.TenderStatusAwarded), false},
+		{"contractor does not view a draft", contractor, nil, ActionView, tender(models.TenderStatusDraft), false},
+		{"contractor does not view a cancelled tender", contractor, nil, ActionView, tender(models.TenderStatusCancelled), false},
+		{"contractor bids on an open tender", contractor, nil, ActionBid, tender(models.TenderStatusOpen), true},
+		{"contractor does not bid on a closed tender", contractor, nil, ActionBid, tender(models.TenderStatusClosed), false},
+		{"contractor does not bid on their own tender", both, map[uuid.UUID]models.OrgRole{clientOrg: models.OrgRoleViewer}, ActionBid, tender(models.TenderStatusOpen), false},
+		{"contractor asks about an open tender", contractor, nil, ActionAsk, tender(models.TenderStatusOpen), true},
+		{"contractor does not ask about their own tender", both, map[uuid.UUID]models.OrgRole{clientOrg: models.OrgRoleOwner}, ActionAsk, tender(models.TenderStatusOpen), false},
+		{"contractor watches an open tender", contractor, nil, ActionWatch, tender(models.TenderStatusOpen), true},
+		{"contractor does not watch a closed tender", contractor, nil, ActionWatch, tender(models.TenderStatusClosed), false},
+		{"contractor does not list questions", contractor, nil, ActionListQuestions, tender(models.TenderStatusOpen), false},
+
+		{"bidder creates a bid", contractor, map[uuid.UUID]models.OrgRole{contractorOrg: models.OrgRoleBidder}, ActionCreate, bid(contractorOrg), true},
+		{"viewer does not create a bid", contractor, map[uuid.UUID]models.OrgRole{contractorOrg: models.OrgRoleViewer}, ActionCreate, bid(contractorOrg), false},
+		{"member views a bid of their organization", contractor, map[uuid.UUID]models.OrgRole{contractorOrg: models.OrgRoleViewer}, ActionView, bid(contractorOrg), true},
+		{"tender team views a bid", client, map[uuid.UUID]models.OrgRole{clientOrg: models.OrgRoleViewer}, ActionView, bid(contractorOrg), true},
+		{"other contractor does not view a bid", contractor, map[uuid.UUID]models.OrgRole{otherOrg: models.OrgRoleOwner}, ActionView, bid(contractorOrg), false},
+		{"bidder withdraws a bid on an open tender", contractor, map[uuid.UUID]models.OrgRole{contractorOrg: models.OrgRoleBidder}, ActionDelete, bid(contractorOrg), true},
+		{"bidder does not withdraw a bid on a closed tender", contractor, map[uuid.UUID]models.OrgRole{contractorOrg: models.OrgRoleBidder}, ActionDelete, bidOn(contractorOrg, models.TenderStatusClosed), false},
+		{"bidder does not withdraw a bid on an evaluating tender", contractor, map[uuid.UUID]models.OrgRole{contractorOrg: models.OrgRoleOwner}, ActionDelete, bidOn(contractorOrg, models.TenderStatusEvaluating), false},
+		{"bidder does not withdraw a bid on an awarded tender", contractor, map[uuid.UUID]models.OrgRole{contractorOrg: models.OrgRoleOwner}, ActionDelete, bidOn(contractorOrg, models.TenderStatusAwarded), false},
+		{"other contractor does not delete a bid", contractor, map[uuid.UUID]models.OrgRole{otherOrg: models.OrgRoleOwner}, ActionDelete, bid(contractorOrg), false},
+		{"tender manager updates a bid", client, map[uuid.UUID]models.OrgRole{clientOrg: models.OrgRoleManager}, ActionUpdate, bid(contractorOrg), true},
+
+		{"bidder asks for their organization", contractor, map[uuid.UUID]models.OrgRole{contractorOrg: models.OrgRoleBidder}, ActionCreate, question(contractorOrg), true},
+		{"contractor does not ask for another organization", contractor, map[uuid.UUID]models.OrgRole{contractorOrg: models.OrgRoleOwner}, ActionCreate, question(otherOrg), false},
+		{"tender manager answers", client, map[uuid.UUID]models.OrgRole{clientOrg: models.OrgRoleManager}, ActionUpdate, question(contractorOrg), true},
+		{"tender viewer does not answer", client, map[uuid.UUID]models.OrgRole{clientOrg: models.OrgRoleViewer}, ActionUpdate, question(contractorOrg), false},
+		{"anonymized question belongs to no organization", contractor, map[uuid.UUID]models.OrgRole{contractorOrg: models.OrgRoleOwner}, ActionCreate, QuestionResource(&models.TenderQuestion{}, &models.Tender{OrganizationID: clientOrg, Status: models.TenderStatusOpen}), false},
+
+		{"member views the organization", contractor, map[uuid.UUID]models.OrgRole{contractorOrg: models.OrgRoleBidder}, ActionView, OrganizationResource(contractorOrg), true},
+		{"manager does not manage members", client, map[uuid.UUID]models.OrgRole{clientOrg: models.OrgRoleManager}, ActionManageMembers, OrganizationResource(clientOrg), false},
+		{"owner manages members", client, map[uuid.UUID]models.OrgRole{clientOrg: models.OrgRoleOwner}, ActionManageMembers, OrganizationResource(clientOrg), true},
+
+		{"user views their tender history", client, nil, ActionView, UserResource(ResourceTenderHistory, userID), true},
+		{"user does not view another tender history", client, nil, ActionView, UserResource(ResourceTenderHistory, otherUserID), false},
+		{"user views their bid history", contractor, nil, ActionView, UserResource(ResourceBidHistory, userID), true},
+		{"user does not view another bid history", contractor, nil, ActionView, UserResource(ResourceBidHistory, otherUserID), false},
+		{"user opens their notifications", contractor, nil, ActionView, UserResource(ResourceNotifications, userID), true},
+		{"user does not open other notifications", contractor, nil, ActionView, UserResource(ResourceNotifications, otherUserID), false},
+
+		{"unlisted action is denied", client, map[uuid.UUID]models.OrgRole{clientOrg: models.OrgRoleOwner}, ActionBid, OrganizationResource(clientOrg), false},
+		{"unlisted resource is denied", client, nil, ActionView, Resource{Type: "unknown"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAuthorizer(fakeOrgRepo{orgs: tt.orgs})
+			ctx := models.ContextWithPrincipal(context.Background(), &models.Principal{UserID: userID, Roles: tt.roles})
+
+			err := a.Authorize(ctx, tt.action, tt.res)
+			switch {
+			case tt.allow && err != nil:
+				t.Errorf("Authorize(%s, %s) = %v, want allowed", tt.action, tt.res.Type, err)
+			case !tt.allow && !errors.Is(err, ErrForbidden):
+				t.Errorf("Authorize(%s, %s) = %v, want ErrForbidden", tt.action, tt.res.Type, err)
+			}
+		})
+	}
+}
+
+func TestAuthorizeWithoutPrincipal(t *testing.T) {
+	a := NewAuthorizer(fakeOrgRepo{})
+	if err := a.Authorize(context.Background(), ActionView, UserResource(ResourceNotifications, uuid.New())); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Authorize without principal = %v, want ErrUnauthorized", err)
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/Dostonlv/hackathon-nt/internal/models"
@@ -11,34 +12,42 @@ import (
 )
 
 var (
-	ErrInvalidTender     = errors.New("invalid tender")
-	ErrInvalidContractor = errors.New("invalid contractor")
-	ErrBidNotFound       = errors.New("bid not found")
+	ErrInvalidTender = errors.New("invalid tender")
+	ErrBidNotFound   = errors.New("bid not found")
+	ErrBiddingClosed = errors.New("the deadline of the tender has passed")
+	ErrBidLocked     = errors.New("bids can only be withdrawn while the tender is open")
 )
 
 type CreateBidInput struct {
 	TenderID     uuid.UUID
 	ContractorID uuid.UUID
-	Price        float64
-	DeliveryTime int
-	Comments     string
+	// OrganizationID is the contractor organization the bid belongs to. It
+	// may be left out by members of a single contractor organization.
+	OrganizationID *uuid.UUID
+	Price          float64
+	DeliveryTime   int
+	Comments       string
 }
 
 type BidService struct {
-	bidRepo    repository.BidRepository
-	tenderRepo repository.TenderRepository
+	bidRepo       repository.BidRepository
+	tenderRepo    repository.TenderRepository
+	authorizer    *Authorizer
+	organizations *OrganizationService
 }
 
-func NewBidService(bidRepo repository.BidRepository, tenderRepo repository.TenderRepository) *BidService {
+func NewBidService(bidRepo repository.BidRepository, tenderRepo repository.TenderRepository, authorizer *Authorizer, organizations *OrganizationService) *BidService {
 	return &BidService{
-		bidRepo:    bidRepo,
-		tenderRepo: tenderRepo,
+		bidRepo:       bidRepo,
+		tenderRepo:    tenderRepo,
+		authorizer:    authorizer,
+		organizations: organizations,
 	}
 }
 
 func (s *BidService) CreateBid(ctx context.Context, input CreateBidInput) (*models.Bid, error) {
 	// Check if tender exists
-	tender, err := s.tenderRepo.GetByID(ctx, input.TenderID)
+	tender, err := s.getTender(ctx, input.TenderID)
 	if err != nil {
 		return nil, err
 	}
@@ -46,51 +55,51 @@ func (s *BidService) CreateBid(ctx context.Context, input CreateBidInput) (*mode
 	if tender.Status != models.TenderStatusOpen {
 		return nil, ErrInvalidTender
 	}
-
-	bid := &models.Bid{
-		ID:           uuid.New(),
-		TenderID:     input.TenderID,
-		ContractorID: input.ContractorID,
-		Price:        input.Price,
-		DeliveryTime: input.DeliveryTime,
-		Comments:     input.Comments,
-		Status:       "open",
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+	// The scheduler closes expired tenders only every so often
+	if !tender.Deadline.After(time.Now()) {
+		return nil, ErrBiddingClosed
 	}
-
-	if err := s.bidRepo.Create(ctx, bid); err != nil {
+	if err := s.authorizer.Authorize(ctx, ActionBid, TenderResource(tender)); err != nil {
 		return nil, err
 	}
 
-	return bid, nil
-}
-
-func (s *BidService) ListBids(ctx context.Context, tenderID uuid.UUID, filters repository.BidFilters) ([]models.Bid, error) {
-	// Get bids with filters
-	bids, err := s.bidRepo.ListByTenderID(ctx, tenderID, filters)
+	org, err := s.organizations.Resolve(ctx, models.OrganizationContractor, input.OrganizationID)
 	if err != nil {
 		return nil, err
 	}
 
-	return bids, nil
-}
-
-// Additional helper methods that might be useful
-
-func (s *BidService) GetBidByID(ctx context.Context, bidID uuid.UUID) (*models.Bid, error) {
-	bid, err := s.bidRepo.GetByID(ctx, bidID)
-	if err != nil {
+	bid := &models.Bid{
+		ID:             uuid.New(),
+		TenderID:       input.TenderID,
+		ContractorID:   input.ContractorID,
+		OrganizationID: org.ID,
+		Price:          input.Price,
+		DeliveryTime:   input.DeliveryTime,
+		Comments:       input.Comments,
+		Status:         "open",
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	if err := s.authorizer.Authorize(ctx, ActionCreate, BidResource(bid, tender)); err != nil {
 		return nil, err
 	}
-	if bid == nil {
-		return nil, ErrBidNotFound
+
+	if err := s.bidRepo.Create(ctx, bid); err != nil {
+		return nil, err
 	}
+
 	return bid, nil
 }
 
+// GetBidByID returns a bid the caller may view: their own, or one placed on
+// their tender.
+func (s *BidService) GetBidByID(ctx context.Context, bidID uuid.UUID) (*models.Bid, error) {
+	bid, _, err := s.authorizedBid(ctx, ActionView, bidID)
+	return bid, err
+}
+
 func (s *BidService) UpdateBidStatus(ctx context.Context, bidID uuid.UUID, status string) error {
-	bid, err := s.GetBidByID(ctx, bidID)
+	bid, _, err := s.authorizedBid(ctx, ActionUpdate, bidID)
 	if err != nil {
 		return err
 	}
@@ -100,76 +109,164 @@ func (s *BidService) UpdateBidStatus(ctx context.Context, bidID uuid.UUID, statu
 
 	return s.bidRepo.Update(ctx, bid)
 }
-func (s *BidService) GetBidsByContractorID(ctx context.Context, contractorID uuid.UUID) ([]models.Bid, error) {
-	bids, err := s.bidRepo.ListByContractorID(ctx, contractorID)
+
+// ListContractorBids lists the bids of every contractor organization of the
+// caller.
+func (s *BidService) ListContractorBids(ctx context.Context, page repository.PageRequest) (*models.Page[models.Bid], error) {
+	ids, err := s.organizations.MemberOrganizationIDs(ctx, models.OrganizationContractor)
 	if err != nil {
 		return nil, err
 	}
-	return bids, nil
+	return s.bidRepo.ListByOrganizationIDs(ctx, ids, page)
 }
 
-func (s *BidService) GetBidsByClientID(ctx context.Context, clientID, tenderID uuid.UUID) ([]models.Bid, error) {
-	bids, err := s.bidRepo.ListByClientTenderID(ctx, clientID, tenderID)
+// GetTenderBids lists the bids on a tender of the caller's organization.
+// With a search only the bids whose comments match it are listed, best
+// match first.
+func (s *BidService) GetTenderBids(ctx context.Context, tenderID uuid.UUID, search string, page repository.PageRequest) (*models.Page[models.Bid], error) {
+	tender, err := s.authorizedTender(ctx, ActionListBids, tenderID)
+	if err != nil {
+		return nil, err
+	}
+	if search = strings.TrimSpace(search); search != "" {
+		return s.bidRepo.SearchByTenderID(ctx, tenderID, search, page)
+	}
+
+	bids, err := s.bidRepo.ListByOrganizationTenderID(ctx, tender.OrganizationID, tenderID, page)
 	if err != nil {
 		return nil, err
 	}
 	return bids, nil
 }
 
-func (s *BidService) AwardBid(ctx context.Context, clientID, tenderID, bidID uuid.UUID) error {
-	// Check if tender exists
-	tender, err := s.tenderRepo.GetByID(ctx, tenderID)
+// AwardBid awards a bid of a closed or evaluated tender owned by the caller
+// and moves the tender to awarded.
+func (s *BidService) AwardBid(ctx context.Context, tenderID, bidID uuid.UUID) error {
+	tender, err := s.authorizedTender(ctx, ActionAward, tenderID)
 	if err != nil {
 		return err
 	}
 
-	if tender.Status != models.TenderStatusOpen {
-		return ErrInvalidTender
+	to, ok := models.TenderTransitionAward.Target(tender.Status)
+	if !ok {
+		return ErrInvalidTransition
 	}
 
 	// Check if bid exists
-	bid, err := s.GetBidByID(ctx, bidID)
+	bid, err := s.getBid(ctx, bidID)
 	if err != nil {
 		return err
 	}
 
 	if bid.TenderID != tenderID {
-		return ErrInvalidTender
+		return ErrBidNotFound
 	}
 
 	// Award the bid
-	err = s.bidRepo.AwardBidByTenderID(ctx, clientID, tenderID, bidID)
+	err = s.bidRepo.Award(ctx, bidID, newStatusChange(ctx, tender, models.TenderTransitionAward, to, ""))
 	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return ErrBidNotFound
+		case errors.Is(err, repository.ErrConflict):
+			return ErrInvalidTransition
+		}
 		return err
 	}
 
 	return nil
 }
 
-func (s *BidService) DeleteBidByContractorID(ctx context.Context, contractorID, bidID uuid.UUID) error {
-	// Check if bid exists
-	bid, err := s.GetBidByID(ctx, bidID)
+// DeleteBid withdraws a bid of the caller's organization while its tender
+// is open.
+func (s *BidService) DeleteBid(ctx context.Context, bidID uuid.UUID) error {
+	bid, tender, err := s.authorizedBid(ctx, ActionView, bidID)
 	if err != nil {
 		return err
 	}
-
-	if bid.ContractorID != contractorID {
-		return ErrInvalidContractor
+	if tender.Status != models.TenderStatusOpen {
+		return ErrBidLocked
+	}
+	if err := s.authorizer.Authorize(ctx, ActionDelete, BidResource(bid, tender)); err != nil {
+		return err
 	}
 
 	// Delete the bid
-	err = s.bidRepo.DeleteByContractorID(ctx, contractorID, bidID)
+	err = s.bidRepo.Delete(ctx, bidID)
 	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrBidNotFound
+		}
 		return err
 	}
 
 	return nil
 }
 
-func (s *BidService) GetClientIDByTenderID(ctx context.Context, tenderID uuid.UUID) (uuid.UUID, error) {
-	clientID, err := s.tenderRepo.GetClientIDByTenderID(ctx, tenderID)
+// TenderTeam returns the members of the organization that owns a tender,
+// who are notified of new bids.
+func (s *BidService) TenderTeam(ctx context.Context, tenderID uuid.UUID) ([]uuid.UUID, error) {
+	tender, err := s.getTender(ctx, tenderID)
+	if err != nil {
+		return nil, err
+	}
+	return s.organizations.memberIDs(ctx, tender.OrganizationID)
+}
+
+// BidTeam returns the members of the organization that placed a bid, who
+// are notified when it is awarded.
+func (s *BidService) BidTeam(ctx context.Context, bid *models.Bid) ([]uuid.UUID, error) {
+	return s.organizations.memberIDs(ctx, bid.OrganizationID)
+}
+
+func (s *BidService) getTender(ctx context.Context, id uuid.UUID) (*models.Tender, error) {
+	tender, err := s.tenderRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTenderNotFound
+		}
+		return nil, err
+	}
+	return tender, nil
+}
+
+func (s *BidService) getBid(ctx context.Context, id uuid.UUID) (*models.Bid, error) {
+	bid, err := s.bidRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if bid == nil {
+		return nil, ErrBidNotFound
+	}
+	return bid, nil
+}
+
+// authorizedTender loads a tender and checks that the caller may perform
+// action on it.
+func (s *BidService) authorizedTender(ctx context.Context, action Action, id uuid.UUID) (*models.Tender, error) {
+	tender, err := s.getTender(ctx, id)
 	if err != nil {
-		return uuid.Nil, err
+		return nil, err
+	}
+	if err := s.authorizer.Authorize(ctx, action, TenderResource(tender)); err != nil {
+		return nil, err
+	}
+	return tender, nil
+}
+
+// authorizedBid loads a bid with its tender and checks that the caller may
+// perform action on the bid.
+func (s *BidService) authorizedBid(ctx context.Context, action Action, id uuid.UUID) (*models.Bid, *models.Tender, error) {
+	bid, err := s.getBid(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	tender, err := s.getTender(ctx, bid.TenderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.authorizer.Authorize(ctx, action, BidResource(bid, tender)); err != nil {
+		return nil, nil, err
 	}
-	return clientID, nil
+	return bid, tender, nil
 }
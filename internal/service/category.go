@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+)
+
+var (
+	ErrUnknownCategory = errors.New("unknown category")
+	ErrTooManyTags     = fmt.Errorf("a tender has at most %d tags", maxTenderTags)
+	ErrInvalidTag      = fmt.Errorf("tags are 1 to %d characters without commas or control characters", maxTagLength)
+)
+
+const (
+	maxTenderTags = 10
+	maxTagLength  = 32
+)
+
+// ListCategories returns the category taxonomy, each category right before
+// its subcategories.
+func (s *TenderService) ListCategories(ctx context.Context) ([]models.Category, error) {
+	return s.categories.List(ctx)
+}
+
+// checkCategory checks that code, when set, names a category.
+func (s *TenderService) checkCategory(ctx context.Context, code *string) error {
+	if code == nil {
+		return nil
+	}
+	if _, err := s.categories.GetByCode(ctx, *code); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: %w %q", ErrInvalidInput, ErrUnknownCategory, *code)
+		}
+		return err
+	}
+	return nil
+}
+
+// categoryCode treats an empty code as no category.
+func categoryCode(code *string) *string {
+	if code == nil || strings.TrimSpace(*code) == "" {
+		return nil
+	}
+	c := strings.TrimSpace(*code)
+	return &c
+}
+
+// normalizeTags normalizes every tag and drops duplicates, keeping the
+// order. The result is never nil.
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTenderTags {
+		return nil, ErrTooManyTags
+	}
+	return normalized, nil
+}
+
+// normalizeTag lowercases a tag and collapses its runs of white space into
+// single spaces, so that "Road  Works" and "road works" are the same tag.
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+	if tag == "" || !utf8.ValidString(tag) || utf8.RuneCountInString(tag) > maxTagLength || strings.ContainsRune(tag, ',') {
+		return "", ErrInvalidTag
+	}
+	for _, r := range tag {
+		if unicode.IsControl(r) {
+			return "", ErrInvalidTag
+		}
+	}
+	return tag, nil
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrQuestionNotFound     = errors.New("question not found")
+	ErrClarificationsClosed = errors.New("the tender no longer takes questions")
+	ErrQuestionAnswered     = errors.New("the question has been answered already")
+)
+
+// maxClarificationLength is the longest question or answer, in characters.
+const maxClarificationLength = 2000
+
+// ClarificationConfig controls clarification questions.
+type ClarificationConfig struct {
+	// CloseBefore is how long before the deadline of a tender it stops
+	// taking questions.
+	CloseBefore time.Duration
+}
+
+// ClarificationService lets contractors ask questions about open tenders
+// and the client answer them, either publicly for every contractor or
+// privately for the organization that asked.
+type ClarificationService struct {
+	repo          repository.ClarificationRepository
+	tenders       repository.TenderRepository
+	bids          repository.BidRepository
+	authorizer    *Authorizer
+	organizations *OrganizationService
+	cfg           ClarificationConfig
+}
+
+func NewClarificationService(repo repository.ClarificationRepository, tenders repository.TenderRepository, bids repository.BidRepository, authorizer *Authorizer, organizations *OrganizationService, cfg ClarificationConfig) *ClarificationService {
+	return &ClarificationService{
+		repo:          repo,
+		tenders:       tenders,
+		bids:          bids,
+		authorizer:    authorizer,
+		organizations: organizations,
+		cfg:           cfg,
+	}
+}
+
+type AskQuestionInput struct {
+	TenderID uuid.UUID
+	// OrganizationID is the contractor organization asking. It may be left
+	// out by members of a single contractor organization.
+	OrganizationID *uuid.UUID
+	Question       string
+}
+
+type AnswerQuestionInput struct {
+	TenderID   uuid.UUID
+	QuestionID uuid.UUID
+	Answer     string
+	Visibility models.AnswerVisibility
+}
+
+// QuestionUpdate is the result of asking or answering a question.
+type QuestionUpdate struct {
+	Tender   *models.Tender
+	Question *models.TenderQuestion
+	// Recipients are the users to notify: the client team of a new
+	// question, the asking organization of an answer, and the bidders and
+	// watchers of the tender as well when the answer is public.
+	Recipients []uuid.UUID
+}
+
+// Ask records a question of the caller about an open tender of another
+// organization, until the clarification deadline of the tender.
+func (s *ClarificationService) Ask(ctx context.Context, input AskQuestionInput) (*QuestionUpdate, error) {
+	p, ok := models.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	text, err := clarificationText(input.Question, "question")
+	if err != nil {
+		return nil, err
+	}
+
+	tender, err := s.getTender(ctx, input.TenderID)
+	if err != nil {
+		return nil, err
+	}
+	if tender.Status != models.TenderStatusOpen {
+		return nil, ErrInvalidTender
+	}
+	if !time.Now().Before(s.closesAt(tender)) {
+		return nil, ErrClarificationsClosed
+	}
+	if err := s.authorizer.Authorize(ctx, ActionAsk, TenderResource(tender)); err != nil {
+		return nil, err
+	}
+
+	org, err := s.organizations.Resolve(ctx, models.OrganizationContractor, input.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	question := &models.TenderQuestion{
+		ID:             uuid.New(),
+		TenderID:       tender.ID,
+		AskedBy:        &p.UserID,
+		OrganizationID: &org.ID,
+		Question:       text,
+		CreatedAt:      time.Now(),
+	}
+	if err := s.authorizer.Authorize(ctx, ActionCreate, QuestionResource(question, tender)); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, question); err != nil {
+		return nil, err
+	}
+
+	team, err := s.organizations.memberIDs(ctx, tender.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	return &QuestionUpdate{Tender: tender, Question: question, Recipients: team}, nil
+}
+
+// ListForContractor returns the questions about a tender the caller may
+// view: the publicly answered ones, without who asked them, and the ones
+// their organizations asked.
+func (s *ClarificationService) ListForContractor(ctx context.Context, tenderID uuid.UUID) (*models.TenderClarifications, error) {
+	tender, err := s.authorizedTender(ctx, ActionView, tenderID)
+	if err != nil {
+		return nil, err
+	}
+	orgIDs, err := s.organizations.MemberOrganizationIDs(ctx, models.OrganizationContractor)
+	if err != nil {
+		return nil, err
+	}
+	questions, err := s.repo.ListVisible(ctx, tenderID, orgIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	own := make(map[uuid.UUID]bool, len(orgIDs))
+	for _, id := range orgIDs {
+		own[id] = true
+	}
+	for i := range questions {
+		q := &questions[i]
+		if own[q.AskingOrganization()] {
+			q.AnsweredBy = nil
+		} else {
+			*q = q.Anonymized()
+		}
+	}
+	return &models.TenderClarifications{ClosesAt: s.closesAt(tender), Questions: questions}, nil
+}
+
+// ListForClient returns every question about a tender of the caller's
+// organization.
+func (s *ClarificationService) ListForClient(ctx context.Context, tenderID uuid.UUID) (*models.TenderClarifications, error) {
+	tender, err := s.authorizedTender(ctx, ActionListQuestions, tenderID)
+	if err != nil {
+		return nil, err
+	}
+	questions, err := s.repo.ListByTenderID(ctx, tenderID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TenderClarifications{ClosesAt: s.closesAt(tender), Questions: questions}, nil
+}
+
+// Answer answers a question about an open tender the caller manages. A
+// question is answered once.
+func (s *ClarificationService) Answer(ctx context.Context, input AnswerQuestionInput) (*QuestionUpdate, error) {
+	p, ok := models.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	text, err := clarificationText(input.Answer, "answer")
+	if err != nil {
+		return nil, err
+	}
+	if !input.Visibility.IsValid() {
+		return nil, fmt.Errorf("%w: visibility must be public or private", ErrInvalidInput)
+	}
+
+	tender, err := s.authorizedTender(ctx, ActionListQuestions, input.TenderID)
+	if err != nil {
+		return nil, err
+	}
+	question, err := s.repo.GetByID(ctx, tender.ID, input.QuestionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrQuestionNotFound
+		}
+		return nil, err
+	}
+	if err := s.authorizer.Authorize(ctx, ActionUpdate, QuestionResource(question, tender)); err != nil {
+		return nil, err
+	}
+	if tender.Status != models.TenderStatusOpen {
+		return nil, ErrInvalidTender
+	}
+	if question.Answer != nil {
+		return nil, ErrQuestionAnswered
+	}
+
+	now := time.Now()
+	question.Answer = &text
+	question.Visibility = &input.Visibility
+	question.AnsweredBy = &p.UserID
+	question.AnsweredAt = &now
+	if err := s.repo.Answer(ctx, question); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrConflict):
+			return nil, ErrQuestionAnswered
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, ErrQuestionNotFound
+		}
+		return nil, err
+	}
+
+	var recipients []uuid.UUID
+	if org := question.AskingOrganization(); org != uuid.Nil {
+		recipients, err = s.organizations.memberIDs(ctx, org)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if input.Visibility == models.AnswerPublic {
+		recipients, err = tenderFollowers(ctx, s.organizations, s.bids, s.tenders, tender.ID, recipients)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &QuestionUpdate{Tender: tender, Question: question, Recipients: recipients}, nil
+}
+
+// closesAt is when a tender stops taking questions.
+func (s *ClarificationService) closesAt(tender *models.Tender) time.Time {
+	return tender.Deadline.Add(-s.cfg.CloseBefore)
+}
+
+func (s *ClarificationService) getTender(ctx context.Context, id uuid.UUID) (*models.Tender, error) {
+	tender, err := s.tenders.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTenderNotFound
+		}
+		return nil, err
+	}
+	return tender, nil
+}
+
+// authorizedTender loads a tender and checks that the caller may perform
+// action on it.
+func (s *ClarificationService) authorizedTender(ctx context.Context, action Action, id uuid.UUID) (*models.Tender, error) {
+	tender, err := s.getTender(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizer.Authorize(ctx, action, TenderResource(tender)); err != nil {
+		return nil, err
+	}
+	return tender, nil
+}
+
+// clarificationText trims a question or answer and checks its length.
+func clarificationText(text, what string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("%w: %s is required", ErrInvalidInput, what)
+	}
+	if utf8.RuneCountInString(text) > maxClarificationLength {
+		return "", fmt.Errorf("%w: %s must be at most %d characters", ErrInvalidInput, what, maxClarificationLength)
+	}
+	return text, nil
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/Dostonlv/hackathon-nt/internal/utils"
+	"github.com/google/uuid"
+)
+
+// DeadlineConfig controls the deadline scheduler.
+type DeadlineConfig struct {
+	// Interval is how often deadlines are checked.
+	Interval time.Duration
+	// Reminders are how long before the deadline of a tender reminders are
+	// sent, e.g. 24h and 1h.
+	Reminders []time.Duration
+	// BatchSize is how many tenders are closed or reminded per query.
+	BatchSize int
+}
+
+// DeadlineScheduler closes tenders whose deadline has passed and reminds the
+// client team, the bidders and the watchers of approaching deadlines. Every
+// replica may run it: tenders are claimed with row locks, so each is closed
+// and each reminder sent once.
+//
+// The scheduler acts on behalf of no one. It goes to the repositories
+// directly instead of through the authorizer, and its status changes have no
+// actor.
+type DeadlineScheduler struct {
+	tenders       repository.TenderRepository
+	bids          repository.BidRepository
+	organizations *OrganizationService
+	notifier      *utils.NotificationService
+	cfg           DeadlineConfig
+}
+
+func NewDeadlineScheduler(tenders repository.TenderRepository, bids repository.BidRepository, organizations *OrganizationService, notifier *utils.NotificationService, cfg DeadlineConfig) *DeadlineScheduler {
+	reminders := append([]time.Duration(nil), cfg.Reminders...)
+	// Longest lead first, see SendReminders
+	sort.Slice(reminders, func(i, j int) bool { return reminders[i] > reminders[j] })
+	cfg.Reminders = reminders
+
+	return &DeadlineScheduler{
+		tenders:       tenders,
+		bids:          bids,
+		organizations: organizations,
+		notifier:      notifier,
+		cfg:           cfg,
+	}
+}
+
+// Run closes expired tenders and sends reminders every Interval until ctx is
+// cancelled.
+func (s *DeadlineScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.CloseExpired(ctx); err != nil {
+				log.Printf("Failed to close expired tenders: %v", err)
+			}
+			if err := s.SendReminders(ctx); err != nil {
+				log.Printf("Failed to send deadline reminders: %v", err)
+			}
+		}
+	}
+}
+
+// CloseExpired closes every open tender whose deadline has passed and
+// notifies everyone following it.
+func (s *DeadlineScheduler) CloseExpired(ctx context.Context) error {
+	for {
+		closed, err := s.tenders.CloseExpired(ctx, time.Now(), s.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range closed {
+			t := &closed[i]
+			s.notify(ctx, t, utils.TenderNotification{
+				Type:     "tender_closed",
+				TenderID: t.ID,
+				Status:   string(models.TenderStatusClosed),
+				Deadline: &t.Deadline,
+				Message:  fmt.Sprintf("Tender %q closed at its deadline", t.Title),
+			})
+		}
+		if len(closed) < s.cfg.BatchSize {
+			return nil
+		}
+	}
+}
+
+// SendReminders sends the reminders that are due. A tender only gets the
+// reminder with the shortest lead it is within, so a tender created an hour
+// before its deadline is not reminded of it a day ahead as well.
+func (s *DeadlineScheduler) SendReminders(ctx context.Context) error {
+	now := time.Now()
+	for i, lead := range s.cfg.Reminders {
+		var next time.Duration
+		if i+1 < len(s.cfg.Reminders) {
+			next = s.cfg.Reminders[i+1]
+		}
+		for {
+			due, err := s.tenders.ClaimDeadlineReminders(ctx, lead, now.Add(next), now.Add(lead), now, s.cfg.BatchSize)
+			if err != nil {
+				return err
+			}
+			for j := range due {
+				t := &due[j]
+				s.notify(ctx, t, utils.TenderNotification{
+					Type:     "tender_deadline_reminder",
+					TenderID: t.ID,
+					Status:   string(t.Status),
+					Deadline: &t.Deadline,
+					Message:  fmt.Sprintf("Tender %q closes in %s", t.Title, t.Deadline.Sub(now).Round(time.Minute)),
+				})
+			}
+			if len(due) < s.cfg.BatchSize {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// notify sends notification to the client team, the bidders and the watchers
+// of a tender. Failures are logged; the tender has been handled either way.
+func (s *DeadlineScheduler) notify(ctx context.Context, tender *models.Tender, notification utils.TenderNotification) {
+	recipients, err := s.recipients(ctx, tender)
+	if err != nil {
+		log.Printf("Failed to list the recipients of tender %s: %v", tender.ID, err)
+		return
+	}
+	for _, userID := range recipients {
+		if err := s.notifier.NotifyTenderUpdate(ctx, userID, notification); err != nil {
+			log.Printf("Failed to send notification: %v", err)
+		}
+	}
+}
+
+func (s *DeadlineScheduler) recipients(ctx context.Context, tender *models.Tender) ([]uuid.UUID, error) {
+	team, err := s.organizations.memberIDs(ctx, tender.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	return tenderFollowers(ctx, s.organizations, s.bids, s.tenders, tender.ID, team)
+}
+
+// tenderFollowers returns users followed by the members of every
+// organization that bid on a tender and the users watching it, each once.
+func tenderFollowers(ctx context.Context, organizations *OrganizationService, bids repository.BidRepository, tenders repository.TenderRepository, tenderID uuid.UUID, users []uuid.UUID) ([]uuid.UUID, error) {
+	seen := make(map[uuid.UUID]bool)
+	var ids []uuid.UUID
+	add := func(users []uuid.UUID) {
+		for _, id := range users {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	add(users)
+
+	orgIDs, err := bids.ListBidderOrganizationIDs(ctx, tenderID)
+	if err != nil {
+		return nil, err
+	}
+	for _, orgID := range orgIDs {
+		members, err := organizations.memberIDs(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		add(members)
+	}
+
+	watchers, err := tenders.ListWatcherIDs(ctx, tenderID)
+	if err != nil {
+		return nil, err
+	}
+	add(watchers)
+	return ids, nil
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/Dostonlv/hackathon-nt/internal/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDocumentNotFound = errors.New("document not found")
+	ErrDocumentTooLarge = errors.New("the file exceeds the size limit")
+	ErrDocumentType     = errors.New("file type not allowed")
+	ErrTooManyDocuments = errors.New("the tender has the maximum number of documents")
+	ErrDocumentEmpty    = errors.New("the file is empty")
+	ErrDocumentFilename = errors.New("a file name of up to 255 characters is required")
+	ErrDocumentRead     = errors.New("error reading the uploaded file")
+)
+
+const (
+	maxDocumentFilenameLength = 255
+	// documentSniffedBytes is how much of a file http.DetectContentType
+	// looks at.
+	documentSniffedBytes = 512
+)
+
+// DocumentConfig holds the limits of tender documents.
+type DocumentConfig struct {
+	// MaxSize is the largest file accepted, in bytes.
+	MaxSize int64
+	// AllowedTypes are the media types accepted, as detected from the
+	// contents of a file rather than taken from the client.
+	AllowedTypes []string
+	MaxPerTender int
+}
+
+// DocumentService stores the files of tenders in a BlobStore and their
+// metadata in Postgres. Uploads are spooled to a temporary file while their
+// size and digest are computed, so no file is held in memory.
+type DocumentService struct {
+	repo       repository.DocumentRepository
+	tenders    repository.TenderRepository
+	blobs      utils.BlobStore
+	authorizer *Authorizer
+	cfg        DocumentConfig
+}
+
+func NewDocumentService(repo repository.DocumentRepository, tenders repository.TenderRepository, blobs utils.BlobStore, authorizer *Authorizer, cfg DocumentConfig) *DocumentService {
+	return &DocumentService{
+		repo:       repo,
+		tenders:    tenders,
+		blobs:      blobs,
+		authorizer: authorizer,
+		cfg:        cfg,
+	}
+}
+
+type UploadDocumentInput struct {
+	TenderID uuid.UUID
+	// Filename is the name the file is downloaded as. Only its last path
+	// element is kept.
+	Filename string
+	Content  io.Reader
+}
+
+// Upload stores a file for a tender the caller manages.
+func (s *DocumentService) Upload(ctx context.Context, input UploadDocumentInput) (*models.TenderDocument, error) {
+	p, ok := models.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	tender, err := s.authorizedTender(ctx, ActionUpdate, input.TenderID)
+	if err != nil {
+		return nil, err
+	}
+	if !tender.Status.Editable() {
+		return nil, ErrTenderLocked
+	}
+
+	filename := documentFilename(input.Filename)
+	if filename == "" {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, ErrDocumentFilename)
+	}
+	count, err := s.repo.CountByTenderID(ctx, tender.ID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= s.cfg.MaxPerTender {
+		return nil, ErrTooManyDocuments
+	}
+
+	tmp, err := os.CreateTemp("", "tender-document-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(input.Content, s.cfg.MaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDocumentRead, err)
+	}
+	if size > s.cfg.MaxSize {
+		return nil, ErrDocumentTooLarge
+	}
+	if size == 0 {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, ErrDocumentEmpty)
+	}
+
+	contentType, err := s.detectContentType(tmp)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	doc := &models.TenderDocument{
+		ID:          uuid.New(),
+		TenderID:    tender.ID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		UploadedBy:  p.UserID,
+		CreatedAt:   time.Now(),
+	}
+	doc.StorageKey = "tenders/" + tender.ID.String() + "/" + doc.ID.String()
+
+	if err := s.blobs.Put(ctx, doc.StorageKey, tmp, size, contentType); err != nil {
+		return nil, fmt.Errorf("error storing document: %w", err)
+	}
+	if err := s.repo.Create(ctx, doc, s.cfg.MaxPerTender); err != nil {
+		s.deleteBlob(ctx, doc.StorageKey)
+		switch {
+		case errors.Is(err, repository.ErrConflict):
+			return nil, ErrTooManyDocuments
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, ErrTenderNotFound
+		}
+		return nil, err
+	}
+	return doc, nil
+}
+
+// List returns the documents of a tender the caller may view.
+func (s *DocumentService) List(ctx context.Context, tenderID uuid.UUID) ([]models.TenderDocument, error) {
+	if _, err := s.authorizedTender(ctx, ActionView, tenderID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListByTenderID(ctx, tenderID)
+}
+
+// Open returns a document of a tender the caller may view together with its
+// contents, which the caller closes.
+func (s *DocumentService) Open(ctx context.Context, tenderID, id uuid.UUID) (*models.TenderDocument, io.ReadCloser, error) {
+	if _, err := s.authorizedTender(ctx, ActionView, tenderID); err != nil {
+		return nil, nil, err
+	}
+	doc, err := s.getDocument(ctx, tenderID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.blobs.Open(ctx, doc.StorageKey)
+	if err != nil {
+		if errors.Is(err, utils.ErrBlobNotFound) {
+			return nil, nil, ErrDocumentNotFound
+		}
+		return nil, nil, err
+	}
+	return doc, content, nil
+}
+
+// Delete removes a document of a tender the caller manages.
+func (s *DocumentService) Delete(ctx context.Context, tenderID, id uuid.UUID) error {
+	tender, err := s.authorizedTender(ctx, ActionUpdate, tenderID)
+	if err != nil {
+		return err
+	}
+	if !tender.Status.Editable() {
+		return ErrTenderLocked
+	}
+	doc, err := s.getDocument(ctx, tenderID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, tenderID, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrDocumentNotFound
+		}
+		return err
+	}
+	s.deleteBlob(ctx, doc.StorageKey)
+	return nil
+}
+
+// ListForTender returns the documents of a tender for a service that has
+// authorized the caller on the tender itself.
+func (s *DocumentService) ListForTender(ctx context.Context, tenderID uuid.UUID) ([]models.TenderDocument, error) {
+	return s.repo.ListByTenderID(ctx, tenderID)
+}
+
+// DeleteTenderFiles removes the stored files of documents whose tender was
+// deleted along with their metadata.
+func (s *DocumentService) DeleteTenderFiles(ctx context.Context, docs []models.TenderDocument) {
+	for _, doc := range docs {
+		s.deleteBlob(ctx, doc.StorageKey)
+	}
+}
+
+// deleteBlob removes a stored file. A failure leaves an unreferenced blob
+// behind and is only logged.
+func (s *DocumentService) deleteBlob(ctx context.Context, key string) {
+	if err := s.blobs.Delete(ctx, key); err != nil {
+		log.Printf("Failed to delete blob %s: %v", key, err)
+	}
+}
+
+// detectContentType sniffs the media type of the spooled file and checks it
+// against the allowed types.
+func (s *DocumentService) detectContentType(f *os.File) (string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	head := make([]byte, documentSniffedBytes)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	contentType := http.DetectContentType(head[:n])
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", err
+	}
+	for _, allowed := range s.cfg.AllowedTypes {
+		if strings.EqualFold(allowed, mediaType) {
+			return contentType, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrDocumentType, mediaType)
+}
+
+func (s *DocumentService) getDocument(ctx context.Context, tenderID, id uuid.UUID) (*models.TenderDocument, error) {
+	doc, err := s.repo.GetByID(ctx, tenderID, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrDocumentNotFound
+		}
+		return nil, err
+	}
+	return doc, nil
+}
+
+// authorizedTender loads a tender and checks that the caller may perform
+// action on it.
+func (s *DocumentService) authorizedTender(ctx context.Context, action Action, id uuid.UUID) (*models.Tender, error) {
+	tender, err := s.tenders.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTenderNotFound
+		}
+		return nil, err
+	}
+	if err := s.authorizer.Authorize(ctx, action, TenderResource(tender)); err != nil {
+		return nil, err
+	}
+	return tender, nil
+}
+
+// documentFilename keeps the last element of a client supplied path and
+// drops control characters. It returns "" when nothing usable is left.
+func documentFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "." || name == "/" || name == "" || !utf8.ValidString(name) || utf8.RuneCountInString(name) > maxDocumentFilenameLength {
+		return ""
+	}
+	return name
+}
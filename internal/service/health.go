@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	HealthStatusUp   = "up"
+	HealthStatusDown = "down"
+)
+
+// HealthCheck is a named probe of a single dependency.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// DependencyHealth is the result of a single HealthCheck.
+type DependencyHealth struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// HealthReport is the combined result of all health checks.
+type HealthReport struct {
+	Status       string                      `json:"status"`
+	Draining     bool                        `json:"draining"`
+	Dependencies map[string]DependencyHealth `json:"dependencies"`
+}
+
+// HealthService runs dependency checks for the liveness and readiness probes.
+type HealthService struct {
+	checks   []HealthCheck
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+func NewHealthService(timeout time.Duration, checks ...HealthCheck) *HealthService {
+	return &HealthService{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// SetDraining marks the service as shutting down, which makes it unready.
+func (s *HealthService) SetDraining() {
+	s.draining.Store(true)
+}
+
+// Check runs every dependency check concurrently.
+func (s *HealthService) Check(ctx context.Context) HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	report := HealthReport{
+		Status:       HealthStatusUp,
+		Draining:     s.draining.Load(),
+		Dependencies: make(map[string]DependencyHealth, len(s.checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, check := range s.checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check.Check(ctx)
+			result := DependencyHealth{
+				Status:    HealthStatusUp,
+				LatencyMS: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				result.Status = HealthStatusDown
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			report.Dependencies[check.Name] = result
+			if err != nil {
+				report.Status = HealthStatusDown
+			}
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// Ready reports whether the instance should receive traffic.
+func (r HealthReport) Ready() bool {
+	return r.Status == HealthStatusUp && !r.Draining
+}
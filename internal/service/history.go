@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"github.com/Dostonlv/hackathon-nt/internal/models"
 	"github.com/Dostonlv/hackathon-nt/internal/repository"
 	"github.com/google/uuid"
@@ -9,21 +11,31 @@ import (
 // HistoryService represents the service for retrieving history data.
 type HistoryService struct {
 	historyRepo repository.HistoryRepository
+	authorizer  *Authorizer
 }
 
 // NewHistoryService creates a new instance of HistoryService.
-func NewHistoryService(historyRepo repository.HistoryRepository) *HistoryService {
+func NewHistoryService(historyRepo repository.HistoryRepository, authorizer *Authorizer) *HistoryService {
 	return &HistoryService{
 		historyRepo: historyRepo,
+		authorizer:  authorizer,
 	}
 }
 
-// GetTenderHistory retrieves the tender history for a specific user.
-func (s *HistoryService) GetTenderHistory(userID uuid.UUID) ([]models.Tender, error) {
-	return s.historyRepo.GetTenderHistory(userID)
+// GetTenderHistory retrieves the tender history for a specific user. Only
+// the user and admins may read it.
+func (s *HistoryService) GetTenderHistory(ctx context.Context, userID uuid.UUID, page repository.PageRequest) (*models.Page[models.Tender], error) {
+	if err := s.authorizer.Authorize(ctx, ActionView, UserResource(ResourceTenderHistory, userID)); err != nil {
+		return nil, err
+	}
+	return s.historyRepo.GetTenderHistory(userID, page)
 }
 
-// GetBidHistory retrieves the bid history for a specific contractor.
-func (s *HistoryService) GetBidHistory(userID uuid.UUID) ([]models.Bid, error) {
-	return s.historyRepo.GetBidHistory(userID)
+// GetBidHistory retrieves the bid history for a specific contractor. Only
+// the contractor and admins may read it.
+func (s *HistoryService) GetBidHistory(ctx context.Context, userID uuid.UUID, page repository.PageRequest) (*models.Page[models.Bid], error) {
+	if err := s.authorizer.Authorize(ctx, ActionView, UserResource(ResourceBidHistory, userID)); err != nil {
+		return nil, err
+	}
+	return s.historyRepo.GetBidHistory(userID, page)
 }
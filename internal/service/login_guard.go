@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/google/uuid"
+)
+
+var ErrLoginBlocked = errors.New("too many failed login attempts")
+
+const (
+	loginScopeUser = "user"
+	loginScopeIP   = "ip"
+)
+
+// LoginBlockedError is returned while an account or address is locked or
+// has to wait before the next attempt.
+type LoginBlockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	return ErrLoginBlocked.Error()
+}
+
+func (e *LoginBlockedError) Unwrap() error {
+	return ErrLoginBlocked
+}
+
+// LoginProtectionConfig controls how failed logins are throttled.
+type LoginProtectionConfig struct {
+	// MaxAccountFailures and MaxIPFailures are the failures after which the
+	// account or address is locked for LockoutDuration.
+	MaxAccountFailures int
+	MaxIPFailures      int
+	// FailureWindow is how long failures are remembered after the last one.
+	FailureWindow   time.Duration
+	LockoutDuration time.Duration
+	// After DelayAfter failures an account has to wait BaseDelay before the
+	// next attempt, doubling with every further failure up to MaxDelay.
+	DelayAfter int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// LoginAttempt identifies who is trying to log in.
+type LoginAttempt struct {
+	Username  string
+	IP        string
+	UserAgent string
+}
+
+// LoginGuard tracks failed logins per account and per IP address, slows
+// down repeated failures and locks accounts and addresses temporarily.
+// Accounts are tracked by the submitted username whether or not it exists,
+// so the guard does not reveal which usernames are registered.
+type LoginGuard struct {
+	repo        repository.LoginAttemptRepository
+	securityLog *SecurityLog
+	cfg         LoginProtectionConfig
+}
+
+func NewLoginGuard(repo repository.LoginAttemptRepository, securityLog *SecurityLog, cfg LoginProtectionConfig) *LoginGuard {
+	return &LoginGuard{
+		repo:        repo,
+		securityLog: securityLog,
+		cfg:         cfg,
+	}
+}
+
+// Check returns a *LoginBlockedError if the attempt must be rejected without
+// looking at the password.
+func (g *LoginGuard) Check(ctx context.Context, attempt LoginAttempt) error {
+	now := time.Now()
+	var wait time.Duration
+
+	for scope, id := range g.keys(attempt) {
+		until, err := g.repo.LoginLockedUntil(ctx, scope, id)
+		if err != nil {
+			return err
+		}
+		if d := until.Sub(now); d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		g.securityLog.Record(ctx, g.event(models.SecurityEventLoginBlocked, attempt, nil,
+			"locked until "+now.Add(wait).Format(time.RFC3339)))
+	}
+
+	if username := normalizeUsername(attempt.Username); username != "" {
+		count, last, err := g.repo.LoginFailures(ctx, loginScopeUser, username)
+		if err != nil {
+			return err
+		}
+		if d := last.Add(g.delay(count)).Sub(now); d > wait {
+			wait = d
+		}
+	}
+
+	if wait > 0 {
+		return &LoginBlockedError{RetryAfter: wait.Round(time.Second) + time.Second}
+	}
+	return nil
+}
+
+// Failed records a failed attempt and locks the account or the address when
+// it reached its limit. userID is nil when the username does not exist.
+func (g *LoginGuard) Failed(ctx context.Context, attempt LoginAttempt, userID *uuid.UUID, reason string) error {
+	now := time.Now()
+	g.securityLog.Record(ctx, g.event(models.SecurityEventLoginFailed, attempt, userID, reason))
+
+	limits := map[string]int{loginScopeUser: g.cfg.MaxAccountFailures, loginScopeIP: g.cfg.MaxIPFailures}
+	for scope, id := range g.keys(attempt) {
+		count, err := g.repo.RecordLoginFailure(ctx, scope, id, now, g.cfg.FailureWindow)
+		if err != nil {
+			return err
+		}
+		if count < int64(limits[scope]) {
+			continue
+		}
+
+		until := now.Add(g.cfg.LockoutDuration)
+		if err := g.repo.LockLogin(ctx, scope, id, until); err != nil {
+			return err
+		}
+		eventType := models.SecurityEventAccountLocked
+		if scope == loginScopeIP {
+			eventType = models.SecurityEventIPLocked
+		}
+		g.securityLog.Record(ctx, g.event(eventType, attempt, userID,
+			fmt.Sprintf("%d failed attempts, locked until %s", count, until.Format(time.RFC3339))))
+	}
+	return nil
+}
+
+// Succeeded forgets the failures of the account and records the login.
+func (g *LoginGuard) Succeeded(ctx context.Context, attempt LoginAttempt, userID uuid.UUID, detail string) error {
+	g.securityLog.Record(ctx, g.event(models.SecurityEventLoginSucceeded, attempt, &userID, detail))
+	return g.repo.ResetLoginFailures(ctx, loginScopeUser, normalizeUsername(attempt.Username))
+}
+
+// Reset forgets the failures of the account once it proved itself outside
+// of a login, e.g. with a two-factor code.
+func (g *LoginGuard) Reset(ctx context.Context, attempt LoginAttempt) error {
+	return g.repo.ResetLoginFailures(ctx, loginScopeUser, normalizeUsername(attempt.Username))
+}
+
+// UnlockAccount lifts a lockout of the account and forgets its failures.
+func (g *LoginGuard) UnlockAccount(ctx context.Context, user *models.User, actorID uuid.UUID) error {
+	if err := g.repo.UnlockLogin(ctx, loginScopeUser, normalizeUsername(user.Username)); err != nil {
+		return err
+	}
+	g.securityLog.Record(ctx, models.SecurityEvent{
+		Type:     models.SecurityEventAccountUnlocked,
+		UserID:   &user.ID,
+		ActorID:  &actorID,
+		Username: user.Username,
+	})
+	return nil
+}
+
+// UnlockIP lifts a lockout of the address and forgets its failures.
+func (g *LoginGuard) UnlockIP(ctx context.Context, ip string, actorID uuid.UUID) error {
+	if err := g.repo.UnlockLogin(ctx, loginScopeIP, ip); err != nil {
+		return err
+	}
+	g.securityLog.Record(ctx, models.SecurityEvent{
+		Type:    models.SecurityEventIPUnlocked,
+		ActorID: &actorID,
+		IP:      ip,
+	})
+	return nil
+}
+
+// delay returns how long an account with count failures has to wait after
+// the last one.
+func (g *LoginGuard) delay(count int64) time.Duration {
+	if count < int64(g.cfg.DelayAfter) || g.cfg.BaseDelay <= 0 {
+		return 0
+	}
+	delay := g.cfg.BaseDelay
+	for i := int64(g.cfg.DelayAfter); i < count && delay < g.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > g.cfg.MaxDelay {
+		delay = g.cfg.MaxDelay
+	}
+	return delay
+}
+
+func (g *LoginGuard) keys(attempt LoginAttempt) map[string]string {
+	keys := make(map[string]string, 2)
+	if username := normalizeUsername(attempt.Username); username != "" {
+		keys[loginScopeUser] = username
+	}
+	if attempt.IP != "" {
+		keys[loginScopeIP] = attempt.IP
+	}
+	return keys
+}
+
+func (g *LoginGuard) event(eventType models.SecurityEventType, attempt LoginAttempt, userID *uuid.UUID, detail string) models.SecurityEvent {
+	return models.SecurityEvent{
+		Type:      eventType,
+		UserID:    userID,
+		Username:  attempt.Username,
+		IP:        attempt.IP,
+		UserAgent: attempt.UserAgent,
+		Detail:    detail,
+	}
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
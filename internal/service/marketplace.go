@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/google/uuid"
+)
+
+// MarketplaceSearch is a page of the marketplace. The facets count the whole
+// list, not just the page.
+type MarketplaceSearch struct {
+	models.Page[models.MarketplaceTender]
+	Facets *models.TenderFacets `json:"facets"`
+}
+
+// ListMarketplace lists the open tenders of every client matching filters
+// and tells which ones the organizations of the caller have bid on. Only
+// open tenders are listed since contractors may only view those, see
+// GetMarketplaceTender.
+func (s *TenderService) ListMarketplace(ctx context.Context, filters repository.TenderFilters, page repository.PageRequest) (*MarketplaceSearch, error) {
+	if filters.Status == "" {
+		filters.Status = string(models.TenderStatusOpen)
+	}
+	if err := s.validateTenderFilters(ctx, &filters); err != nil {
+		return nil, err
+	}
+	if filters.Status != string(models.TenderStatusOpen) {
+		return nil, fmt.Errorf("%w: %s tenders are not listed", ErrInvalidInput, filters.Status)
+	}
+	filters.OrganizationIDs = nil
+
+	tenders, err := s.repo.List(ctx, filters, page)
+	if err != nil {
+		return nil, err
+	}
+	facets, err := s.repo.Facets(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	// The status counts ignore the status filter
+	for status := range facets.Statuses {
+		if status != models.TenderStatusOpen {
+			delete(facets.Statuses, status)
+		}
+	}
+
+	result := &MarketplaceSearch{
+		Page: models.Page[models.MarketplaceTender]{
+			Items:      make([]models.MarketplaceTender, len(tenders.Items)),
+			NextCursor: tenders.NextCursor,
+			Total:      tenders.Total,
+		},
+		Facets: facets,
+	}
+	ids := make([]uuid.UUID, len(tenders.Items))
+	for i := range tenders.Items {
+		result.Items[i] = models.NewMarketplaceTender(&tenders.Items[i])
+		ids[i] = tenders.Items[i].ID
+	}
+	bid, err := s.bidTenderIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result.Items {
+		result.Items[i].HasBid = bid[result.Items[i].ID]
+	}
+	return result, nil
+}
+
+// GetMarketplaceTender returns a tender the caller may view together with
+// its documents.
+func (s *TenderService) GetMarketplaceTender(ctx context.Context, id uuid.UUID) (*models.MarketplaceTender, error) {
+	tender, err := s.authorizedTender(ctx, ActionView, id)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := s.documents.ListForTender(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := models.NewMarketplaceTender(tender)
+	result.Documents = make([]models.MarketplaceDocument, len(docs))
+	for i := range docs {
+		result.Documents[i] = models.NewMarketplaceDocument(&docs[i])
+	}
+	bid, err := s.bidTenderIDs(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	result.HasBid = bid[id]
+	return &result, nil
+}
+
+// bidTenderIDs returns which of the given tenders a contractor organization
+// of the caller has bid on.
+func (s *TenderService) bidTenderIDs(ctx context.Context, tenderIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	bid := make(map[uuid.UUID]bool)
+	if len(tenderIDs) == 0 {
+		return bid, nil
+	}
+	orgIDs, err := s.organizations.MemberOrganizationIDs(ctx, models.OrganizationContractor)
+	if err != nil || len(orgIDs) == 0 {
+		return bid, err
+	}
+	ids, err := s.bidRepo.ListBidTenderIDs(ctx, orgIDs, tenderIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		bid[id] = true
+	}
+	return bid, nil
+}
@@ -0,0 +1,426 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/Dostonlv/hackathon-nt/internal/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrOrganizationNotFound     = errors.New("organization not found")
+	ErrInvalidOrganizationName  = errors.New("organization name must be 1 to 255 characters")
+	ErrInvalidOrganizationKind  = errors.New("organization kind must match the user's role")
+	ErrInvalidOrgRole           = errors.New("invalid organization role")
+	ErrMemberNotFound           = errors.New("member not found")
+	ErrAlreadyMember            = errors.New("user is already a member")
+	ErrLastOwner                = errors.New("an organization needs at least one owner")
+	ErrInvitationNotFound       = errors.New("invitation not found")
+	ErrInvalidInvitation        = errors.New("invalid or expired invitation")
+	ErrInvitationEmailMismatch  = errors.New("invitation was sent to another email address")
+	ErrOrganizationRequired     = errors.New("member of several organizations, organization_id is required")
+	ErrOrganizationKindMismatch = errors.New("organization is of the wrong kind")
+)
+
+// OrganizationConfig holds the invitation settings.
+type OrganizationConfig struct {
+	InvitationTTL time.Duration
+	// LinkBaseURL is where invitation links point, as for account emails.
+	LinkBaseURL string
+}
+
+// OrganizationService manages organizations, their members and invitations.
+// It also picks the organization a new tender or bid belongs to.
+type OrganizationService struct {
+	repo       repository.OrganizationRepository
+	userRepo   repository.UserRepository
+	authorizer *Authorizer
+	mailer     utils.Mailer
+	cfg        OrganizationConfig
+}
+
+func NewOrganizationService(repo repository.OrganizationRepository, userRepo repository.UserRepository, authorizer *Authorizer, mailer utils.Mailer, cfg OrganizationConfig) *OrganizationService {
+	return &OrganizationService{
+		repo:       repo,
+		userRepo:   userRepo,
+		authorizer: authorizer,
+		mailer:     mailer,
+		cfg:        cfg,
+	}
+}
+
+type CreateOrganizationInput struct {
+	Name string `json:"name" validate:"required,max=255"`
+	// Kind defaults to the caller's role. Only admins may pick another one.
+	Kind models.OrganizationKind `json:"kind,omitempty"`
+}
+
+type UpdateOrganizationInput struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+type InviteMemberInput struct {
+	Email string         `json:"email" validate:"required,email"`
+	Role  models.OrgRole `json:"role" validate:"required"`
+}
+
+type AcceptInvitationInput struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// Create creates an organization with the caller as its owner.
+func (s *OrganizationService) Create(ctx context.Context, input CreateOrganizationInput) (*models.Organization, error) {
+	p, ok := models.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 255 {
+		return nil, ErrInvalidOrganizationName
+	}
+
+	kind := input.Kind
+	if kind == "" {
+		kind = kindForRoles(p)
+	}
+	if !kind.Valid() || !p.HasRole(models.RoleAdmin) && !p.HasRole(models.UserRole(kind)) {
+		return nil, ErrInvalidOrganizationKind
+	}
+	return s.create(ctx, p.UserID, name, kind)
+}
+
+// List returns the organizations of the caller with the caller's role in
+// each.
+func (s *OrganizationService) List(ctx context.Context) ([]models.Organization, error) {
+	p, ok := models.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	return s.repo.ListForUser(ctx, p.UserID)
+}
+
+// Get returns an organization the caller is a member of.
+func (s *OrganizationService) Get(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	return s.authorized(ctx, ActionView, id)
+}
+
+// Update renames an organization. Only owners may.
+func (s *OrganizationService) Update(ctx context.Context, id uuid.UUID, input UpdateOrganizationInput) (*models.Organization, error) {
+	org, err := s.authorized(ctx, ActionUpdate, id)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 255 {
+		return nil, ErrInvalidOrganizationName
+	}
+
+	org.Name = name
+	org.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, org); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// ListMembers lists the members of an organization the caller belongs to.
+func (s *OrganizationService) ListMembers(ctx context.Context, id uuid.UUID) ([]models.Membership, error) {
+	if _, err := s.authorized(ctx, ActionView, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(ctx, id)
+}
+
+// UpdateMemberRole changes the role of a member. Only owners may, and the
+// last owner cannot step down.
+func (s *OrganizationService) UpdateMemberRole(ctx context.Context, id, userID uuid.UUID, role models.OrgRole) error {
+	org, err := s.authorized(ctx, ActionManageMembers, id)
+	if err != nil {
+		return err
+	}
+	if !role.ValidFor(org.Kind) {
+		return ErrInvalidOrgRole
+	}
+	return s.memberError(s.repo.UpdateMemberRole(ctx, id, userID, role))
+}
+
+// RemoveMember removes a member from an organization. Owners may remove
+// anyone and every member may leave, except the last owner.
+func (s *OrganizationService) RemoveMember(ctx context.Context, id, userID uuid.UUID) error {
+	p, ok := models.PrincipalFromContext(ctx)
+	if !ok {
+		return ErrUnauthorized
+	}
+	action := ActionManageMembers
+	if userID == p.UserID {
+		action = ActionView
+	}
+	if _, err := s.authorized(ctx, action, id); err != nil {
+		return err
+	}
+	return s.memberError(s.repo.RemoveMember(ctx, id, userID))
+}
+
+// Invite emails an invitation to join the organization. Only owners may
+// invite.
+func (s *OrganizationService) Invite(ctx context.Context, id uuid.UUID, input InviteMemberInput) (*models.OrganizationInvitation, error) {
+	org, err := s.authorized(ctx, ActionManageMembers, id)
+	if err != nil {
+		return nil, err
+	}
+	if !input.Role.ValidFor(org.Kind) {
+		return nil, ErrInvalidOrgRole
+	}
+	p, _ := models.PrincipalFromContext(ctx)
+
+	token, hash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	invitation := &models.OrganizationInvitation{
+		ID:             uuid.New(),
+		OrganizationID: org.ID,
+		Email:          strings.ToLower(strings.TrimSpace(input.Email)),
+		Role:           input.Role,
+		TokenHash:      hash,
+		InvitedBy:      &p.UserID,
+		ExpiresAt:      now.Add(s.cfg.InvitationTTL),
+		CreatedAt:      now,
+	}
+	if err := s.repo.CreateInvitation(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	err = s.mailer.Send(ctx, utils.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("Join %s", org.Name),
+		Body: fmt.Sprintf("Hello,\n\nYou have been invited to join %s as %s. Sign in with this email address and open the link below to accept:\n\n%s\n\nThe invitation expires in %s.\n",
+			org.Name, invitation.Role, s.link("/accept-invitation", token), s.cfg.InvitationTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// ListInvitations lists the open invitations of an organization. Only owners
+// may.
+func (s *OrganizationService) ListInvitations(ctx context.Context, id uuid.UUID) ([]models.OrganizationInvitation, error) {
+	if _, err := s.authorized(ctx, ActionManageMembers, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListInvitations(ctx, id, time.Now())
+}
+
+// RevokeInvitation deletes an invitation. Only owners may.
+func (s *OrganizationService) RevokeInvitation(ctx context.Context, id, invitationID uuid.UUID) error {
+	if _, err := s.authorized(ctx, ActionManageMembers, id); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteInvitation(ctx, id, invitationID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvitationNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// AcceptInvitation adds the caller to the organization of an invitation that
+// was sent to the caller's email address.
+func (s *OrganizationService) AcceptInvitation(ctx context.Context, input AcceptInvitationInput) (*models.Organization, error) {
+	p, ok := models.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	invitation, err := s.repo.GetInvitationByHash(ctx, utils.HashToken(input.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidInvitation
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if invitation.AcceptedAt != nil || !now.Before(invitation.ExpiresAt) {
+		return nil, ErrInvalidInvitation
+	}
+
+	user, err := s.userRepo.GetByID(ctx, p.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		return nil, ErrInvitationEmailMismatch
+	}
+	org, err := s.repo.GetByID(ctx, invitation.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role != models.UserRole(org.Kind) {
+		return nil, ErrOrganizationKindMismatch
+	}
+
+	if err := s.repo.AcceptInvitation(ctx, invitation.ID, user.ID, now); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, ErrInvalidInvitation
+		case errors.Is(err, repository.ErrConflict):
+			return nil, ErrAlreadyMember
+		}
+		return nil, err
+	}
+	org.Role = invitation.Role
+	return org, nil
+}
+
+// Resolve returns the organization of kind a new tender or bid of the caller
+// belongs to. An explicit id is returned as is and checked by the caller's
+// authorization. Without one, the caller's only organization of that kind is
+// used; a caller who has none gets a personal organization, so single users
+// keep working without setting anything up.
+func (s *OrganizationService) Resolve(ctx context.Context, kind models.OrganizationKind, id *uuid.UUID) (*models.Organization, error) {
+	if id != nil {
+		org, err := s.repo.GetByID(ctx, *id)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, ErrOrganizationNotFound
+			}
+			return nil, err
+		}
+		if org.Kind != kind {
+			return nil, ErrOrganizationKindMismatch
+		}
+		return org, nil
+	}
+
+	p, ok := models.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	orgs, err := s.ofKind(ctx, p.UserID, kind)
+	if err != nil {
+		return nil, err
+	}
+	switch len(orgs) {
+	case 0:
+		if !p.HasRole(models.UserRole(kind)) {
+			return nil, ErrForbidden
+		}
+		user, err := s.userRepo.GetByID(ctx, p.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return s.create(ctx, user.ID, user.Username, kind)
+	case 1:
+		return &orgs[0], nil
+	}
+	return nil, ErrOrganizationRequired
+}
+
+// MemberOrganizationIDs returns the IDs of the caller's organizations of
+// kind.
+func (s *OrganizationService) MemberOrganizationIDs(ctx context.Context, kind models.OrganizationKind) ([]uuid.UUID, error) {
+	p, ok := models.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	orgs, err := s.ofKind(ctx, p.UserID, kind)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(orgs))
+	for i, org := range orgs {
+		ids[i] = org.ID
+	}
+	return ids, nil
+}
+
+// memberIDs returns every member of an organization, for notifications.
+func (s *OrganizationService) memberIDs(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	members, err := s.repo.ListMembers(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(members))
+	for i, m := range members {
+		ids[i] = m.UserID
+	}
+	return ids, nil
+}
+
+func (s *OrganizationService) create(ctx context.Context, ownerID uuid.UUID, name string, kind models.OrganizationKind) (*models.Organization, error) {
+	now := time.Now()
+	org := &models.Organization{
+		ID:        uuid.New(),
+		Name:      name,
+		Kind:      kind,
+		CreatedBy: &ownerID,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Role:      models.OrgRoleOwner,
+	}
+	if err := s.repo.Create(ctx, org, ownerID); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func (s *OrganizationService) ofKind(ctx context.Context, userID uuid.UUID, kind models.OrganizationKind) ([]models.Organization, error) {
+	orgs, err := s.repo.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	matching := orgs[:0]
+	for _, org := range orgs {
+		if org.Kind == kind {
+			matching = append(matching, org)
+		}
+	}
+	return matching, nil
+}
+
+// authorized loads an organization and checks that the caller may perform
+// action on it.
+func (s *OrganizationService) authorized(ctx context.Context, action Action, id uuid.UUID) (*models.Organization, error) {
+	org, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	if err := s.authorizer.Authorize(ctx, action, OrganizationResource(id)); err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func (s *OrganizationService) memberError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrMemberNotFound
+	case errors.Is(err, repository.ErrConflict):
+		return ErrLastOwner
+	}
+	return err
+}
+
+func (s *OrganizationService) link(path, token string) string {
+	return strings.TrimSuffix(s.cfg.LinkBaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// kindForRoles returns the organization kind matching the principal's role.
+func kindForRoles(p *models.Principal) models.OrganizationKind {
+	if p.HasRole(models.RoleContractor) {
+		return models.OrganizationContractor
+	}
+	return models.OrganizationClient
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/casbin/casbin/v2"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidPolicy   = errors.New("invalid policy")
+	ErrPolicyExists    = errors.New("policy already exists")
+	ErrPolicyNotFound  = errors.New("policy not found")
+	ErrProtectedPolicy = errors.New("policy is protected")
+)
+
+var (
+	policySubjectRe = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,63}$`)
+	policyObjectRe  = regexp.MustCompile(`^/api(/[A-Za-z0-9_.:*-]+)*/?$`)
+	policyActions   = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "WS", "*"}
+)
+
+// protectedPolicy keeps admins from locking themselves out of the policy API.
+var protectedPolicy = models.Policy{Subject: string(models.RoleAdmin), Object: "/api/*", Action: "*"}
+
+// PolicySet is the whole authorization policy.
+type PolicySet struct {
+	Policies  []models.Policy       `json:"policies"`
+	Groupings []models.RoleGrouping `json:"groupings"`
+}
+
+// PolicyService changes the casbin policy at runtime. The enforcer saves
+// every change to Postgres and announces it to the other instances; this
+// service validates changes and records them in the audit trail.
+type PolicyService struct {
+	enforcer  *casbin.SyncedEnforcer
+	auditRepo repository.PolicyAuditRepository
+}
+
+func NewPolicyService(enforcer *casbin.SyncedEnforcer, auditRepo repository.PolicyAuditRepository) *PolicyService {
+	return &PolicyService{
+		enforcer:  enforcer,
+		auditRepo: auditRepo,
+	}
+}
+
+// List returns every policy and role grouping.
+func (s *PolicyService) List(ctx context.Context) (*PolicySet, error) {
+	policies, err := s.enforcer.GetPolicy()
+	if err != nil {
+		return nil, err
+	}
+	groupings, err := s.enforcer.GetGroupingPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	set := &PolicySet{
+		Policies:  make([]models.Policy, 0, len(policies)),
+		Groupings: make([]models.RoleGrouping, 0, len(groupings)),
+	}
+	for _, p := range policies {
+		if len(p) >= 3 {
+			set.Policies = append(set.Policies, models.Policy{Subject: p[0], Object: p[1], Action: p[2]})
+		}
+	}
+	for _, g := range groupings {
+		if len(g) >= 2 {
+			set.Groupings = append(set.Groupings, models.RoleGrouping{Member: g[0], Role: g[1]})
+		}
+	}
+	return set, nil
+}
+
+func (s *PolicyService) AddPolicy(ctx context.Context, actorID uuid.UUID, policy models.Policy) error {
+	policy, err := validatePolicy(policy)
+	if err != nil {
+		return err
+	}
+	added, err := s.enforcer.AddPolicy(policy.Subject, policy.Object, policy.Action)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return ErrPolicyExists
+	}
+	return s.audit(ctx, actorID, models.PolicyAuditAdd, "p", policy.Subject, policy.Object, policy.Action)
+}
+
+func (s *PolicyService) RemovePolicy(ctx context.Context, actorID uuid.UUID, policy models.Policy) error {
+	policy, err := validatePolicy(policy)
+	if err != nil {
+		return err
+	}
+	if policy == protectedPolicy {
+		return ErrProtectedPolicy
+	}
+	removed, err := s.enforcer.RemovePolicy(policy.Subject, policy.Object, policy.Action)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrPolicyNotFound
+	}
+	return s.audit(ctx, actorID, models.PolicyAuditRemove, "p", policy.Subject, policy.Object, policy.Action)
+}
+
+func (s *PolicyService) AddGrouping(ctx context.Context, actorID uuid.UUID, grouping models.RoleGrouping) error {
+	grouping, err := validateGrouping(grouping)
+	if err != nil {
+		return err
+	}
+	added, err := s.enforcer.AddGroupingPolicy(grouping.Member, grouping.Role)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return ErrPolicyExists
+	}
+	return s.audit(ctx, actorID, models.PolicyAuditAdd, "g", grouping.Member, grouping.Role)
+}
+
+func (s *PolicyService) RemoveGrouping(ctx context.Context, actorID uuid.UUID, grouping models.RoleGrouping) error {
+	grouping, err := validateGrouping(grouping)
+	if err != nil {
+		return err
+	}
+	removed, err := s.enforcer.RemoveGroupingPolicy(grouping.Member, grouping.Role)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrPolicyNotFound
+	}
+	return s.audit(ctx, actorID, models.PolicyAuditRemove, "g", grouping.Member, grouping.Role)
+}
+
+// PolicyDecision explains how the policy decides a request.
+type PolicyDecision struct {
+	Subject string `json:"subject"`
+	Object  string `json:"object"`
+	Action  string `json:"action"`
+	Allowed bool   `json:"allowed"`
+	// MatchedPolicy is the policy that allowed the request.
+	MatchedPolicy *models.Policy `json:"matched_policy,omitempty"`
+	// RoleChain is the subject followed by every role it inherits from,
+	// directly or through other roles.
+	RoleChain []string `json:"role_chain"`
+	// Candidates are the policies of the role chain for the same action,
+	// whether or not their path matched, to spot near misses such as a
+	// mistyped pattern.
+	Candidates []models.Policy `json:"candidates"`
+}
+
+// Explain evaluates a request for one subject, a role or scope:<name>, and
+// reports the decision together with the policy that allowed it.
+func (s *PolicyService) Explain(ctx context.Context, request models.Policy) (*PolicyDecision, error) {
+	request.Subject = strings.TrimSpace(request.Subject)
+	request.Object = strings.TrimSpace(request.Object)
+	request.Action = strings.ToUpper(strings.TrimSpace(request.Action))
+	if err := validateSubject(request.Subject); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(request.Object, "/api/") {
+		return nil, fmt.Errorf("%w: path must start with /api/", ErrInvalidPolicy)
+	}
+	if request.Action == "" {
+		return nil, fmt.Errorf("%w: method is required", ErrInvalidPolicy)
+	}
+
+	allowed, explain, err := s.enforcer.EnforceEx(request.Subject, request.Object, request.Action)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.enforcer.GetImplicitRolesForUser(request.Subject)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := s.enforcer.GetPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	decision := &PolicyDecision{
+		Subject:    request.Subject,
+		Object:     request.Object,
+		Action:     request.Action,
+		Allowed:    allowed,
+		RoleChain:  append([]string{request.Subject}, roles...),
+		Candidates: []models.Policy{},
+	}
+	if allowed && len(explain) >= 3 {
+		decision.MatchedPolicy = &models.Policy{Subject: explain[0], Object: explain[1], Action: explain[2]}
+	}
+
+	chain := make(map[string]bool, len(decision.RoleChain))
+	for _, role := range decision.RoleChain {
+		chain[role] = true
+	}
+	for _, p := range policies {
+		if len(p) >= 3 && chain[p[0]] && (p[2] == request.Action || p[2] == "*") {
+			decision.Candidates = append(decision.Candidates, models.Policy{Subject: p[0], Object: p[1], Action: p[2]})
+		}
+	}
+	return decision, nil
+}
+
+// Audit returns the most recent policy changes, newest first.
+func (s *PolicyService) Audit(ctx context.Context, limit int) ([]models.PolicyAudit, error) {
+	return s.auditRepo.List(ctx, limit)
+}
+
+func (s *PolicyService) audit(ctx context.Context, actorID uuid.UUID, action models.PolicyAuditAction, ptype string, rule ...string) error {
+	return s.auditRepo.Create(context.WithoutCancel(ctx), &models.PolicyAudit{
+		ID:        uuid.New(),
+		ActorID:   &actorID,
+		Action:    action,
+		Ptype:     ptype,
+		Rule:      rule,
+		CreatedAt: time.Now(),
+	})
+}
+
+// validatePolicy checks that the subject is a role name or an API key scope,
+// the object an /api path pattern and the action an HTTP method, WS or *.
+func validatePolicy(policy models.Policy) (models.Policy, error) {
+	policy.Subject = strings.TrimSpace(policy.Subject)
+	policy.Object = strings.TrimSpace(policy.Object)
+	policy.Action = strings.ToUpper(strings.TrimSpace(policy.Action))
+
+	if err := validateSubject(policy.Subject); err != nil {
+		return policy, err
+	}
+	if !policyObjectRe.MatchString(policy.Object) || strings.Contains(policy.Object, "//") {
+		return policy, fmt.Errorf("%w: object must be a path pattern under /api, such as /api/client/tenders/*", ErrInvalidPolicy)
+	}
+	for _, action := range policyActions {
+		if policy.Action == action {
+			return policy, nil
+		}
+	}
+	return policy, fmt.Errorf("%w: action must be one of %s", ErrInvalidPolicy, strings.Join(policyActions, ", "))
+}
+
+func validateGrouping(grouping models.RoleGrouping) (models.RoleGrouping, error) {
+	grouping.Member = strings.TrimSpace(grouping.Member)
+	grouping.Role = strings.TrimSpace(grouping.Role)
+	if !policySubjectRe.MatchString(grouping.Member) || !policySubjectRe.MatchString(grouping.Role) {
+		return grouping, fmt.Errorf("%w: member and role must be role names", ErrInvalidPolicy)
+	}
+	if grouping.Member == grouping.Role {
+		return grouping, fmt.Errorf("%w: a role cannot inherit from itself", ErrInvalidPolicy)
+	}
+	return grouping, nil
+}
+
+func validateSubject(subject string) error {
+	if scope, ok := strings.CutPrefix(subject, "scope:"); ok {
+		if !models.ValidAPIKeyScope(scope) {
+			return fmt.Errorf("%w: unknown scope %q", ErrInvalidPolicy, scope)
+		}
+		return nil
+	}
+	if !policySubjectRe.MatchString(subject) {
+		return fmt.Errorf("%w: subject must be a role name or scope:<scope>", ErrInvalidPolicy)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/google/uuid"
+)
+
+// SecurityLog is the audit trail of authentication events. Events are
+// written to the log and stored in Postgres.
+type SecurityLog struct {
+	repo repository.SecurityEventRepository
+}
+
+func NewSecurityLog(repo repository.SecurityEventRepository) *SecurityLog {
+	return &SecurityLog{repo: repo}
+}
+
+// Record stores event. Failures are logged rather than returned so that
+// auditing never breaks the request it describes.
+func (l *SecurityLog) Record(ctx context.Context, event models.SecurityEvent) {
+	event.ID = uuid.New()
+	event.CreatedAt = time.Now()
+
+	userID := "-"
+	if event.UserID != nil {
+		userID = event.UserID.String()
+	}
+	log.Printf("security event=%s user_id=%s username=%q ip=%q detail=%q", event.Type, userID, event.Username, event.IP, event.Detail)
+
+	if err := l.repo.Create(context.WithoutCancel(ctx), &event); err != nil {
+		log.Printf("Failed to store security event %s: %v", event.Type, err)
+	}
+}
+
+// List returns the most recent events matching filters, newest first.
+func (l *SecurityLog) List(ctx context.Context, filters repository.SecurityEventFilters) ([]models.SecurityEvent, error) {
+	return l.repo.List(ctx, filters)
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/Dostonlv/hackathon-nt/internal/utils"
+)
+
+// KeyRotationConfig controls how often JWT signing keys are replaced.
+type KeyRotationConfig struct {
+	// Interval is how long a key is used for signing.
+	Interval time.Duration
+	// PublishLead is how long a new key is published in the JWKS before it
+	// starts signing, so verifiers caching the JWKS already know it.
+	PublishLead time.Duration
+	// RefreshInterval is how often the key set is reloaded from the database.
+	RefreshInterval time.Duration
+	// EncryptionSecret encrypts private keys at rest.
+	EncryptionSecret string
+}
+
+// KeyRotationService keeps the JWT key set of every replica in sync with the
+// keys stored in Postgres and creates new keys on schedule.
+type KeyRotationService struct {
+	repo    repository.SigningKeyRepository
+	jwtUtil *utils.JWTUtil
+	cfg     KeyRotationConfig
+}
+
+func NewKeyRotationService(repo repository.SigningKeyRepository, jwtUtil *utils.JWTUtil, cfg KeyRotationConfig) *KeyRotationService {
+	return &KeyRotationService{
+		repo:    repo,
+		jwtUtil: jwtUtil,
+		cfg:     cfg,
+	}
+}
+
+// Sync creates a new key when rotation is due and loads the current key set
+// into the JWTUtil.
+func (s *KeyRotationService) Sync(ctx context.Context) error {
+	algorithm := s.jwtUtil.Algorithm()
+	now := time.Now()
+
+	stored, err := s.repo.ListSigningKeys(ctx, algorithm)
+	if err != nil {
+		return err
+	}
+
+	if s.rotationDue(stored, now) {
+		created, err := s.createKey(ctx, stored, now)
+		if err != nil {
+			return err
+		}
+		if created {
+			if stored, err = s.repo.ListSigningKeys(ctx, algorithm); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.repo.DeleteExpiredSigningKeys(ctx, now); err != nil {
+		return err
+	}
+
+	keys := make([]utils.SigningKey, 0, len(stored))
+	for _, k := range stored {
+		key, err := utils.OpenSigningKey(k.ID, k.Algorithm, k.PrivateKey, s.cfg.EncryptionSecret, k.ActivatesAt, k.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	s.jwtUtil.SetSigningKeys(keys)
+	return nil
+}
+
+// Run calls Sync every RefreshInterval until ctx is cancelled.
+func (s *KeyRotationService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Sync(ctx); err != nil {
+				log.Printf("Failed to sync JWT signing keys: %v", err)
+			}
+		}
+	}
+}
+
+func (s *KeyRotationService) rotationDue(stored []models.SigningKey, now time.Time) bool {
+	for _, k := range stored {
+		if k.CreatedAt.After(now.Add(-s.cfg.Interval)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *KeyRotationService) createKey(ctx context.Context, stored []models.SigningKey, now time.Time) (bool, error) {
+	// The first key signs right away; later keys are published ahead of use.
+	activatesAt := now
+	if len(stored) > 0 {
+		activatesAt = now.Add(s.cfg.PublishLead)
+	}
+	// A key signs until the next one activates and must verify the last
+	// tokens it signed until they expire.
+	expiresAt := activatesAt.Add(s.cfg.Interval + s.cfg.PublishLead + s.cfg.RefreshInterval + s.jwtUtil.TokenTTL())
+
+	key, err := utils.GenerateSigningKey(s.jwtUtil.Algorithm(), activatesAt, expiresAt)
+	if err != nil {
+		return false, err
+	}
+	sealed, err := utils.SealPrivateKey(key.Private, s.cfg.EncryptionSecret)
+	if err != nil {
+		return false, err
+	}
+
+	created, err := s.repo.CreateSigningKeyIfDue(ctx, &models.SigningKey{
+		ID:          key.ID,
+		Algorithm:   s.jwtUtil.Algorithm(),
+		PrivateKey:  sealed,
+		ActivatesAt: activatesAt,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   now,
+	}, now.Add(-s.cfg.Interval))
+	if err != nil {
+		return false, fmt.Errorf("error storing signing key: %w", err)
+	}
+	if created {
+		log.Printf("Created JWT signing key %s, active from %s", key.ID, activatesAt.Format(time.RFC3339))
+	}
+	return created, nil
+}
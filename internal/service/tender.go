@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Dostonlv/hackathon-nt/internal/models"
@@ -14,44 +16,76 @@ var (
 	ErrInvalidInput   = errors.New("invalid input parameters")
 	ErrTenderNotFound = errors.New("tender not found")
 	ErrUnauthorized   = errors.New("unauthorized action")
+	ErrTenderLocked   = errors.New("awarded and cancelled tenders cannot be edited")
+	ErrBudgetLocked   = errors.New("the tender has bids, the budget can only change with notify_bidders set")
+	ErrNotWatching    = errors.New("not watching the tender")
+	ErrTenderHasBids  = errors.New("tenders with bids cannot be deleted, cancel them instead")
 )
 
 type TenderService struct {
-	repo repository.TenderRepository
+	repo          repository.TenderRepository
+	bidRepo       repository.BidRepository
+	categories    repository.CategoryRepository
+	authorizer    *Authorizer
+	organizations *OrganizationService
+	documents     *DocumentService
 }
 
-func NewTenderService(repo repository.TenderRepository) *TenderService {
+func NewTenderService(repo repository.TenderRepository, bidRepo repository.BidRepository, categories repository.CategoryRepository, authorizer *Authorizer, organizations *OrganizationService, documents *DocumentService) *TenderService {
 	if repo == nil {
 		panic("tender repository cannot be nil")
 	}
 	return &TenderService{
-		repo: repo,
+		repo:          repo,
+		bidRepo:       bidRepo,
+		categories:    categories,
+		authorizer:    authorizer,
+		organizations: organizations,
+		documents:     documents,
 	}
 }
 
 type CreateTenderInput struct {
-	ClientID    uuid.UUID
-	Title       string
-	Description string
-	Deadline    time.Time
-	Budget      float64
-	Attachment  *string
+	ClientID uuid.UUID
+	// OrganizationID is the client organization the tender belongs to. It
+	// may be left out by members of a single client organization.
+	OrganizationID *uuid.UUID
+	Title          string
+	Description    string
+	Deadline       time.Time
+	Budget         float64
+	Attachment     *string
+	// CategoryCode classifies the tender; nil or empty leaves it
+	// unclassified.
+	CategoryCode *string
+	Tags         []string
+	// Draft keeps the tender from contractors until it is published.
+	Draft bool
 }
 
 func (s *TenderService) validateCreateTenderInput(input CreateTenderInput) error {
 	if input.ClientID == uuid.Nil {
 		return errors.New("client ID is required")
 	}
-	if input.Title == "" {
-		return errors.New("title is required")
-	}
-	if input.Description == "" {
-		return errors.New("description is required")
+	if err := validateTenderDetails(input.Title, input.Description, input.Budget); err != nil {
+		return err
 	}
 	if input.Deadline.Before(time.Now()) {
 		return errors.New("deadline must be in the future")
 	}
-	if input.Budget <= 0 {
+	return nil
+}
+
+// validateTenderDetails checks the fields of a tender that are required
+// whether or not its deadline changes.
+func validateTenderDetails(title, description string, budget float64) error {
+	if title == "" {
+		return errors.New("title is required")
+	}
+	if description == "" {
+		return errors.New("description is required")
+	}
+	if budget <= 0 {
 		return errors.New("budget must be greater than zero")
 	}
 	return nil
@@ -59,20 +93,46 @@ func (s *TenderService) validateCreateTenderInput(input CreateTenderInput) error
 
 // CreateTender creates a new tender
 func (s *TenderService) CreateTender(ctx context.Context, input CreateTenderInput) (*models.Tender, error) {
+	if err := s.validateCreateTenderInput(input); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	tags, err := normalizeTags(input.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	category := categoryCode(input.CategoryCode)
+	if err := s.checkCategory(ctx, category); err != nil {
+		return nil, err
+	}
+	org, err := s.organizations.Resolve(ctx, models.OrganizationClient, input.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizer.Authorize(ctx, ActionCreate, Resource{Type: ResourceTender, OrganizationID: org.ID, TenderOrganizationID: org.ID}); err != nil {
+		return nil, err
+	}
+
+	status := models.TenderStatusOpen
+	if input.Draft {
+		status = models.TenderStatusDraft
+	}
 	tender := &models.Tender{
-		ID:          uuid.New(),
-		ClientID:    input.ClientID,
-		Title:       input.Title,
-		Description: input.Description,
-		Deadline:    input.Deadline,
-		Budget:      input.Budget,
-		Attachment:  input.Attachment,
-		Status:      models.TenderStatusOpen,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	}
-
-	err := s.repo.Create(ctx, tender)
+		ID:             uuid.New(),
+		ClientID:       input.ClientID,
+		OrganizationID: org.ID,
+		Title:          input.Title,
+		Description:    input.Description,
+		Deadline:       input.Deadline,
+		Budget:         input.Budget,
+		Attachment:     input.Attachment,
+		CategoryCode:   category,
+		Tags:           tags,
+		Status:         status,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	err = s.repo.Create(ctx, tender)
 	if err != nil {
 		return nil, err
 	}
@@ -80,90 +140,304 @@ func (s *TenderService) CreateTender(ctx context.Context, input CreateTenderInpu
 	return tender, nil
 }
 
-func (s *TenderService) ListTenders(ctx context.Context, clientID uuid.UUID) ([]models.Tender, error) {
-	return s.repo.ListByClientID(ctx, clientID)
+// ListTenders lists the tenders of every client organization of the caller.
+func (s *TenderService) ListTenders(ctx context.Context, page repository.PageRequest) (*models.Page[models.Tender], error) {
+	ids, err := s.organizations.MemberOrganizationIDs(ctx, models.OrganizationClient)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListByOrganizationIDs(ctx, ids, page)
 }
 
 func (s *TenderService) GetTenderByID(ctx context.Context, id uuid.UUID) (*models.Tender, error) {
 	if id == uuid.Nil {
 		return nil, errors.Join(ErrInvalidInput, errors.New("tender not found"))
 	}
+	return s.authorizedTender(ctx, ActionView, id)
+}
 
-	tender, err := s.repo.GetByID(ctx, id)
+// UpdateTenderStatus moves a tender to status through the lifecycle
+// transition that leads there. reason is required to cancel.
+func (s *TenderService) UpdateTenderStatus(ctx context.Context, id uuid.UUID, status, reason string) (*TenderUpdate, error) {
+	if id == uuid.Nil {
+		return nil, errors.Join(ErrInvalidInput, errors.New("invalid tender ID"))
+	}
+	newStatus := models.TenderStatus(status)
+	if !newStatus.IsValid() {
+		return nil, errors.Join(ErrInvalidInput, errors.New("invalid status"))
+	}
+
+	tender, err := s.authorizedTender(ctx, ActionUpdate, id)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			return nil, ErrTenderNotFound
-		}
 		return nil, err
 	}
-
-	return tender, nil
+	transition, ok := models.TenderTransitionTo(tender.Status, newStatus)
+	if !ok {
+		return nil, ErrInvalidTransition
+	}
+	return s.transition(ctx, tender, transition, reason)
 }
 
+// UpdateTenderInput holds the fields to change; nil fields are kept. An
+// empty Attachment or CategoryCode removes it, and empty Tags remove every
+// tag.
 type UpdateTenderInput struct {
-	ID     uuid.UUID
-	Status *string
+	ID          uuid.UUID
+	Title       *string
+	Description *string
+	Deadline    *time.Time
+	Budget      *float64
+	Attachment  *string
+	// CategoryCode and Tags replace the category and the tags.
+	CategoryCode *string
+	Tags         *[]string
+	// NotifyBidders acknowledges that every bidder is told about a budget
+	// change. Without it the budget of a tender with bids cannot change.
+	NotifyBidders bool
+}
+
+// TenderUpdate is the result of a change to a tender.
+type TenderUpdate struct {
+	Tender *models.Tender
+	// Bidders are the members of every organization that bid on the tender,
+	// who must be notified of the change. It is empty when they need not be.
+	Bidders []uuid.UUID
 }
 
-func (s *TenderService) UpdateTender(ctx context.Context, input UpdateTenderInput) (*models.Tender, error) {
-	// Validate input
+// UpdateTender edits the details of a tender. Awarded tenders cannot be
+// edited, a new deadline must be in the future and the budget of a tender
+// with bids only changes when the bidders are notified.
+func (s *TenderService) UpdateTender(ctx context.Context, input UpdateTenderInput) (*TenderUpdate, error) {
 	if input.ID == uuid.Nil {
 		return nil, errors.Join(ErrInvalidInput, errors.New("invalid tender ID"))
 	}
 
-	// Get existing tender
-	tender, err := s.repo.GetByID(ctx, input.ID)
+	tender, err := s.authorizedTender(ctx, ActionUpdate, input.ID)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			return nil, ErrTenderNotFound
-		}
 		return nil, err
 	}
+	if !tender.Status.Editable() {
+		return nil, ErrTenderLocked
+	}
 
-	if input.Status != nil {
-		newStatus := models.TenderStatus(*input.Status)
-		if !newStatus.IsValid() {
-			return nil, errors.Join(ErrInvalidInput, errors.New("invalid status"))
+	changes := CreateTenderInput{
+		ClientID:     tender.ClientID,
+		Title:        tender.Title,
+		Description:  tender.Description,
+		Deadline:     tender.Deadline,
+		Budget:       tender.Budget,
+		Attachment:   tender.Attachment,
+		CategoryCode: tender.CategoryCode,
+		Tags:         tender.Tags,
+	}
+	if input.Title != nil {
+		changes.Title = *input.Title
+	}
+	if input.Description != nil {
+		changes.Description = *input.Description
+	}
+	if input.Deadline != nil {
+		changes.Deadline = *input.Deadline
+	}
+	if input.Budget != nil {
+		changes.Budget = *input.Budget
+	}
+	if input.Attachment != nil {
+		changes.Attachment = input.Attachment
+		if *input.Attachment == "" {
+			changes.Attachment = nil
 		}
-		tender.Status = newStatus
 	}
 
-	tender.UpdatedAt = time.Now()
-
-	// Save updates
-	err = s.repo.UpdateStatus(ctx, input.ID, string(tender.Status))
+	// A deadline that already passed may stay while other fields change,
+	// but no deadline may be moved into the past.
+	if input.Deadline != nil {
+		err = s.validateCreateTenderInput(changes)
+	} else {
+		err = validateTenderDetails(changes.Title, changes.Description, changes.Budget)
+	}
 	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			return nil, ErrTenderNotFound
+		return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	if input.Tags != nil {
+		changes.Tags, err = normalizeTags(*input.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+		}
+	}
+	if input.CategoryCode != nil {
+		changes.CategoryCode = categoryCode(input.CategoryCode)
+		if err := s.checkCategory(ctx, changes.CategoryCode); err != nil {
+			return nil, err
+		}
+	}
+
+	update := &TenderUpdate{Tender: tender}
+	if changes.Budget != tender.Budget {
+		update.Bidders, err = s.bidders(ctx, tender.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(update.Bidders) > 0 && !input.NotifyBidders {
+			return nil, ErrBudgetLocked
+		}
+	}
+
+	tender.Title = changes.Title
+	tender.Description = changes.Description
+	tender.Deadline = changes.Deadline
+	tender.Budget = changes.Budget
+	tender.Attachment = changes.Attachment
+	tender.CategoryCode = changes.CategoryCode
+	tender.Tags = changes.Tags
+	tender.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, tender); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrTenderLocked
 		}
 		return nil, err
 	}
-	return tender, nil
+	return update, nil
 }
 
 // DeleteTender deletes a tender
-func (s *TenderService) DeleteTender(ctx context.Context, tenderID, clientID uuid.UUID) error {
+func (s *TenderService) DeleteTender(ctx context.Context, tenderID uuid.UUID) error {
 	if tenderID == uuid.Nil {
 		return errors.Join(ErrInvalidInput, errors.New("invalid tender ID"))
 	}
 
-	// Get existing tender
-	tender, err := s.repo.GetByID(ctx, tenderID)
+	if _, err := s.authorizedTender(ctx, ActionDelete, tenderID); err != nil {
+		return err
+	}
+
+	// The metadata of the documents goes with the tender, their files are
+	// removed afterwards
+	docs, err := s.documents.ListForTender(ctx, tenderID)
 	if err != nil {
-		if errors.Is(err, repository.ErrNotFound) {
-			return ErrTenderNotFound
+		return err
+	}
+	if err := s.repo.Delete(ctx, tenderID); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return ErrTenderHasBids
 		}
 		return err
 	}
+	s.documents.DeleteTenderFiles(ctx, docs)
+	return nil
+}
+
+// TenderSearch is a page of a filtered tender list. The facets count the
+// whole list, not just the page.
+type TenderSearch struct {
+	models.Page[models.Tender]
+	Facets *models.TenderFacets `json:"facets"`
+}
+
+// ListTendersFiltering lists the tenders matching filters together with
+// their counts by category and by status. Only admins see the tenders of
+// every client; everyone else is limited to the tenders of their
+// organizations.
+func (s *TenderService) ListTendersFiltering(ctx context.Context, filters repository.TenderFilters, page repository.PageRequest) (*TenderSearch, error) {
+	p, ok := models.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	if err := s.validateTenderFilters(ctx, &filters); err != nil {
+		return nil, err
+	}
+	if !p.HasRole(models.RoleAdmin) {
+		ids, err := s.organizations.MemberOrganizationIDs(ctx, models.OrganizationClient)
+		if err != nil {
+			return nil, err
+		}
+		filters.OrganizationIDs = ids
+	}
+
+	tenders, err := s.repo.List(ctx, filters, page)
+	if err != nil {
+		return nil, err
+	}
+	facets, err := s.repo.Facets(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	return &TenderSearch{Page: *tenders, Facets: facets}, nil
+}
 
-	// Check authorization
-	if tender.ClientID != clientID {
+// validateTenderFilters checks filters and normalizes the search and the tag
+// to match the stored tags.
+func (s *TenderService) validateTenderFilters(ctx context.Context, filters *repository.TenderFilters) error {
+	filters.Search = strings.TrimSpace(filters.Search)
+	if filters.Status != "" && !models.TenderStatus(filters.Status).IsValid() {
+		return fmt.Errorf("%w: invalid status %q", ErrInvalidInput, filters.Status)
+	}
+	if filters.Category != "" {
+		if err := s.checkCategory(ctx, &filters.Category); err != nil {
+			return err
+		}
+	}
+	if filters.Tag != "" {
+		tag, err := normalizeTag(filters.Tag)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidInput, err)
+		}
+		filters.Tag = tag
+	}
+	if filters.MinBudget != nil && filters.MaxBudget != nil && *filters.MinBudget > *filters.MaxBudget {
+		return fmt.Errorf("%w: min_budget is greater than max_budget", ErrInvalidInput)
+	}
+	if filters.DeadlineAfter != nil && filters.DeadlineBefore != nil && filters.DeadlineAfter.After(*filters.DeadlineBefore) {
+		return fmt.Errorf("%w: deadline_after is later than deadline_before", ErrInvalidInput)
+	}
+	switch filters.Sort {
+	case repository.TenderSortNewest, repository.TenderSortDeadline, repository.TenderSortDeadlineDesc:
+	default:
+		return fmt.Errorf("%w: invalid sort %q", ErrInvalidInput, filters.Sort)
+	}
+	return nil
+}
+
+// WatchTender subscribes the caller to the deadline reminders of an open
+// tender without bidding on it.
+func (s *TenderService) WatchTender(ctx context.Context, id uuid.UUID) error {
+	p, ok := models.PrincipalFromContext(ctx)
+	if !ok {
 		return ErrUnauthorized
 	}
+	if _, err := s.authorizedTender(ctx, ActionWatch, id); err != nil {
+		return err
+	}
+	return s.repo.Watch(ctx, id, p.UserID, time.Now())
+}
 
-	return s.repo.Delete(ctx, tenderID)
+// UnwatchTender unsubscribes the caller from a tender. It works in any status
+// of the tender.
+func (s *TenderService) UnwatchTender(ctx context.Context, id uuid.UUID) error {
+	p, ok := models.PrincipalFromContext(ctx)
+	if !ok {
+		return ErrUnauthorized
+	}
+	if err := s.repo.Unwatch(ctx, id, p.UserID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrNotWatching
+		}
+		return err
+	}
+	return nil
 }
 
-func (s *TenderService) ListTendersFiltering(ctx context.Context, filters repository.TenderFilters) ([]models.Tender, error) {
-	return s.repo.List(ctx, filters)
+// authorizedTender loads a tender and checks that the caller may perform
+// action on it.
+func (s *TenderService) authorizedTender(ctx context.Context, action Action, id uuid.UUID) (*models.Tender, error) {
+	tender, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTenderNotFound
+		}
+		return nil, err
+	}
+	if err := s.authorizer.Authorize(ctx, action, TenderResource(tender)); err != nil {
+		return nil, err
+	}
+	return tender, nil
 }
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/models"
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidTransition  = errors.New("transition not allowed from the tender's status")
+	ErrDeadlinePassed     = errors.New("the deadline has passed, move it first")
+	ErrNoBids             = errors.New("the tender has no bids")
+	ErrCancellationReason = errors.New("a reason of 1 to 500 characters is required to cancel")
+	ErrAwardRequiresBid   = errors.New("tenders are awarded by awarding one of their bids")
+)
+
+const maxCancellationReasonLength = 500
+
+type TransitionTenderInput struct {
+	Transition models.TenderTransition `json:"transition" binding:"required"`
+	// Reason is required to cancel.
+	Reason string `json:"reason,omitempty"`
+}
+
+// TenderLifecycle is where a tender stands in its lifecycle.
+type TenderLifecycle struct {
+	Status models.TenderStatus `json:"status"`
+	// Transitions are the transitions allowed from Status.
+	Transitions []models.TenderTransition   `json:"transitions"`
+	History     []models.TenderStatusChange `json:"history"`
+}
+
+// TransitionTender moves a tender through its lifecycle. Everyone who bid on
+// the tender is to be notified, except of publishing since there are no bids
+// yet.
+func (s *TenderService) TransitionTender(ctx context.Context, id uuid.UUID, input TransitionTenderInput) (*TenderUpdate, error) {
+	tender, err := s.authorizedTender(ctx, ActionUpdate, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.transition(ctx, tender, input.Transition, input.Reason)
+}
+
+// GetTenderLifecycle returns the status of a tender, the transitions allowed
+// from it and its status history.
+func (s *TenderService) GetTenderLifecycle(ctx context.Context, id uuid.UUID) (*TenderLifecycle, error) {
+	tender, err := s.authorizedTender(ctx, ActionView, id)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.repo.ListStatusChanges(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &TenderLifecycle{
+		Status:      tender.Status,
+		Transitions: tender.Status.Transitions(),
+		History:     history,
+	}, nil
+}
+
+// transition checks the guards of t and applies it to tender.
+func (s *TenderService) transition(ctx context.Context, tender *models.Tender, t models.TenderTransition, reason string) (*TenderUpdate, error) {
+	to, ok := t.Target(tender.Status)
+	if !ok {
+		return nil, ErrInvalidTransition
+	}
+
+	reason = strings.TrimSpace(reason)
+	switch t {
+	case models.TenderTransitionPublish:
+		if err := validateTenderDetails(tender.Title, tender.Description, tender.Budget); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidInput, err)
+		}
+		if !tender.Deadline.After(time.Now()) {
+			return nil, ErrDeadlinePassed
+		}
+	case models.TenderTransitionReopen:
+		if !tender.Deadline.After(time.Now()) {
+			return nil, ErrDeadlinePassed
+		}
+	case models.TenderTransitionAward:
+		return nil, ErrAwardRequiresBid
+	case models.TenderTransitionCancel:
+		if reason == "" || len(reason) > maxCancellationReasonLength {
+			return nil, ErrCancellationReason
+		}
+	}
+
+	update := &TenderUpdate{Tender: tender}
+	if t != models.TenderTransitionPublish {
+		bidders, err := s.bidders(ctx, tender.ID)
+		if err != nil {
+			return nil, err
+		}
+		if t == models.TenderTransitionEvaluate && len(bidders) == 0 {
+			return nil, ErrNoBids
+		}
+		update.Bidders = bidders
+	}
+
+	change := newStatusChange(ctx, tender, t, to, reason)
+	if err := s.repo.Transition(ctx, change); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrInvalidTransition
+		}
+		return nil, err
+	}
+
+	tender.Status = to
+	tender.UpdatedAt = change.CreatedAt
+	if to == models.TenderStatusCancelled {
+		tender.CancellationReason = change.Reason
+	}
+	return update, nil
+}
+
+// bidders returns the members of every organization that bid on a tender.
+func (s *TenderService) bidders(ctx context.Context, tenderID uuid.UUID) ([]uuid.UUID, error) {
+	orgIDs, err := s.bidRepo.ListBidderOrganizationIDs(ctx, tenderID)
+	if err != nil {
+		return nil, err
+	}
+	var members []uuid.UUID
+	for _, orgID := range orgIDs {
+		ids, err := s.organizations.memberIDs(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, ids...)
+	}
+	return members, nil
+}
+
+// newStatusChange describes moving tender to status to. The caller in ctx,
+// if any, is recorded as the actor.
+func newStatusChange(ctx context.Context, tender *models.Tender, t models.TenderTransition, to models.TenderStatus, reason string) *models.TenderStatusChange {
+	change := &models.TenderStatusChange{
+		ID:         uuid.New(),
+		TenderID:   tender.ID,
+		Transition: t,
+		FromStatus: tender.Status,
+		ToStatus:   to,
+		CreatedAt:  time.Now(),
+	}
+	if reason != "" {
+		change.Reason = &reason
+	}
+	if p, ok := models.PrincipalFromContext(ctx); ok {
+		change.ActorID = &p.UserID
+	}
+	return change
+}
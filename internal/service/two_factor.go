@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Dostonlv/hackathon-nt/internal/repository"
+	"github.com/Dostonlv/hackathon-nt/internal/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication not enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor enrollment not started")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrInvalidLoginChallenge   = errors.New("invalid or expired login challenge")
+)
+
+const (
+	recoveryCodeCount = 10
+	// maxChallengeAttempts bounds how many codes may be tried against one
+	// login challenge before the password has to be entered again.
+	maxChallengeAttempts = 5
+)
+
+// TwoFactorConfig holds the TOTP settings.
+type TwoFactorConfig struct {
+	// Issuer is the account label shown in authenticator apps.
+	Issuer string
+	// EncryptionSecret encrypts TOTP secrets at rest.
+	EncryptionSecret string
+	// ChallengeTTL is how long a login challenge waits for its code.
+	ChallengeTTL time.Duration
+}
+
+// TwoFactorChallenge is returned by Login instead of tokens when the user has
+// two-factor authentication enabled.
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+type TwoFactorLoginInput struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	// Code is a TOTP code or an unused recovery code.
+	Code string `json:"code" validate:"required"`
+	// IP and UserAgent are set by the handler, as in LoginInput.
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+type TwoFactorCodeInput struct {
+	Code string `json:"code" validate:"required"`
+	// IP and UserAgent are set by the handler, as in LoginInput.
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// TwoFactorEnrollment is the secret of a pending enrollment, to be added to
+// an authenticator app by scanning URI as a QR code or typing Secret.
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TwoFactorStatus struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// RecoveryCodes are shown to the user once; only their hashes are stored.
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// TwoFactorStatus reports whether the user has two-factor authentication
+// enabled and how many recovery codes are left.
+func (s *AuthService) TwoFactorStatus(ctx context.Context, userID uuid.UUID) (*TwoFactorStatus, error) {
+	enabled, err := s.twoFactorEnabled(ctx, userID)
+	if err != nil || !enabled {
+		return &TwoFactorStatus{}, err
+	}
+	remaining, err := s.twoFactorRepo.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorStatus{Enabled: true, RecoveryCodesRemaining: remaining}, nil
+}
+
+// BeginTwoFactorEnrollment creates a new TOTP secret for the user. It only
+// takes effect once ConfirmTwoFactor receives a valid code for it.
+func (s *AuthService) BeginTwoFactorEnrollment(ctx context.Context, userID uuid.UUID) (*TwoFactorEnrollment, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := utils.Seal(secret, s.twoFactor.EncryptionSecret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.SavePendingTwoFactor(ctx, userID, sealed); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return &TwoFactorEnrollment{
+		Secret: utils.EncodeTOTPSecret(secret),
+		URI:    utils.TOTPURI(s.twoFactor.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables two-factor authentication once the user proves
+// their authenticator works, and returns the recovery codes.
+func (s *AuthService) ConfirmTwoFactor(ctx context.Context, userID uuid.UUID, input TwoFactorCodeInput) (*RecoveryCodes, error) {
+	tf, err := s.twoFactorRepo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrTwoFactorNotEnrolled
+		}
+		return nil, err
+	}
+	if tf.Enabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := utils.Open(tf.Secret, s.twoFactor.EncryptionSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := utils.ValidateTOTP(secret, input.Code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.EnableTwoFactor(ctx, userID, step, hashes); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			return nil, ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+	return &RecoveryCodes{Codes: codes}, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off after checking a TOTP
+// or recovery code.
+func (s *AuthService) DisableTwoFactor(ctx context.Context, userID uuid.UUID, input TwoFactorCodeInput) error {
+	if err := s.guardedSecondFactor(ctx, userID, input, "disable two-factor authentication"); err != nil {
+		return err
+	}
+	return s.twoFactorRepo.DisableTwoFactor(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user after
+// checking a TOTP or recovery code.
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, input TwoFactorCodeInput) (*RecoveryCodes, error) {
+	if err := s.guardedSecondFactor(ctx, userID, input, "regenerate recovery codes"); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return &RecoveryCodes{Codes: codes}, nil
+}
+
+// CompleteTwoFactorLogin exchanges a login challenge and a TOTP or recovery
+// code for tokens. The session is marked as opened with a second factor.
+func (s *AuthService) CompleteTwoFactorLogin(ctx context.Context, input TwoFactorLoginInput) (*AuthResponse, error) {
+	hash := utils.HashToken(input.ChallengeToken)
+	userID, err := s.twoFactorRepo.GetLoginChallenge(ctx, hash)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidLoginChallenge
+		}
+		return nil, err
+	}
+
+	attempts, err := s.twoFactorRepo.CountLoginChallengeAttempt(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if attempts > maxChallengeAttempts {
+		if err := s.twoFactorRepo.DeleteLoginChallenge(ctx, hash); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Suspended() {
+		return nil, ErrAccountSuspended
+	}
+	attempt := LoginAttempt{Username: user.Username, IP: input.IP, UserAgent: input.UserAgent}
+	if err := s.loginGuard.Check(ctx, attempt); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifySecondFactor(ctx, userID, input.Code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if err := s.loginGuard.Failed(ctx, attempt, &user.ID, "invalid two-factor code"); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+	if err := s.twoFactorRepo.DeleteLoginChallenge(ctx, hash); err != nil {
+		return nil, err
+	}
+
+	if err := s.loginGuard.Succeeded(ctx, attempt, user.ID, "password and two-factor code"); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, user, uuid.New(), nil, true)
+}
+
+// guardedSecondFactor checks a code of a signed in user for action. Wrong
+// codes count as failed logins of the account, so a stolen access token
+// cannot be used to guess codes faster than a login could.
+func (s *AuthService) guardedSecondFactor(ctx context.Context, userID uuid.UUID, input TwoFactorCodeInput, action string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	attempt := LoginAttempt{Username: user.Username, IP: input.IP, UserAgent: input.UserAgent}
+	if err := s.loginGuard.Check(ctx, attempt); err != nil {
+		return err
+	}
+
+	if err := s.verifySecondFactor(ctx, userID, input.Code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			if err := s.loginGuard.Failed(ctx, attempt, &user.ID, "invalid two-factor code to "+action); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+	return s.loginGuard.Reset(ctx, attempt)
+}
+
+func (s *AuthService) twoFactorEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	tf, err := s.twoFactorRepo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return tf.Enabled(), nil
+}
+
+func (s *AuthService) createLoginChallenge(ctx context.Context, userID uuid.UUID) (*TwoFactorChallenge, error) {
+	token, hash, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepo.CreateLoginChallenge(ctx, hash, userID, s.twoFactor.ChallengeTTL); err != nil {
+		return nil, err
+	}
+	return &TwoFactorChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresAt:         time.Now().Add(s.twoFactor.ChallengeTTL),
+	}, nil
+}
+
+// verifySecondFactor accepts a TOTP code that was not used before or an
+// unused recovery code, which is then spent.
+func (s *AuthService) verifySecondFactor(ctx context.Context, userID uuid.UUID, code string) error {
+	tf, err := s.twoFactorRepo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrTwoFactorNotEnabled
+		}
+		return err
+	}
+	if !tf.Enabled() {
+		return ErrTwoFactorNotEnabled
+	}
+
+	secret, err := utils.Open(tf.Secret, s.twoFactor.EncryptionSecret)
+	if err != nil {
+		return err
+	}
+	if step, ok := utils.ValidateTOTP(secret, code, time.Now()); ok {
+		if err := s.twoFactorRepo.UseTOTPStep(ctx, userID, step); err != nil {
+			if errors.Is(err, repository.ErrConflict) {
+				return ErrInvalidTwoFactorCode
+			}
+			return err
+		}
+		return nil
+	}
+
+	err = s.twoFactorRepo.UseRecoveryCode(ctx, userID, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrInvalidTwoFactorCode
+		}
+		return err
+	}
+	return nil
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = utils.HashToken(utils.NormalizeRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
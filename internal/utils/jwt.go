@@ -2,7 +2,9 @@ package utils
 
 import (
 	"errors"
+	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Dostonlv/hackathon-nt/internal/models"
@@ -10,55 +12,126 @@ import (
 	"github.com/google/uuid"
 )
 
+// JWTUtil issues and verifies access tokens. With the HS256 algorithm tokens
+// are signed with the shared secret. With RS256 or EdDSA they are signed with
+// the active key of a rotating key set and carry its ID in the kid header, so
+// other services can verify them through the JWKS endpoint.
 type JWTUtil struct {
 	secretKey []byte
+	tokenTTL  time.Duration
+	algorithm string
+
+	mu   sync.RWMutex
+	keys []SigningKey
 }
 
 type Claims struct {
 	UserID uuid.UUID       `json:"user_id"`
 	Role   models.UserRole `json:"role"`
+	// MFA is set when the session was opened with a second factor.
+	MFA bool `json:"mfa,omitempty"`
+	// IssuedAtMicro is when the token was issued in microseconds. iat only
+	// has seconds, too coarse to tell a token issued right after a logout
+	// of every session from one issued before it.
+	IssuedAtMicro int64 `json:"iat_us,omitempty"`
 	jwt.StandardClaims
 }
 
-func NewJWTUtil(secretKey string) *JWTUtil {
+// IssuedTime returns when the token was issued, to the second for tokens
+// without iat_us.
+func (c *Claims) IssuedTime() time.Time {
+	if c.IssuedAtMicro != 0 {
+		return time.UnixMicro(c.IssuedAtMicro)
+	}
+	return time.Unix(c.IssuedAt, 0)
+}
+
+func NewJWTUtil(secretKey string, tokenTTL time.Duration) *JWTUtil {
 	return &JWTUtil{
 		secretKey: []byte(secretKey),
+		tokenTTL:  tokenTTL,
+		algorithm: AlgorithmHS256,
 	}
 }
 
-func (j *JWTUtil) GenerateToken(userID uuid.UUID, role models.UserRole) (string, error) {
-	claims := Claims{
-		UserID: userID,
-		Role:   role,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(24 * time.Hour).Unix(),
-			IssuedAt:  time.Now().Unix(),
-		},
+// NewAsymmetricJWTUtil creates a JWTUtil that signs with RS256 or EdDSA keys
+// provided through SetSigningKeys.
+func NewAsymmetricJWTUtil(algorithm string, tokenTTL time.Duration) (*JWTUtil, error) {
+	if _, err := signingMethod(algorithm); err != nil {
+		return nil, err
+	}
+	if algorithm == AlgorithmHS256 {
+		return nil, errors.New("HS256 is not an asymmetric algorithm")
 	}
+	return &JWTUtil{
+		tokenTTL:  tokenTTL,
+		algorithm: algorithm,
+	}, nil
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secretKey)
+// Algorithm returns the algorithm new tokens are signed with.
+func (j *JWTUtil) Algorithm() string {
+	return j.algorithm
 }
 
-func (j *JWTUtil) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return j.secretKey, nil
-	})
+// TokenTTL returns the lifetime of access tokens.
+func (j *JWTUtil) TokenTTL() time.Duration {
+	return j.tokenTTL
+}
 
-	if err != nil {
-		return nil, err
+// SetSigningKeys replaces the key set. Keys past their expiry are dropped.
+func (j *JWTUtil) SetSigningKeys(keys []SigningKey) {
+	now := time.Now()
+	current := make([]SigningKey, 0, len(keys))
+	for _, key := range keys {
+		if key.ExpiresAt.After(now) {
+			current = append(current, key)
+		}
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	j.mu.Lock()
+	j.keys = current
+	j.mu.Unlock()
+}
+
+// SigningKeys returns the keys currently used for verification, including
+// keys that are published but not yet used for signing.
+func (j *JWTUtil) SigningKeys() []SigningKey {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return append([]SigningKey(nil), j.keys...)
+}
+
+func (j *JWTUtil) GenerateToken(userID uuid.UUID, role models.UserRole, mfa bool) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID:        userID,
+		Role:          role,
+		MFA:           mfa,
+		IssuedAtMicro: now.UnixMicro(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.New().String(),
+			ExpiresAt: now.Add(j.tokenTTL).Unix(),
+			IssuedAt:  now.Unix(),
+		},
 	}
 
-	return nil, errors.New("invalid token")
+	if j.algorithm == AlgorithmHS256 {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString(j.secretKey)
+	}
+
+	key, ok := j.activeKey()
+	if !ok {
+		return "", errors.New("no active signing key")
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
 }
-func (j *JWTUtil) ParseToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return j.secretKey, nil
-	})
+
+func (j *JWTUtil) ValidateToken(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, j.keyFunc)
 
 	if err != nil {
 		return nil, err
@@ -71,25 +144,49 @@ func (j *JWTUtil) ParseToken(tokenString string) (*Claims, error) {
 	return nil, errors.New("invalid token")
 }
 
-func ParseToken(tokenString string, secretKey []byte) (*Claims, error) {
-	var jwtString string
-	if strings.HasPrefix(tokenString, "Bearer ") {
-		jwtString = strings.Split(tokenString, "Bearer ")[1]
-	} else {
-		jwtString = tokenString
-	}
-
-	token, err := jwt.ParseWithClaims(jwtString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return secretKey, nil
-	})
+// ParseToken validates a token taken from an Authorization header, with or
+// without the Bearer prefix.
+func (j *JWTUtil) ParseToken(tokenString string) (*Claims, error) {
+	return j.ValidateToken(strings.TrimPrefix(tokenString, "Bearer "))
+}
 
-	if err != nil {
-		return nil, err
+func (j *JWTUtil) keyFunc(token *jwt.Token) (interface{}, error) {
+	if j.algorithm == AlgorithmHS256 {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return j.secretKey, nil
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("missing kid header")
+	}
+	for _, key := range j.SigningKeys() {
+		if key.ID == kid {
+			if key.Method.Alg() != token.Method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+			}
+			return key.Public, nil
+		}
 	}
+	return nil, fmt.Errorf("unknown kid %q", kid)
+}
 
-	return nil, errors.New("invalid token")
+// activeKey returns the most recently activated key.
+func (j *JWTUtil) activeKey() (SigningKey, bool) {
+	now := time.Now()
+	var (
+		active SigningKey
+		found  bool
+	)
+	for _, key := range j.SigningKeys() {
+		if key.ActivatesAt.After(now) || !key.ExpiresAt.After(now) {
+			continue
+		}
+		if !found || key.ActivatesAt.After(active.ActivatesAt) {
+			active, found = key, true
+		}
+	}
+	return active, found
 }
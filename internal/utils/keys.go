@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const rsaKeyBits = 2048
+
+// SigningKey is an asymmetric key of the JWT key set. A key is published in
+// the JWKS as soon as it is loaded, signs tokens from ActivatesAt until a
+// newer key activates, and is kept for verification until ExpiresAt.
+type SigningKey struct {
+	ID          string
+	Method      jwt.SigningMethod
+	Private     crypto.PrivateKey
+	Public      crypto.PublicKey
+	ActivatesAt time.Time
+	ExpiresAt   time.Time
+}
+
+func signingMethod(algorithm string) (jwt.SigningMethod, error) {
+	switch algorithm {
+	case AlgorithmHS256:
+		return jwt.SigningMethodHS256, nil
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgorithmEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported JWT algorithm %q", algorithm)
+}
+
+// GenerateSigningKey creates a new key pair for algorithm.
+func GenerateSigningKey(algorithm string, activatesAt, expiresAt time.Time) (SigningKey, error) {
+	method, err := signingMethod(algorithm)
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	key := SigningKey{
+		ID:          uuid.New().String(),
+		Method:      method,
+		ActivatesAt: activatesAt,
+		ExpiresAt:   expiresAt,
+	}
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return SigningKey{}, err
+		}
+		key.Private, key.Public = private, &private.PublicKey
+	case AlgorithmEdDSA:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return SigningKey{}, err
+		}
+		key.Private, key.Public = private, public
+	default:
+		return SigningKey{}, fmt.Errorf("%s keys cannot be generated", algorithm)
+	}
+	return key, nil
+}
+
+// SealPrivateKey encodes the private key as PKCS#8 and encrypts it with
+// AES-GCM under a key derived from secret, for storage at rest.
+func SealPrivateKey(key crypto.PrivateKey, secret string) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return Seal(der, secret)
+}
+
+// OpenSigningKey decrypts a key sealed by SealPrivateKey.
+func OpenSigningKey(id, algorithm string, sealed []byte, secret string, activatesAt, expiresAt time.Time) (SigningKey, error) {
+	method, err := signingMethod(algorithm)
+	if err != nil {
+		return SigningKey{}, err
+	}
+	der, err := Open(sealed, secret)
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("error decrypting signing key %s: %w", id, err)
+	}
+	private, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	key := SigningKey{
+		ID:          id,
+		Method:      method,
+		Private:     private,
+		ActivatesAt: activatesAt,
+		ExpiresAt:   expiresAt,
+	}
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		key.Public = &private.PublicKey
+	case ed25519.PrivateKey:
+		key.Public = private.Public()
+	default:
+		return SigningKey{}, fmt.Errorf("unsupported private key type %T", private)
+	}
+	return key, nil
+}
+
+// Seal encrypts data with AES-GCM under a key derived from secret, for
+// storing secrets at rest.
+func Seal(data []byte, secret string) ([]byte, error) {
+	gcm, err := keyCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// Open decrypts data sealed by Seal.
+func Open(sealed []byte, secret string) ([]byte, error) {
+	gcm, err := keyCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("sealed data is too short")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+func keyCipher(secret string) (cipher.AEAD, error) {
+	sum := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// JWK is a public JSON Web Key (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys that verify tokens issued by j.
+func (j *JWTUtil) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range j.SigningKeys() {
+		jwk := JWK{
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+		}
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
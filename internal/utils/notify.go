@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
@@ -11,7 +12,8 @@ import (
 
 type NotificationService struct {
 	// Map of client ID to their WebSocket connection
-	clients sync.Map
+	clients      sync.Map
+	writeTimeout time.Duration
 }
 
 type BidNotification struct {
@@ -22,8 +24,8 @@ type BidNotification struct {
 	Message  string    `json:"message"`
 }
 
-func NewNotificationService() *NotificationService {
-	return &NotificationService{}
+func NewNotificationService(writeTimeout time.Duration) *NotificationService {
+	return &NotificationService{writeTimeout: writeTimeout}
 }
 
 // RegisterClient registers a new WebSocket client
@@ -33,7 +35,20 @@ func (s *NotificationService) RegisterClient(clientID uuid.UUID, conn *websocket
 
 // UnregisterClient removes a WebSocket client
 func (s *NotificationService) UnregisterClient(clientID uuid.UUID) {
-	s.clients.Delete(clientID)
+	s.clients.Delete(clientID.String())
+}
+
+// Shutdown sends a close frame to every connected client and closes the
+// connections.
+func (s *NotificationService) Shutdown() {
+	message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	s.clients.Range(func(key, value interface{}) bool {
+		conn := value.(*websocket.Conn)
+		conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(s.writeTimeout))
+		conn.Close()
+		s.clients.Delete(key)
+		return true
+	})
 }
 
 // NotifyNewBid sends a notification to a specific client about a new bid
@@ -49,6 +64,7 @@ func (s *NotificationService) NotifyNewBid(ctx context.Context, clientID uuid.UU
 		return err
 	}
 
+	wsConn.SetWriteDeadline(time.Now().Add(s.writeTimeout))
 	return wsConn.WriteMessage(websocket.TextMessage, message)
 }
 
@@ -72,5 +88,34 @@ func (s *NotificationService) NotifyAward(ctx context.Context, clientID uuid.UUI
 		return err
 	}
 
+	wsConn.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+	return wsConn.WriteMessage(websocket.TextMessage, message)
+}
+
+// TenderNotification tells bidders that a tender they bid on changed.
+type TenderNotification struct {
+	Type     string     `json:"type"`
+	TenderID uuid.UUID  `json:"tender_id"`
+	Status   string     `json:"status,omitempty"`
+	Budget   float64    `json:"budget,omitempty"`
+	Deadline *time.Time `json:"deadline,omitempty"`
+	Message  string     `json:"message"`
+}
+
+// NotifyTenderUpdate sends a notification to a specific user about a changed
+// tender
+func (s *NotificationService) NotifyTenderUpdate(ctx context.Context, userID uuid.UUID, notification TenderNotification) error {
+	conn, ok := s.clients.Load(userID.String())
+	if !ok {
+		return nil // Client not connected, silently ignore
+	}
+
+	wsConn := conn.(*websocket.Conn)
+	message, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	wsConn.SetWriteDeadline(time.Now().Add(s.writeTimeout))
 	return wsConn.WriteMessage(websocket.TextMessage, message)
 }
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// PolicyWatcher is a casbin watcher that tells every instance to reload its
+// policy after one of them changed it, using Redis pub/sub. Since pub/sub
+// drops messages while an instance is disconnected, Run also reloads on a
+// fixed interval.
+type PolicyWatcher struct {
+	client     *redis.Client
+	channel    string
+	instanceID string
+
+	mu       sync.RWMutex
+	callback func(string)
+}
+
+func NewPolicyWatcher(client *redis.Client, channel string) *PolicyWatcher {
+	return &PolicyWatcher{
+		client:     client,
+		channel:    channel,
+		instanceID: uuid.NewString(),
+	}
+}
+
+// SetUpdateCallback sets the function called when another instance changed
+// the policy.
+func (w *PolicyWatcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	w.callback = callback
+	w.mu.Unlock()
+	return nil
+}
+
+// Update announces a policy change made by this instance. A failed publish
+// is only logged: the change is already stored and other instances pick it
+// up on their next periodic reload.
+func (w *PolicyWatcher) Update() error {
+	if err := w.client.Publish(context.Background(), w.channel, w.instanceID).Err(); err != nil {
+		log.Printf("Failed to publish policy update: %v", err)
+	}
+	return nil
+}
+
+func (w *PolicyWatcher) Close() {}
+
+// Run listens for policy changes of other instances until ctx is cancelled,
+// and reloads every reloadInterval when it is positive.
+func (w *PolicyWatcher) Run(ctx context.Context, reloadInterval time.Duration) {
+	sub := w.client.Subscribe(ctx, w.channel)
+	defer sub.Close()
+	messages := sub.Channel()
+
+	var tick <-chan time.Time
+	if reloadInterval > 0 {
+		ticker := time.NewTicker(reloadInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			if msg.Payload != w.instanceID {
+				w.notify(msg.Payload)
+			}
+		case <-tick:
+			w.notify("")
+		}
+	}
+}
+
+func (w *PolicyWatcher) notify(source string) {
+	w.mu.RLock()
+	callback := w.callback
+	w.mu.RUnlock()
+	if callback != nil {
+		callback(source)
+	}
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of the test vectors in RFC 6238,
+// appendix B. The expected codes are the last six of its eight digits.
+var rfc6238Secret = []byte("12345678901234567890")
+
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestTOTPCode(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		if got := totpCode(rfc6238Secret, v.unix/totpPeriod); got != v.code {
+			t.Errorf("totpCode at %d = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		step, ok := ValidateTOTP(rfc6238Secret, v.code, time.Unix(v.unix, 0))
+		if !ok || step != v.unix/totpPeriod {
+			t.Errorf("ValidateTOTP(%s) at %d = %d, %v, want %d, true", v.code, v.unix, step, ok, v.unix/totpPeriod)
+		}
+	}
+
+	const unix = 1111111109
+	code := "081804"
+	tests := []struct {
+		name string
+		code string
+		at   int64
+		ok   bool
+	}{
+		{"surrounding spaces", " " + code + " ", unix, true},
+		{"one period late", code, unix + totpPeriod, true},
+		{"one period early", code, unix - totpPeriod, true},
+		{"two periods late", code, unix + 2*totpPeriod, false},
+		{"wrong code", "081805", unix, false},
+		{"too short", code[:5], unix, false},
+		{"too long", code + "0", unix, false},
+		{"empty", "", unix, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(rfc6238Secret, tt.code, time.Unix(tt.at, 0)); ok != tt.ok {
+				t.Errorf("ValidateTOTP(%q) = %v, want %v", tt.code, ok, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("GenerateRecoveryCodes returned %q, want xxxxx-xxxxx", code)
+		}
+		want := code[:5] + code[6:]
+		for _, typed := range []string{code, " " + code + " ", code[:5] + " " + code[6:], want} {
+			if got := NormalizeRecoveryCode(typed); got != want {
+				t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", typed, got, want)
+			}
+		}
+	}
+	if got := NormalizeRecoveryCode("ABCDE-FGHIJ"); got != "abcdefghij" {
+		t.Errorf("NormalizeRecoveryCode(ABCDE-FGHIJ) = %q, want abcdefghij", got)
+	}
+}
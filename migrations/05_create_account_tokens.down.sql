@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_account_tokens_user_id_purpose;
+DROP TABLE IF EXISTS account_tokens;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
\ No newline at end of file
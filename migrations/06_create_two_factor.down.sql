@@ -0,0 +1,4 @@
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS mfa;
+DROP INDEX IF EXISTS idx_two_factor_recovery_codes_user_id;
+DROP TABLE IF EXISTS two_factor_recovery_codes;
+DROP TABLE IF EXISTS user_two_factor;
\ No newline at end of file
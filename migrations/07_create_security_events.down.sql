@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_security_events_ip;
+DROP INDEX IF EXISTS idx_security_events_user_id;
+DROP INDEX IF EXISTS idx_security_events_created_at;
+DROP TABLE IF EXISTS security_events;
\ No newline at end of file
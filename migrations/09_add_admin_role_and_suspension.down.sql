@@ -0,0 +1,9 @@
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_at;
+
+-- Admins become clients, the role they are closest to.
+ALTER TYPE user_role RENAME TO user_role_old;
+CREATE TYPE user_role AS ENUM ('client', 'contractor');
+ALTER TABLE users ALTER COLUMN role TYPE user_role
+    USING (CASE WHEN role::text = 'admin' THEN 'client' ELSE role::text END)::user_role;
+DROP TYPE user_role_old;
@@ -0,0 +1,9 @@
+-- Recreate the enum instead of ALTER TYPE ... ADD VALUE, which cannot run
+-- inside the migration transaction on Postgres before 12.
+ALTER TYPE user_role RENAME TO user_role_old;
+CREATE TYPE user_role AS ENUM ('client', 'contractor', 'admin');
+ALTER TABLE users ALTER COLUMN role TYPE user_role USING role::text::user_role;
+DROP TYPE user_role_old;
+
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN suspended_reason TEXT;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_policy_audit_created_at;
+DROP TABLE IF EXISTS policy_audit;
+DROP TABLE IF EXISTS casbin_rules;
@@ -0,0 +1,22 @@
+CREATE TABLE casbin_rules (
+    id BIGSERIAL PRIMARY KEY,
+    ptype VARCHAR(8) NOT NULL,
+    v0 VARCHAR(255) NOT NULL DEFAULT '',
+    v1 VARCHAR(255) NOT NULL DEFAULT '',
+    v2 VARCHAR(255) NOT NULL DEFAULT '',
+    v3 VARCHAR(255) NOT NULL DEFAULT '',
+    v4 VARCHAR(255) NOT NULL DEFAULT '',
+    v5 VARCHAR(255) NOT NULL DEFAULT '',
+    UNIQUE (ptype, v0, v1, v2, v3, v4, v5)
+);
+
+CREATE TABLE policy_audit (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(16) NOT NULL,
+    ptype VARCHAR(8) NOT NULL,
+    rule TEXT[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_policy_audit_created_at ON policy_audit(created_at);
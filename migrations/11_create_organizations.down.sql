@@ -0,0 +1,12 @@
+DELETE FROM casbin_rules WHERE ptype = 'p' AND v1 IN ('/api/organizations', '/api/organizations/*');
+
+DROP INDEX IF EXISTS idx_bids_organization_id;
+DROP INDEX IF EXISTS idx_tenders_organization_id;
+ALTER TABLE bids DROP COLUMN IF EXISTS organization_id;
+ALTER TABLE tenders DROP COLUMN IF EXISTS organization_id;
+
+DROP TABLE IF EXISTS organization_invitations;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
+DROP TYPE IF EXISTS organization_role;
+DROP TYPE IF EXISTS organization_kind;
@@ -0,0 +1,89 @@
+CREATE TYPE organization_kind AS ENUM ('client', 'contractor');
+CREATE TYPE organization_role AS ENUM ('owner', 'manager', 'viewer', 'bidder');
+
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    kind organization_kind NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE organization_members (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role organization_role NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX idx_organization_members_user_id ON organization_members(user_id);
+
+CREATE TABLE organization_invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    role organization_role NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_organization_invitations_organization_id ON organization_invitations(organization_id);
+
+-- Every user who acts as a client or contractor, or did so before a role
+-- change, gets a personal organization of that kind that they own. Their
+-- tenders and bids move into it.
+INSERT INTO organizations (name, kind, created_by)
+SELECT u.username, 'client', u.id
+FROM users u
+WHERE u.role = 'client' OR EXISTS (SELECT 1 FROM tenders t WHERE t.client_id = u.id);
+
+INSERT INTO organizations (name, kind, created_by)
+SELECT u.username, 'contractor', u.id
+FROM users u
+WHERE u.role = 'contractor' OR EXISTS (SELECT 1 FROM bids b WHERE b.contractor_id = u.id);
+
+INSERT INTO organization_members (organization_id, user_id, role)
+SELECT id, created_by, 'owner' FROM organizations;
+
+ALTER TABLE tenders ADD COLUMN organization_id UUID REFERENCES organizations(id);
+ALTER TABLE bids ADD COLUMN organization_id UUID REFERENCES organizations(id);
+
+UPDATE tenders t SET organization_id = o.id
+FROM organizations o
+WHERE o.created_by = t.client_id AND o.kind = 'client';
+
+UPDATE bids b SET organization_id = o.id
+FROM organizations o
+WHERE o.created_by = b.contractor_id AND o.kind = 'contractor';
+
+ALTER TABLE tenders ALTER COLUMN organization_id SET NOT NULL;
+ALTER TABLE bids ALTER COLUMN organization_id SET NOT NULL;
+
+CREATE INDEX idx_tenders_organization_id ON tenders(organization_id);
+CREATE INDEX idx_bids_organization_id ON bids(organization_id);
+
+-- Installs whose policy was already imported from policy.csv get the
+-- organization routes here; new installs import them from the file.
+INSERT INTO casbin_rules (ptype, v0, v1, v2)
+SELECT 'p', r.role, r.object, r.action
+FROM (VALUES
+    ('client', '/api/organizations', 'GET'),
+    ('client', '/api/organizations', 'POST'),
+    ('client', '/api/organizations/*', 'GET'),
+    ('client', '/api/organizations/*', 'POST'),
+    ('client', '/api/organizations/*', 'PUT'),
+    ('client', '/api/organizations/*', 'DELETE'),
+    ('contractor', '/api/organizations', 'GET'),
+    ('contractor', '/api/organizations', 'POST'),
+    ('contractor', '/api/organizations/*', 'GET'),
+    ('contractor', '/api/organizations/*', 'POST'),
+    ('contractor', '/api/organizations/*', 'PUT'),
+    ('contractor', '/api/organizations/*', 'DELETE')
+) AS r(role, object, action)
+WHERE EXISTS (SELECT 1 FROM casbin_rules)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+-- The broken policies are not restored.
+SELECT 1;
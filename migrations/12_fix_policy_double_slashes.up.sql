@@ -0,0 +1,10 @@
+-- The seed policy file contained "/api//users/*/tenders", which no request
+-- path matches, so clients could not read their tender history. Collapse
+-- repeated slashes in every policy object; the policy API rejects them since.
+INSERT INTO casbin_rules (ptype, v0, v1, v2, v3, v4, v5)
+SELECT ptype, v0, regexp_replace(v1, '/{2,}', '/', 'g'), v2, v3, v4, v5
+FROM casbin_rules
+WHERE ptype = 'p' AND v1 LIKE '%//%'
+ON CONFLICT DO NOTHING;
+
+DELETE FROM casbin_rules WHERE ptype = 'p' AND v1 LIKE '%//%';
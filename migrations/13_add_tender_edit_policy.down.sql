@@ -0,0 +1 @@
+DELETE FROM casbin_rules WHERE ptype = 'p' AND v1 = '/api/client/tenders/*' AND v2 = 'PATCH';
@@ -0,0 +1,7 @@
+-- Installs whose policy was already imported from policy.csv get the tender
+-- editing route here; new installs import it from the file.
+INSERT INTO casbin_rules (ptype, v0, v1, v2)
+SELECT 'p', r.subject, '/api/client/tenders/*', 'PATCH'
+FROM (VALUES ('client'), ('scope:tenders:write')) AS r(subject)
+WHERE EXISTS (SELECT 1 FROM casbin_rules)
+ON CONFLICT DO NOTHING;
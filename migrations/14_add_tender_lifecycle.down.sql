@@ -0,0 +1,19 @@
+DELETE FROM casbin_rules WHERE ptype = 'p' AND v1 = '/api/client/tenders/*/transitions';
+
+DROP TABLE IF EXISTS tender_status_history;
+ALTER TABLE tenders DROP COLUMN IF EXISTS cancellation_reason;
+
+-- Drafts become open and evaluated or cancelled tenders closed, the states
+-- they are closest to.
+ALTER TABLE tenders ALTER COLUMN status DROP DEFAULT;
+ALTER TYPE tender_status RENAME TO tender_status_old;
+CREATE TYPE tender_status AS ENUM ('open', 'closed', 'awarded');
+ALTER TABLE tenders ALTER COLUMN status TYPE tender_status
+    USING (CASE status::text
+        WHEN 'draft' THEN 'open'
+        WHEN 'evaluating' THEN 'closed'
+        WHEN 'cancelled' THEN 'closed'
+        ELSE status::text
+    END)::tender_status;
+ALTER TABLE tenders ALTER COLUMN status SET DEFAULT 'open';
+DROP TYPE tender_status_old;
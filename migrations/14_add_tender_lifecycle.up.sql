@@ -0,0 +1,40 @@
+-- Recreate the enum instead of ALTER TYPE ... ADD VALUE, which cannot run
+-- inside the migration transaction on Postgres before 12.
+ALTER TABLE tenders ALTER COLUMN status DROP DEFAULT;
+ALTER TYPE tender_status RENAME TO tender_status_old;
+CREATE TYPE tender_status AS ENUM ('draft', 'open', 'closed', 'evaluating', 'awarded', 'cancelled');
+ALTER TABLE tenders ALTER COLUMN status TYPE tender_status USING status::text::tender_status;
+ALTER TABLE tenders ALTER COLUMN status SET DEFAULT 'open';
+DROP TYPE tender_status_old;
+
+ALTER TABLE tenders ADD COLUMN cancellation_reason TEXT;
+
+CREATE TABLE tender_status_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tender_id UUID NOT NULL REFERENCES tenders(id) ON DELETE CASCADE,
+    transition VARCHAR(16) NOT NULL,
+    from_status VARCHAR(16) NOT NULL,
+    to_status VARCHAR(16) NOT NULL,
+    reason TEXT,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_tender_status_history_tender_id ON tender_status_history(tender_id, created_at);
+
+-- Awarding a bid used to leave its tender open.
+UPDATE tenders t SET status = 'awarded', updated_at = CURRENT_TIMESTAMP
+WHERE t.status <> 'awarded'
+AND EXISTS (SELECT 1 FROM bids b WHERE b.tender_id = t.id AND b.status = 'awarded');
+
+-- Installs whose policy was already imported from policy.csv get the
+-- transition routes here; new installs import them from the file.
+INSERT INTO casbin_rules (ptype, v0, v1, v2)
+SELECT 'p', r.subject, r.object, r.action
+FROM (VALUES
+    ('client', '/api/client/tenders/*/transitions', 'POST'),
+    ('scope:tenders:write', '/api/client/tenders/*/transitions', 'POST'),
+    ('scope:tenders:read', '/api/client/tenders/*/transitions', 'GET')
+) AS r(subject, object, action)
+WHERE EXISTS (SELECT 1 FROM casbin_rules)
+ON CONFLICT DO NOTHING;
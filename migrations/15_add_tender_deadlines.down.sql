@@ -0,0 +1,5 @@
+DELETE FROM casbin_rules WHERE ptype = 'p' AND v1 = '/api/contractor/tenders/*/watch';
+
+DROP INDEX IF EXISTS idx_tenders_open_deadline;
+DROP TABLE IF EXISTS tender_deadline_reminders;
+DROP TABLE IF EXISTS tender_watchers;
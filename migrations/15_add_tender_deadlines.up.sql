@@ -0,0 +1,33 @@
+-- Contractors who follow a tender without having bid on it.
+CREATE TABLE tender_watchers (
+    tender_id UUID NOT NULL REFERENCES tenders(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tender_id, user_id)
+);
+
+CREATE INDEX idx_tender_watchers_user_id ON tender_watchers(user_id);
+
+-- One row per reminder sent. The deadline is part of the key, so moving it
+-- sends the reminders again.
+CREATE TABLE tender_deadline_reminders (
+    tender_id UUID NOT NULL REFERENCES tenders(id) ON DELETE CASCADE,
+    lead_seconds BIGINT NOT NULL,
+    deadline TIMESTAMP WITH TIME ZONE NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tender_id, lead_seconds, deadline)
+);
+
+-- The scheduler looks up open tenders by deadline.
+CREATE INDEX idx_tenders_open_deadline ON tenders(deadline) WHERE status = 'open';
+
+-- Installs whose policy was already imported from policy.csv get the watch
+-- routes here; new installs import them from the file.
+INSERT INTO casbin_rules (ptype, v0, v1, v2)
+SELECT 'p', r.subject, r.object, r.action
+FROM (VALUES
+    ('contractor', '/api/contractor/tenders/*/watch', 'POST'),
+    ('contractor', '/api/contractor/tenders/*/watch', 'DELETE')
+) AS r(subject, object, action)
+WHERE EXISTS (SELECT 1 FROM casbin_rules)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,9 @@
+DELETE FROM casbin_rules WHERE ptype = 'p' AND v1 IN (
+    '/api/client/tenders/*/documents',
+    '/api/client/tenders/*/documents/*',
+    '/api/contractor/tenders/*/documents',
+    '/api/contractor/tenders/*/documents/*'
+);
+
+-- The blobs of the documents stay in the blob store.
+DROP TABLE IF EXISTS tender_documents;
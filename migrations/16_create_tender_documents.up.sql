@@ -0,0 +1,31 @@
+CREATE TABLE tender_documents (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tender_id UUID NOT NULL REFERENCES tenders(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    storage_key VARCHAR(512) NOT NULL UNIQUE,
+    uploaded_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT size_not_negative CHECK (size >= 0)
+);
+
+CREATE INDEX idx_tender_documents_tender_id ON tender_documents(tender_id, created_at);
+
+-- Installs whose policy was already imported from policy.csv get the
+-- document routes here; new installs import them from the file.
+INSERT INTO casbin_rules (ptype, v0, v1, v2)
+SELECT 'p', r.subject, r.object, r.action
+FROM (VALUES
+    ('client', '/api/client/tenders/*/documents', 'POST'),
+    ('scope:tenders:write', '/api/client/tenders/*/documents', 'POST'),
+    ('scope:tenders:read', '/api/client/tenders/*/documents', 'GET'),
+    ('scope:tenders:read', '/api/client/tenders/*/documents/*', 'GET'),
+    ('contractor', '/api/contractor/tenders/*/documents', 'GET'),
+    ('contractor', '/api/contractor/tenders/*/documents/*', 'GET'),
+    ('scope:tenders:read', '/api/contractor/tenders/*/documents', 'GET'),
+    ('scope:tenders:read', '/api/contractor/tenders/*/documents/*', 'GET')
+) AS r(subject, object, action)
+WHERE EXISTS (SELECT 1 FROM casbin_rules)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,5 @@
+DELETE FROM casbin_rules WHERE ptype = 'p' AND v1 = '/api/categories';
+
+ALTER TABLE tenders DROP COLUMN IF EXISTS tags;
+ALTER TABLE tenders DROP COLUMN IF EXISTS category_code;
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,56 @@
+-- The classification of tenders, keyed by CPV-style codes.
+CREATE TABLE categories (
+    code VARCHAR(16) PRIMARY KEY,
+    parent_code VARCHAR(16) REFERENCES categories(code),
+    name VARCHAR(255) NOT NULL
+);
+
+CREATE INDEX idx_categories_parent_code ON categories(parent_code);
+
+INSERT INTO categories (code, parent_code, name) VALUES
+    ('03000000', NULL, 'Agricultural, farming, fishing, forestry and related products'),
+    ('09000000', NULL, 'Petroleum products, fuel, electricity and other sources of energy'),
+    ('30000000', NULL, 'Office and computing machinery, equipment and supplies except furniture and software packages'),
+    ('30200000', '30000000', 'Computer equipment and supplies'),
+    ('32000000', NULL, 'Radio, television, communication, telecommunication and related equipment'),
+    ('33000000', NULL, 'Medical equipments, pharmaceuticals and personal care products'),
+    ('34000000', NULL, 'Transport equipment and auxiliary products to transportation'),
+    ('39000000', NULL, 'Furniture, furnishings, domestic appliances and cleaning products'),
+    ('44000000', NULL, 'Construction structures and materials; auxiliary products to construction'),
+    ('45000000', NULL, 'Construction work'),
+    ('45100000', '45000000', 'Site preparation work'),
+    ('45200000', '45000000', 'Works for complete or part construction and civil engineering work'),
+    ('45210000', '45200000', 'Building construction work'),
+    ('45230000', '45200000', 'Construction work for pipelines, communication and power lines, highways, roads, airfields and railways'),
+    ('45300000', '45000000', 'Building installation work'),
+    ('45400000', '45000000', 'Building completion work'),
+    ('48000000', NULL, 'Software package and information systems'),
+    ('50000000', NULL, 'Repair and maintenance services'),
+    ('71000000', NULL, 'Architectural, construction, engineering and inspection services'),
+    ('71200000', '71000000', 'Architectural and related services'),
+    ('71300000', '71000000', 'Engineering services'),
+    ('72000000', NULL, 'IT services: consulting, software development, Internet and support'),
+    ('72200000', '72000000', 'Software programming and consultancy services'),
+    ('72400000', '72000000', 'Internet services'),
+    ('79000000', NULL, 'Business services: law, marketing, consulting, recruitment, printing and security'),
+    ('80000000', NULL, 'Education and training services'),
+    ('85000000', NULL, 'Health and social work services'),
+    ('90000000', NULL, 'Sewage, refuse, cleaning and environmental services');
+
+ALTER TABLE tenders ADD COLUMN category_code VARCHAR(16) REFERENCES categories(code);
+ALTER TABLE tenders ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_tenders_category_code ON tenders(category_code);
+CREATE INDEX idx_tenders_tags ON tenders USING GIN (tags);
+
+-- Installs whose policy was already imported from policy.csv get the
+-- category route here; new installs import them from the file.
+INSERT INTO casbin_rules (ptype, v0, v1, v2)
+SELECT 'p', r.subject, r.object, r.action
+FROM (VALUES
+    ('client', '/api/categories', 'GET'),
+    ('contractor', '/api/categories', 'GET'),
+    ('scope:tenders:read', '/api/categories', 'GET')
+) AS r(subject, object, action)
+WHERE EXISTS (SELECT 1 FROM casbin_rules)
+ON CONFLICT DO NOTHING;
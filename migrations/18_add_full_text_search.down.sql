@@ -0,0 +1,4 @@
+ALTER TABLE bids DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE tenders DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS search_language();
+DROP TABLE IF EXISTS search_settings;
@@ -0,0 +1,29 @@
+-- The text search configuration of the search columns below. server migrate
+-- up keeps it in step with search.language and rebuilds the columns when it
+-- changes, see postgres.Migrator.SetSearchLanguage.
+CREATE TABLE search_settings (
+    id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+    language REGCONFIG NOT NULL
+);
+
+INSERT INTO search_settings (language) VALUES ('english');
+
+-- Queries parse their search terms with the configuration the columns were
+-- built with.
+CREATE FUNCTION search_language() RETURNS regconfig
+    LANGUAGE sql STABLE
+    AS $$ SELECT language FROM search_settings $$;
+
+-- Titles rank above descriptions.
+ALTER TABLE tenders ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX idx_tenders_search_vector ON tenders USING GIN (search_vector);
+
+ALTER TABLE bids ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    to_tsvector('english', coalesce(comments, ''))
+) STORED;
+
+CREATE INDEX idx_bids_search_vector ON bids USING GIN (search_vector);
@@ -0,0 +1,14 @@
+CREATE INDEX IF NOT EXISTS idx_tenders_organization_id ON tenders(organization_id);
+CREATE INDEX IF NOT EXISTS idx_tenders_client_id ON tenders(client_id);
+CREATE INDEX IF NOT EXISTS idx_bids_tender_id ON bids(tender_id);
+CREATE INDEX IF NOT EXISTS idx_bids_contractor_id ON bids(contractor_id);
+CREATE INDEX IF NOT EXISTS idx_bids_organization_id ON bids(organization_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id);
+
+DROP INDEX IF EXISTS idx_notifications_user_id_created_at;
+DROP INDEX IF EXISTS idx_bids_organization_id_created_at;
+DROP INDEX IF EXISTS idx_bids_contractor_id_created_at;
+DROP INDEX IF EXISTS idx_bids_tender_id_created_at;
+DROP INDEX IF EXISTS idx_tenders_client_id_created_at;
+DROP INDEX IF EXISTS idx_tenders_organization_id_created_at;
+DROP INDEX IF EXISTS idx_tenders_created_at_id;
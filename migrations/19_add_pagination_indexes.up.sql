@@ -0,0 +1,17 @@
+-- Lists are paged by keyset on (created_at, id), newest first. Each index
+-- serves the ordering and the cursor condition of a list together with its
+-- filter column, and replaces the index on that column alone.
+CREATE INDEX idx_tenders_created_at_id ON tenders(created_at DESC, id DESC);
+CREATE INDEX idx_tenders_organization_id_created_at ON tenders(organization_id, created_at DESC, id DESC);
+CREATE INDEX idx_tenders_client_id_created_at ON tenders(client_id, created_at DESC, id DESC);
+CREATE INDEX idx_bids_tender_id_created_at ON bids(tender_id, created_at DESC, id DESC);
+CREATE INDEX idx_bids_contractor_id_created_at ON bids(contractor_id, created_at DESC, id DESC);
+CREATE INDEX idx_bids_organization_id_created_at ON bids(organization_id, created_at DESC, id DESC);
+CREATE INDEX idx_notifications_user_id_created_at ON notifications(user_id, created_at DESC, id DESC);
+
+DROP INDEX IF EXISTS idx_tenders_organization_id;
+DROP INDEX IF EXISTS idx_tenders_client_id;
+DROP INDEX IF EXISTS idx_bids_tender_id;
+DROP INDEX IF EXISTS idx_bids_contractor_id;
+DROP INDEX IF EXISTS idx_bids_organization_id;
+DROP INDEX IF EXISTS idx_notifications_user_id;
@@ -0,0 +1,5 @@
+DELETE FROM casbin_rules
+WHERE ptype = 'p'
+  AND v0 IN ('contractor', 'scope:tenders:read')
+  AND v1 IN ('/api/contractor/tenders', '/api/contractor/tenders/*')
+  AND v2 = 'GET';
@@ -0,0 +1,12 @@
+-- Installs whose policy was already imported from policy.csv get the
+-- marketplace routes here; new installs import them from the file.
+INSERT INTO casbin_rules (ptype, v0, v1, v2)
+SELECT 'p', r.subject, r.object, r.action
+FROM (VALUES
+    ('contractor', '/api/contractor/tenders', 'GET'),
+    ('contractor', '/api/contractor/tenders/*', 'GET'),
+    ('scope:tenders:read', '/api/contractor/tenders', 'GET'),
+    ('scope:tenders:read', '/api/contractor/tenders/*', 'GET')
+) AS r(subject, object, action)
+WHERE EXISTS (SELECT 1 FROM casbin_rules)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,7 @@
+DELETE FROM casbin_rules WHERE ptype = 'p' AND v1 IN (
+    '/api/contractor/tenders/*/questions',
+    '/api/client/tenders/*/questions',
+    '/api/client/tenders/*/questions/*/answer'
+);
+
+DROP TABLE IF EXISTS tender_questions;
@@ -0,0 +1,36 @@
+CREATE TABLE tender_questions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tender_id UUID NOT NULL REFERENCES tenders(id) ON DELETE CASCADE,
+    asked_by UUID NOT NULL REFERENCES users(id),
+    organization_id UUID NOT NULL REFERENCES organizations(id),
+    question TEXT NOT NULL,
+    answer TEXT,
+    visibility VARCHAR(16),
+    answered_by UUID REFERENCES users(id),
+    answered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT valid_visibility CHECK (visibility IN ('public', 'private')),
+    CONSTRAINT answered_together CHECK (
+        (answer IS NULL) = (visibility IS NULL)
+        AND (answer IS NULL) = (answered_by IS NULL)
+        AND (answer IS NULL) = (answered_at IS NULL)
+    )
+);
+
+CREATE INDEX idx_tender_questions_tender_id ON tender_questions(tender_id, created_at);
+
+-- Installs whose policy was already imported from policy.csv get the
+-- question routes here; new installs import them from the file.
+INSERT INTO casbin_rules (ptype, v0, v1, v2)
+SELECT 'p', r.subject, r.object, r.action
+FROM (VALUES
+    ('contractor', '/api/contractor/tenders/*/questions', 'POST'),
+    ('contractor', '/api/contractor/tenders/*/questions', 'GET'),
+    ('scope:bids:write', '/api/contractor/tenders/*/questions', 'POST'),
+    ('scope:tenders:read', '/api/contractor/tenders/*/questions', 'GET'),
+    ('scope:tenders:read', '/api/client/tenders/*/questions', 'GET'),
+    ('client', '/api/client/tenders/*/questions/*/answer', 'POST'),
+    ('scope:tenders:write', '/api/client/tenders/*/questions/*/answer', 'POST')
+) AS r(subject, object, action)
+WHERE EXISTS (SELECT 1 FROM casbin_rules)
+ON CONFLICT DO NOTHING;
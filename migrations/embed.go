@@ -0,0 +1,9 @@
+// Package migrations embeds the SQL schema migrations into the binary.
+package migrations
+
+import "embed"
+
+// FS holds every NN_name.up.sql and NN_name.down.sql file in this directory.
+//
+//go:embed *.sql
+var FS embed.FS